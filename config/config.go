@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -12,26 +13,72 @@ type WSConfig struct {
 	Port          string `env:"PORT" env-default:"8080"`
 	CheckOrigin   bool   `env:"WS_CHECK_ORIGIN" env-default:"true"`
 	AllowedOrigin string `env:"WS_ALLOWED_ORIGIN"`
+	// InstanceID identifies ws-notifications instance in the cluster, hostname is used by default
+	InstanceID         string `env:"WS_INSTANCE_ID"`
+	PresenceTTLSeconds int    `env:"WS_PRESENCE_TTL_SECONDS" env-default:"30"`
+	// SendBufferSize notifications waiting to be written to one connection, connection is closed when it overflows
+	SendBufferSize      int `env:"WS_SEND_BUFFER_SIZE" env-default:"64"`
+	WriteTimeoutSeconds int `env:"WS_WRITE_TIMEOUT_SECONDS" env-default:"10"`
+}
+
+// ServiceHTTPConfig HTTP listener of services without own HTTP API (metrics, probes)
+type ServiceHTTPConfig struct {
+	Host string `env:"SERVICE_HTTP_HOST" env-default:"0.0.0.0"`
+	Port string `env:"SERVICE_HTTP_PORT" env-default:"9090"`
+	// UserTokenSecret signs tokens of users managing their webhooks and push subscriptions: hex HMAC-SHA256 of the email,
+	// the token is issued by the application the user is logged in. These endpoints are disabled without it.
+	UserTokenSecret string `env:"USER_TOKEN_SECRET"`
 }
 
 // KafkaConfig Kafka configuration
 type KafkaConfig struct {
-	Brokers                 string `env:"KAFKA_BROKERS"`
-	ConsumerGroupID         string `env:"KAFKA_CONSUMER_GROUP_ID"  env-default:"notifications-processor-async"`
-	TimeoutSeconds          int    `env:"KAFKA_TIMEOUT_SECONDS" env-default:"6"`
-	IntervalSeconds         int    `env:"KAFKA_INTERVAL_SECONDS" env-default:"3"`
-	TopicEmailNotifications string `env:"KAFKA_TOPIC_EMAIL_NOTIFICATIONS"`
-	TopicPushNotifications  string `env:"KAFKA_TOPIC_PUSH_NOTIFICATIONS"`
-	TopicWSNotifications    string `env:"KAFKA_TOPIC_WS_NOTIFICATIONS"`
-	TopicDeadNotifications  string `env:"KAFKA_TOPIC_DEAD_NOTIFICATIONS"`
+	Brokers                    string `env:"KAFKA_BROKERS"`
+	ConsumerGroupID            string `env:"KAFKA_CONSUMER_GROUP_ID"  env-default:"notifications-processor-async"`
+	TimeoutSeconds             int    `env:"KAFKA_TIMEOUT_SECONDS" env-default:"6"`
+	IntervalSeconds            int    `env:"KAFKA_INTERVAL_SECONDS" env-default:"3"`
+	TopicEmailNotifications    string `env:"KAFKA_TOPIC_EMAIL_NOTIFICATIONS"`
+	TopicPushNotifications     string `env:"KAFKA_TOPIC_PUSH_NOTIFICATIONS"`
+	TopicWSNotifications       string `env:"KAFKA_TOPIC_WS_NOTIFICATIONS"`
+	TopicWebhookNotifications  string `env:"KAFKA_TOPIC_WEBHOOK_NOTIFICATIONS" env-default:"notifications_webhook"`
+	TopicSmsNotifications      string `env:"KAFKA_TOPIC_SMS_NOTIFICATIONS" env-default:"notifications_sms"`
+	TopicTelegramNotifications string `env:"KAFKA_TOPIC_TELEGRAM_NOTIFICATIONS" env-default:"notifications_telegram"`
+	TopicSlackNotifications    string `env:"KAFKA_TOPIC_SLACK_NOTIFICATIONS" env-default:"notifications_slack"`
+	TopicDeadNotifications     string `env:"KAFKA_TOPIC_DEAD_NOTIFICATIONS"`
+	// TopicNotificationRequests is read by router, which fans requests out to channel topics
+	TopicNotificationRequests string `env:"KAFKA_TOPIC_NOTIFICATION_REQUESTS" env-default:"notification_requests"`
+	// RouterConsumerGroupID is group of router, channels consume their topics with KAFKA_CONSUMER_GROUP_ID
+	RouterConsumerGroupID string `env:"KAFKA_ROUTER_CONSUMER_GROUP_ID" env-default:"notifications-router"`
 }
 
 // RedisConfig Redis configuration
 type RedisConfig struct {
-	Addr           string `env:"REDIS_ADDR"`
-	DB             int    `env:"REDIS_DB"`
-	MaxRetries     int    `env:"REDIS_MAX_RETRIES" env-default:"5"`
-	TimeoutSeconds int    `env:"REDIS_TIMEOUT_SECONDS" env-default:"5"`
+	// Mode is one of standalone, sentinel or cluster
+	Mode  string   `env:"REDIS_MODE" env-default:"standalone"`
+	Addr  string   `env:"REDIS_ADDR"`
+	Addrs []string `env:"REDIS_ADDRS" env-separator:","`
+	// MasterName is name of the master watched by sentinels, REDIS_ADDRS are sentinel addresses then
+	MasterName            string `env:"REDIS_MASTER_NAME"`
+	SentinelUsername      string `env:"REDIS_SENTINEL_USERNAME"`
+	SentinelPassword      string `env:"REDIS_SENTINEL_PASSWORD"`
+	Username              string `env:"REDIS_USERNAME"`
+	Password              string `env:"REDIS_PASSWORD"`
+	DB                    int    `env:"REDIS_DB"`
+	TLSEnabled            bool   `env:"REDIS_TLS_ENABLED" env-default:"false"`
+	TLSServerName         string `env:"REDIS_TLS_SERVER_NAME"`
+	TLSInsecureSkipVerify bool   `env:"REDIS_TLS_INSECURE_SKIP_VERIFY" env-default:"false"`
+	PoolSize              int    `env:"REDIS_POOL_SIZE"`
+	MaxRetries            int    `env:"REDIS_MAX_RETRIES" env-default:"5"`
+	TimeoutSeconds        int    `env:"REDIS_TIMEOUT_SECONDS" env-default:"5"`
+	// Retention of per-user notification streams, 0 disables the limit
+	StreamMaxLen           int64 `env:"REDIS_STREAM_MAX_LEN" env-default:"1000"`
+	StreamMaxAgeHours      int   `env:"REDIS_STREAM_MAX_AGE_HOURS" env-default:"720"`
+	JanitorIntervalMinutes int   `env:"REDIS_JANITOR_INTERVAL_MINUTES" env-default:"60"`
+	InactiveUserTTLHours   int   `env:"REDIS_INACTIVE_USER_TTL_HOURS" env-default:"2160"`
+	// MigrateLegacyKeys moves user keys without cluster hash tags to current keys on start and in janitor passes
+	MigrateLegacyKeys bool `env:"REDIS_MIGRATE_LEGACY_KEYS" env-default:"true"`
+	// Shared readers of per-user streams: max streams per one XREAD and its block timeout
+	ReaderBatchSize         int `env:"REDIS_READER_BATCH_SIZE" env-default:"500"`
+	ReaderBlockMilliseconds int `env:"REDIS_READER_BLOCK_MILLISECONDS" env-default:"1000"`
 }
 
 // EmailConfig Email/SMTP configuration
@@ -41,24 +88,202 @@ type EmailConfig struct {
 	SmtpTimeoutSeconds int    `env:"SMTP_TIMEOUT_SECONDS" env-default:"10"`
 	SmtpUsername       string `env:"SMTP_USERNAME" env-default:""`
 	SmtpPassword       string `env:"SMTP_PASSWORD" env-default:""`
+	SmtpPoolSize       int    `env:"SMTP_POOL_SIZE" env-default:"4"`
 	FromEmail          string `env:"FROM_EMAIL" env-default:"email@notificator.ru"`
 }
 
+// TracingConfig OpenTelemetry tracing configuration
+type TracingConfig struct {
+	// Exporter is one of none, otlp, stdout or file
+	Exporter     string  `env:"TRACING_EXPORTER" env-default:"none"`
+	OTLPEndpoint string  `env:"TRACING_OTLP_ENDPOINT" env-default:"localhost:4318"`
+	OTLPInsecure bool    `env:"TRACING_OTLP_INSECURE" env-default:"true"`
+	FilePath     string  `env:"TRACING_FILE_PATH" env-default:"traces.jsonl"`
+	SampleRatio  float64 `env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+}
+
+// PriorityConfig lanes of notifications topics: <topic>.high, <topic> and <topic>.low.
+// Weights are amounts of notifications taken from the lane in one round, while it isn't empty.
+type PriorityConfig struct {
+	LanesEnabled  bool `env:"PRIORITY_LANES_ENABLED" env-default:"true"`
+	HighWeight    int  `env:"PRIORITY_HIGH_WEIGHT" env-default:"6"`
+	NormalWeight  int  `env:"PRIORITY_NORMAL_WEIGHT" env-default:"3"`
+	LowWeight     int  `env:"PRIORITY_LOW_WEIGHT" env-default:"1"`
+	LaneQueueSize int  `env:"PRIORITY_LANE_QUEUE_SIZE" env-default:"10"`
+}
+
+// SchedulerConfig scheduler of delayed notifications
+type SchedulerConfig struct {
+	PollIntervalMilliseconds int `env:"SCHEDULER_POLL_INTERVAL_MILLISECONDS" env-default:"1000"`
+	BatchSize                int `env:"SCHEDULER_BATCH_SIZE" env-default:"100"`
+	// LeaseSeconds claimed notification returns to the schedule, if the instance didn't release it in time
+	LeaseSeconds int `env:"SCHEDULER_LEASE_SECONDS" env-default:"60"`
+}
+
+// DigestConfig collects notifications of categories into one email per window
+type DigestConfig struct {
+	// Categories maps category to its window, hourly or daily, e.g. social:hourly,marketing:daily
+	Categories          map[string]string `env:"DIGEST_CATEGORIES" env-separator:","`
+	PollIntervalSeconds int               `env:"DIGEST_POLL_INTERVAL_SECONDS" env-default:"30"`
+	// LeaseSeconds closed window returns to the queue, if the instance didn't send its digest in time
+	LeaseSeconds int `env:"DIGEST_LEASE_SECONDS" env-default:"300"`
+	MaxItems     int `env:"DIGEST_MAX_ITEMS" env-default:"100"`
+}
+
+// FallbackConfig chains of channels tried one after another until notification is delivered
+type FallbackConfig struct {
+	// Chains maps chain name to its steps: channel with optional time to wait for delivery, e.g. urgent:ws/30s|push|email
+	Chains              map[string]string `env:"FALLBACK_CHAINS" env-separator:","`
+	PollIntervalSeconds int               `env:"FALLBACK_POLL_INTERVAL_SECONDS" env-default:"5"`
+	LeaseSeconds        int               `env:"FALLBACK_LEASE_SECONDS" env-default:"60"`
+}
+
+// WebhookConfig delivery of notifications to webhook URLs registered by partners
+type WebhookConfig struct {
+	TimeoutSeconds        int `env:"WEBHOOK_TIMEOUT_SECONDS" env-default:"10"`
+	ConnectTimeoutSeconds int `env:"WEBHOOK_CONNECT_TIMEOUT_SECONDS" env-default:"5"`
+	// AllowInsecure allows registration of plain http URLs, use it for local development only
+	AllowInsecure bool `env:"WEBHOOK_ALLOW_INSECURE" env-default:"false"`
+	// AllowPrivateNetworks allows delivery to loopback, private and link-local addresses, use it for local development only
+	AllowPrivateNetworks bool `env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS" env-default:"false"`
+}
+
+// SmsConfig delivery of SMS through SMPP 3.4 server or HTTP gateway
+type SmsConfig struct {
+	// Provider is one of none, smpp or http, SMS channel isn't started with none
+	Provider string `env:"SMS_PROVIDER" env-default:"none"`
+	// SourceAddr is sender of SMS: phone number or alphanumeric sender ID
+	SourceAddr  string `env:"SMS_SOURCE_ADDR"`
+	MaxSegments int    `env:"SMS_MAX_SEGMENTS" env-default:"6"`
+	// SMPP server, bound as transceiver: it accepts submit_sm and sends delivery receipts
+	SmppAddr               string `env:"SMPP_ADDR"`
+	SmppSystemID           string `env:"SMPP_SYSTEM_ID"`
+	SmppPassword           string `env:"SMPP_PASSWORD"`
+	SmppSystemType         string `env:"SMPP_SYSTEM_TYPE"`
+	SmppTimeoutSeconds     int    `env:"SMPP_TIMEOUT_SECONDS" env-default:"10"`
+	SmppEnquireLinkSeconds int    `env:"SMPP_ENQUIRE_LINK_SECONDS" env-default:"30"`
+	GatewayURL             string `env:"SMS_GATEWAY_URL"`
+	GatewayToken           string `env:"SMS_GATEWAY_TOKEN"`
+	GatewayReceiptsToken   string `env:"SMS_GATEWAY_RECEIPTS_TOKEN"`
+	GatewayTimeoutSeconds  int    `env:"SMS_GATEWAY_TIMEOUT_SECONDS" env-default:"10"`
+}
+
+// WebPushConfig delivery of push channel to browsers, push channel only logs notifications without VAPID key
+type WebPushConfig struct {
+	// VAPIDPrivateKey is base64url of P-256 private key scalar, public key is derived from it
+	VAPIDPrivateKey string `env:"WEBPUSH_VAPID_PRIVATE_KEY"`
+	// VAPIDSubject is contact of the sender for push services, mailto: or https: URL
+	VAPIDSubject   string `env:"WEBPUSH_VAPID_SUBJECT" env-default:"mailto:admin@notificator.ru"`
+	TTLSeconds     int    `env:"WEBPUSH_TTL_SECONDS" env-default:"86400"`
+	TimeoutSeconds int    `env:"WEBPUSH_TIMEOUT_SECONDS" env-default:"10"`
+	// AllowPrivateNetworks allows delivery to loopback, private and link-local addresses, use it for local development only
+	AllowPrivateNetworks bool `env:"WEBPUSH_ALLOW_PRIVATE_NETWORKS" env-default:"false"`
+}
+
+// TelegramConfig delivery through Telegram Bot API, channel isn't started without bot token
+type TelegramConfig struct {
+	// APIURL is replaced with fake API in local environment
+	APIURL   string `env:"TELEGRAM_API_URL" env-default:"https://api.telegram.org"`
+	BotToken string `env:"TELEGRAM_BOT_TOKEN"`
+	// ChatIDs maps user email to chat with the bot, e.g. ops@example.com:-1001234567890
+	ChatIDs        map[string]string `env:"TELEGRAM_CHAT_IDS" env-separator:","`
+	TimeoutSeconds int               `env:"TELEGRAM_TIMEOUT_SECONDS" env-default:"10"`
+}
+
+// SlackConfig delivery to Slack-compatible incoming webhook, channel isn't started without webhook URL
+type SlackConfig struct {
+	WebhookURL     string `env:"SLACK_WEBHOOK_URL"`
+	TimeoutSeconds int    `env:"SLACK_TIMEOUT_SECONDS" env-default:"10"`
+}
+
+// ChannelConfig overrides of one notifications channel settings, zero values mean global defaults
+type ChannelConfig struct {
+	Workers   int  `env:"WORKERS" yaml:"workers" toml:"workers"`
+	QueueSize int  `env:"QUEUE_SIZE" yaml:"queue_size" toml:"queue_size"`
+	Ordered   bool `env:"ORDERED" yaml:"ordered" toml:"ordered"`
+	// Rate limits of the whole channel and of one recipient, 0 disables the limit
+	RateLimit                       int `env:"RATE_LIMIT" yaml:"rate_limit" toml:"rate_limit"`
+	RateLimitPeriodSeconds          int `env:"RATE_LIMIT_PERIOD_SECONDS" env-default:"1" yaml:"rate_limit_period_seconds" toml:"rate_limit_period_seconds"`
+	RecipientRateLimit              int `env:"RECIPIENT_RATE_LIMIT" yaml:"recipient_rate_limit" toml:"recipient_rate_limit"`
+	RecipientRateLimitPeriodSeconds int `env:"RECIPIENT_RATE_LIMIT_PERIOD_SECONDS" env-default:"3600" yaml:"recipient_rate_limit_period_seconds" toml:"recipient_rate_limit_period_seconds"`
+	// RateLimitPolicy is delay (postpone until limit resets) or drop (pass to dead notifications)
+	RateLimitPolicy string `env:"RATE_LIMIT_POLICY" env-default:"delay" yaml:"rate_limit_policy" toml:"rate_limit_policy"`
+}
+
+// RetryPolicy retries of failed notification, nil fields mean global defaults
+type RetryPolicy struct {
+	Count           *int `yaml:"count" toml:"count"`
+	IntervalSeconds *int `yaml:"interval_seconds" toml:"interval_seconds"`
+}
+
+// ChannelDefinition notifications channel declared in config file
+type ChannelDefinition struct {
+	Name string `yaml:"name" toml:"name"`
+	// Key identifies channel in preferences, statuses and fallback chains, e.g. email or sms
+	Key   string `yaml:"key" toml:"key"`
+	Topic string `yaml:"topic" toml:"topic"`
+	// ConsumerGroup defaults to <KAFKA_CONSUMER_GROUP_ID>-<key>, channels built from env use KAFKA_CONSUMER_GROUP_ID.
+	// New group has no committed offsets and reads the topic from the oldest retained message.
+	ConsumerGroup string `yaml:"consumer_group" toml:"consumer_group"`
+	// Processor is type of processor registered in processor factories, e.g. email, console or slack
+	Processor string            `yaml:"processor" toml:"processor"`
+	Options   map[string]string `yaml:"options" toml:"options"`
+	Retry     RetryPolicy       `yaml:"retry" toml:"retry"`
+	// QuietHours defers non-urgent notifications, Digest collects digest categories and sends digests through the channel
+	QuietHours bool `yaml:"quiet_hours" toml:"quiet_hours"`
+	Digest     bool `yaml:"digest" toml:"digest"`
+	// Env variables aren't read for channels from config file, zero periods and policy get defaults of ChannelConfig
+	ChannelConfig `yaml:",inline"`
+}
+
 // Config Main config of application
 type Config struct {
-	NotificationsRetryCount           int `env:"NOTIFICATIONS_RETRY_COUNT" env-default:"3"`
-	NotificationsRetryIntervalSeconds int `env:"NOTIFICATIONS_RETRY_INTERVAL_SECONDS" env-default:"3"`
+	NotificationsRetryCount           int           `env:"NOTIFICATIONS_RETRY_COUNT" env-default:"3"`
+	NotificationsRetryIntervalSeconds int           `env:"NOTIFICATIONS_RETRY_INTERVAL_SECONDS" env-default:"3"`
+	ShutdownTimeoutSeconds            int           `env:"SHUTDOWN_TIMEOUT_SECONDS" env-default:"30"`
+	NotificationsWorkers              int           `env:"NOTIFICATIONS_WORKERS" env-default:"10"`
+	NotificationsQueueSize            int           `env:"NOTIFICATIONS_QUEUE_SIZE" env-default:"100"`
+	NotificationsOrdered              bool          `env:"NOTIFICATIONS_ORDERED" env-default:"false"`
+	NotificationsMaxDelayed           int           `env:"NOTIFICATIONS_MAX_DELAYED" env-default:"1000"`
+	UrgentCategories                  []string      `env:"URGENT_CATEGORIES" env-separator:"," env-default:"security,otp"`
+	NotificationStatusTTLHours        int           `env:"NOTIFICATION_STATUS_TTL_HOURS" env-default:"168"`
+	EmailChannel                      ChannelConfig `env-prefix:"EMAIL_CHANNEL_"`
+	PushChannel                       ChannelConfig `env-prefix:"PUSH_CHANNEL_"`
+	WSChannel                         ChannelConfig `env-prefix:"WS_CHANNEL_"`
+	WebhookChannel                    ChannelConfig `env-prefix:"WEBHOOK_CHANNEL_"`
+	SmsChannel                        ChannelConfig `env-prefix:"SMS_CHANNEL_"`
+	TelegramChannel                   ChannelConfig `env-prefix:"TELEGRAM_CHANNEL_"`
+	SlackChannel                      ChannelConfig `env-prefix:"SLACK_CHANNEL_"`
 	WS                                WSConfig
+	ServiceHTTP                       ServiceHTTPConfig
 	Kafka                             KafkaConfig
 	Redis                             RedisConfig
 	Email                             EmailConfig
+	Tracing                           TracingConfig
+	Scheduler                         SchedulerConfig
+	Priority                          PriorityConfig
+	Digest                            DigestConfig
+	Fallback                          FallbackConfig
+	Webhook                           WebhookConfig
+	Sms                               SmsConfig
+	WebPush                           WebPushConfig
+	Telegram                          TelegramConfig
+	Slack                             SlackConfig
+	// Channels of async notifications service, they are built from env sections above when the list is empty
+	Channels []ChannelDefinition `yaml:"channels" toml:"channels"`
 }
 
-// NewConfig returns initialized config
+// NewConfig returns initialized config. When CONFIG_FILE points to YAML or TOML file, it is read first
+// and env variables override its values.
 func NewConfig() (*Config, error) {
 	var cfg Config
 
-	err := cleanenv.ReadEnv(&cfg)
+	var err error
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		err = cleanenv.ReadConfig(path, &cfg)
+	} else {
+		err = cleanenv.ReadEnv(&cfg)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("не удалось прочитать параметры конфига: %w", err)
 	}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConfigReadsChannelsFromYAML(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "channels.example.yaml")
+
+	cfg, err := NewConfig()
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Channels, 3)
+	email := cfg.Channels[0]
+	assert.Equal(t, "Email processor", email.Name)
+	assert.Equal(t, "email", email.Key)
+	assert.Empty(t, email.ConsumerGroup)
+	assert.True(t, email.QuietHours)
+	assert.True(t, email.Digest)
+	assert.Equal(t, ChannelConfig{Workers: 10, QueueSize: 100, RateLimit: 50}, email.ChannelConfig)
+
+	push := cfg.Channels[1]
+	assert.Equal(t, "notifications-push", push.ConsumerGroup)
+	assert.Equal(t, map[string]string{"name": "push"}, push.Options)
+	require.NotNil(t, push.Retry.Count)
+	require.NotNil(t, push.Retry.IntervalSeconds)
+	assert.Equal(t, 5, *push.Retry.Count)
+	assert.Equal(t, 10, *push.Retry.IntervalSeconds)
+
+	ops := cfg.Channels[2]
+	assert.Equal(t, "slack", ops.Key)
+	assert.Equal(t, "notifications_ops", ops.Topic)
+	require.NotNil(t, ops.Retry.Count)
+	assert.Equal(t, 0, *ops.Retry.Count)
+	assert.Nil(t, ops.Retry.IntervalSeconds)
+
+	// Значения по умолчанию из env-default заполняются и при чтении файла
+	assert.Equal(t, "notifications-processor-async", cfg.Kafka.ConsumerGroupID)
+}
+
+func TestNewConfigReadsChannelsFromTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(path, []byte(`
+[[channels]]
+name = "SMS processor"
+key = "sms"
+topic = "notifications_sms"
+consumer_group = "notifications-sms"
+processor = "sms"
+quiet_hours = true
+rate_limit = 5
+rate_limit_policy = "drop"
+
+[channels.retry]
+count = 2
+`), 0o600)
+	require.NoError(t, err)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("KAFKA_CONSUMER_GROUP_ID", "notifications")
+
+	cfg, err := NewConfig()
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Channels, 1)
+	sms := cfg.Channels[0]
+	assert.Equal(t, "sms", sms.Key)
+	assert.Equal(t, "notifications-sms", sms.ConsumerGroup)
+	assert.True(t, sms.QuietHours)
+	assert.Equal(t, 5, sms.RateLimit)
+	assert.Equal(t, "drop", sms.RateLimitPolicy)
+	require.NotNil(t, sms.Retry.Count)
+	assert.Equal(t, 2, *sms.Retry.Count)
+	// Переменные окружения переопределяют значения файла
+	assert.Equal(t, "notifications", cfg.Kafka.ConsumerGroupID)
+}
+
+func TestNewConfigFailsOnInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("channels: [name"), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+
+	_, err := NewConfig()
+	assert.Error(t, err)
+}
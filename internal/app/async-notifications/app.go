@@ -3,17 +3,33 @@ package async_notifications
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/controller/http"
 	dead_notifications_processor "github.com/mwsbkru/evrone-go-final/internal/dead-notifications-processor"
+	health_checkers "github.com/mwsbkru/evrone-go-final/internal/health-checkers"
 	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/kafka"
-	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/smtp"
-	notifications_observer "github.com/mwsbkru/evrone-go-final/internal/notifications-observer"
-	notifications_processor "github.com/mwsbkru/evrone-go-final/internal/notifications-processor"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/redis"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/webpush"
+	notifications_digest "github.com/mwsbkru/evrone-go-final/internal/notifications-digest"
+	notifications_scheduler "github.com/mwsbkru/evrone-go-final/internal/notifications-scheduler"
+	preferences_store "github.com/mwsbkru/evrone-go-final/internal/preferences-store"
+	push_subscriptions_store "github.com/mwsbkru/evrone-go-final/internal/push-subscriptions-store"
+	rate_limiter "github.com/mwsbkru/evrone-go-final/internal/rate-limiter"
 	"github.com/mwsbkru/evrone-go-final/internal/service"
+	status_tracker "github.com/mwsbkru/evrone-go-final/internal/status-tracker"
+	webhooks_store "github.com/mwsbkru/evrone-go-final/internal/webhooks-store"
 )
 
 func Run(ctx context.Context, cfg *config.Config) error {
+	tracingProvider, err := tracing.NewProvider(ctx, cfg, "async-notifications")
+	if err != nil {
+		return fmt.Errorf("can't init tracing: %w", err)
+	}
+	defer tracingProvider.Close()
+
 	kafkaClient, err := kafka.NewClient(cfg)
 	if err != nil {
 		return fmt.Errorf("can't init Kafka client: %w", err)
@@ -28,64 +44,88 @@ func Run(ctx context.Context, cfg *config.Config) error {
 
 	deadProcessor := dead_notifications_processor.NewKafkaDeadNotificationsProcessor(producer.GetProducer(), cfg)
 
-	smtpClient, err := smtp.NewClient(cfg)
+	redisClient, err := redis.NewClient(cfg)
 	if err != nil {
-		return fmt.Errorf("can't initialize SMTP client: %w", err)
+		return fmt.Errorf("can't init Redis client: %w", err)
 	}
-	defer smtpClient.Close()
+	defer redisClient.Close()
 
-	notificationsChannelEmail, consumerEmail, err := initializeEmailNotificationChannel(cfg, kafkaClient, smtpClient, deadProcessor)
-	if err != nil {
-		return fmt.Errorf("can't initialize email notification channel: %w", err)
+	rateLimiter := rate_limiter.NewRedisRateLimiter(redisClient.GetClient())
+	scheduler := notifications_scheduler.NewRedisNotificationsScheduler(redisClient.GetClient())
+	digest := notifications_digest.NewRedisNotificationsDigest(redisClient.GetClient())
+	statusTracker := status_tracker.NewRedisStatusTracker(redisClient.GetClient(), cfg)
+	preferencesService := service.NewPreferencesService(preferences_store.NewRedisPreferencesStore(redisClient.GetClient()))
+	webhooksService := service.NewWebhooksService(cfg, webhooks_store.NewRedisWebhooksStore(redisClient.GetClient()))
+
+	var vapid *webpush.Vapid
+	vapidPublicKey := ""
+	if cfg.WebPush.VAPIDPrivateKey != "" {
+		vapid, err = webpush.NewVapid(cfg.WebPush.VAPIDPrivateKey, cfg.WebPush.VAPIDSubject)
+		if err != nil {
+			return fmt.Errorf("can't init VAPID: %w", err)
+		}
+		vapidPublicKey = vapid.PublicKey()
 	}
-	defer consumerEmail.Close()
+	pushSubscriptionsService := service.NewPushSubscriptionsService(cfg, push_subscriptions_store.NewRedisPushSubscriptionsStore(redisClient.GetClient()), vapidPublicKey)
 
-	notificationsChannelPush, consumerPush, err := initializePushNotificationChannel(cfg, kafkaClient, deadProcessor)
+	deps := &ChannelDependencies{
+		Cfg:               cfg,
+		RedisClient:       redisClient,
+		StatusTracker:     statusTracker,
+		Webhooks:          webhooksService,
+		PushSubscriptions: pushSubscriptionsService,
+		Vapid:             vapid,
+	}
+	defer deps.Close()
+
+	builder := &channelsBuilder{
+		deps:          deps,
+		kafkaClient:   kafkaClient,
+		deadProcessor: deadProcessor,
+		rateLimiter:   rateLimiter,
+		scheduler:     scheduler,
+		preferences:   preferencesService,
+		digest:        digest,
+	}
+	notificationsChannels, digestChannel, err := builder.build(channelDefinitions(cfg))
 	if err != nil {
-		return fmt.Errorf("can't initialize push notification channel: %w", err)
+		return fmt.Errorf("can't initialize notifications channels: %w", err)
 	}
-	defer consumerPush.Close()
 
-	notificationsChannels := []*service.NotificationsChannel{notificationsChannelEmail, notificationsChannelPush}
 	notificationsService := service.NewNotificationsService(notificationsChannels)
-	notificationsService.Run(ctx)
+	schedulerService := service.NewSchedulerService(cfg, scheduler, notificationsChannels)
 
-	return nil
-}
+	healthCheckers := []service.HealthChecker{
+		health_checkers.NewKafkaHealthChecker(kafkaClient.GetClient()),
+		health_checkers.NewRedisHealthChecker(redisClient.GetClient()),
+	}
+	healthCheckers = append(healthCheckers, deps.healthCheckers...)
+	healthCheckers = append(healthCheckers, health_checkers.NewConsumerSessionsHealthChecker(notificationsChannels))
+	healthService := service.NewHealthService(healthCheckers)
 
-// initializeEmailNotificationChannel creates and initializes an email notification channel
-func initializeEmailNotificationChannel(
-	cfg *config.Config,
-	kafkaClient *kafka.Client,
-	smtpClient *smtp.Client,
-	deadProcessor service.DeadNotificationsProcessor,
-) (*service.NotificationsChannel, *kafka.Consumer, error) {
-	consumerEmail, err := kafka.NewConsumer(cfg.Kafka.ConsumerGroupID, kafkaClient.GetClient())
-	if err != nil {
-		return nil, nil, fmt.Errorf("can't init Kafka consumerEmail: %w", err)
+	go http.ServeService(ctx, cfg, http.ServiceAPI{
+		Health:      healthService,
+		Scheduler:   schedulerService,
+		Preferences: preferencesService,
+		Status:      service.NewStatusService(statusTracker),
+		Webhooks:    webhooksService,
+		// Квитанции SMPP приходят в сессии, по HTTP их присылает только шлюз
+		SmsReceipts:       deps.SmsReceiptsForHttp(),
+		PushSubscriptions: pushSubscriptionsService,
+	})
+	go schedulerService.Run(ctx)
+	if digestChannel != nil {
+		digestService := service.NewDigestService(cfg, digest, notifications_digest.NewTemplateDigestRenderer(), digestChannel)
+		go digestService.Run(ctx)
 	}
 
-	topicEmailNotifications := cfg.Kafka.TopicEmailNotifications
-	kafkaObserverEmail := notifications_observer.NewKafkaNotificationsObserver(topicEmailNotifications, cfg, consumerEmail.GetConsumer())
-	processorEmail := notifications_processor.NewEmailNotificationsProcessor(cfg, smtpClient.GetClient())
-	channel := service.NewNotificationChannel(cfg, "Email processor", kafkaObserverEmail, processorEmail, deadProcessor)
-	return channel, consumerEmail, nil
-}
+	// Останавливает чтение из Kafka и дожидается уведомлений в обработке
+	notificationsService.Run(ctx)
 
-// initializePushNotificationChannel creates and initializes a push notification channel
-func initializePushNotificationChannel(
-	cfg *config.Config,
-	kafkaClient *kafka.Client,
-	deadProcessor service.DeadNotificationsProcessor,
-) (*service.NotificationsChannel, *kafka.Consumer, error) {
-	consumerPush, err := kafka.NewConsumer(cfg.Kafka.ConsumerGroupID, kafkaClient.GetClient())
-	if err != nil {
-		return nil, nil, fmt.Errorf("can't init Kafka consumerPush: %w", err)
+	slog.Info("Flushing dead notifications producer")
+	if err := producer.Close(); err != nil {
+		slog.Error("Can't flush dead notifications producer", slog.String("error", err.Error()))
 	}
 
-	topicPushNotifications := cfg.Kafka.TopicPushNotifications
-	kafkaObserverPush := notifications_observer.NewKafkaNotificationsObserver(topicPushNotifications, cfg, consumerPush.GetConsumer())
-	consoleProcessorPush := notifications_processor.ConsoleNotificationsProcessor{Name: "push"}
-	channel := service.NewNotificationChannel(cfg, "Push processor", kafkaObserverPush, &consoleProcessorPush, deadProcessor)
-	return channel, consumerPush, nil
+	return nil
 }
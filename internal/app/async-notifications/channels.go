@@ -0,0 +1,183 @@
+package async_notifications
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/kafka"
+	notifications_observer "github.com/mwsbkru/evrone-go-final/internal/notifications-observer"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+	sms_senders "github.com/mwsbkru/evrone-go-final/internal/sms-senders"
+)
+
+// channelsBuilder builds notifications channels declared in config with processors from the registry
+type channelsBuilder struct {
+	deps          *ChannelDependencies
+	kafkaClient   *kafka.Client
+	deadProcessor service.DeadNotificationsProcessor
+	rateLimiter   service.RateLimiter
+	scheduler     service.NotificationsScheduler
+	preferences   *service.PreferencesService
+	digest        service.NotificationsDigest
+}
+
+// build returns channels and the channel digests are sent through, it's nil when no channel collects digests.
+// Observers of built channels are closed by deps.Close.
+func (b *channelsBuilder) build(definitions []config.ChannelDefinition) ([]*service.NotificationsChannel, *service.NotificationsChannel, error) {
+	if err := validateChannelDefinitions(definitions); err != nil {
+		return nil, nil, err
+	}
+
+	channels := make([]*service.NotificationsChannel, 0, len(definitions))
+	var digestChannel *service.NotificationsChannel
+	for _, definition := range definitions {
+		channel, err := b.buildChannel(definition)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't initialize channel %q: %w", definition.Name, err)
+		}
+		channels = append(channels, channel)
+
+		if definition.Digest {
+			digestChannel = channel
+		}
+	}
+
+	return channels, digestChannel, nil
+}
+
+// validateChannelDefinitions rejects channels sharing name or key, the key identifies channel in preferences and statuses.
+// Digests of the user are collected regardless of channel, so only one channel can send them.
+func validateChannelDefinitions(definitions []config.ChannelDefinition) error {
+	names := make(map[string]struct{}, len(definitions))
+	keys := make(map[string]struct{}, len(definitions))
+	digestChannel := ""
+
+	for _, definition := range definitions {
+		if definition.Name == "" || definition.Key == "" || definition.Topic == "" {
+			return fmt.Errorf("name, key and topic of channel %q must be set", definition.Name)
+		}
+		if _, ok := names[definition.Name]; ok {
+			return fmt.Errorf("channel %q is declared twice", definition.Name)
+		}
+		names[definition.Name] = struct{}{}
+		if _, ok := keys[definition.Key]; ok {
+			return fmt.Errorf("key %q of channel %q is used by another channel", definition.Key, definition.Name)
+		}
+		keys[definition.Key] = struct{}{}
+
+		if definition.Digest {
+			if digestChannel != "" {
+				return fmt.Errorf("channels %q and %q both send digests, only one channel can", digestChannel, definition.Name)
+			}
+			digestChannel = definition.Name
+		}
+	}
+
+	return nil
+}
+
+func (b *channelsBuilder) buildChannel(definition config.ChannelDefinition) (*service.NotificationsChannel, error) {
+	factory, ok := processorFactories[definition.Processor]
+	if !ok {
+		return nil, fmt.Errorf("unknown processor %q", definition.Processor)
+	}
+	processor, err := factory(b.deps, definition)
+	if err != nil {
+		return nil, fmt.Errorf("can't init processor %s: %w", definition.Processor, err)
+	}
+
+	observer, err := notifications_observer.NewKafkaPriorityNotificationsObserver(definition.Topic, definition.ConsumerGroup, b.deps.Cfg, b.kafkaClient.GetClient())
+	if err != nil {
+		return nil, fmt.Errorf("can't init Kafka observer of %s: %w", definition.Topic, err)
+	}
+	b.deps.onClose(func() { observer.Close() })
+
+	channelCfg := channelConfigWithDefaults(definition.ChannelConfig)
+	options := []service.NotificationsChannelOption{
+		service.WithConcurrency(channelCfg.Workers, channelCfg.QueueSize),
+		service.WithOrdering(b.deps.Cfg.NotificationsOrdered || channelCfg.Ordered),
+		service.WithRetryPolicy(definition.Retry.Count, definition.Retry.IntervalSeconds),
+		service.WithRateLimits(b.rateLimiter, service.RateLimitsFromConfig(channelCfg)),
+		service.WithScheduler(b.scheduler),
+		service.WithPreferences(b.preferences, definition.Key),
+		service.WithStatusTracker(b.deps.StatusTracker, definition.Key),
+	}
+	if definition.QuietHours {
+		options = append(options, service.WithQuietHours(b.deps.Cfg.UrgentCategories))
+	}
+	if definition.Digest {
+		options = append(options, service.WithDigest(b.digest, b.deps.Cfg.Digest.Categories))
+	}
+
+	return service.NewNotificationChannel(b.deps.Cfg, definition.Name, observer, processor, b.deadProcessor, options...), nil
+}
+
+// channelConfigWithDefaults fills settings of channel from config file, which env defaults don't reach
+func channelConfigWithDefaults(channelCfg config.ChannelConfig) config.ChannelConfig {
+	if channelCfg.RateLimitPeriodSeconds <= 0 {
+		channelCfg.RateLimitPeriodSeconds = 1
+	}
+	if channelCfg.RecipientRateLimitPeriodSeconds <= 0 {
+		channelCfg.RecipientRateLimitPeriodSeconds = 3600
+	}
+	if channelCfg.RateLimitPolicy == "" {
+		channelCfg.RateLimitPolicy = service.RateLimitPolicyDelay
+	}
+
+	return channelCfg
+}
+
+// channelDefinitions returns channels of config file or channels of env without it.
+// Channel of config file without consumer group gets its own group <KAFKA_CONSUMER_GROUP_ID>-<key>,
+// channels of env keep KAFKA_CONSUMER_GROUP_ID and so offsets committed by previous versions.
+func channelDefinitions(cfg *config.Config) []config.ChannelDefinition {
+	// Без файла конфигурации каналы собираются как раньше из env
+	if len(cfg.Channels) == 0 {
+		return defaultChannelDefinitions(cfg)
+	}
+
+	definitions := slices.Clone(cfg.Channels)
+	for i := range definitions {
+		if definitions[i].ConsumerGroup == "" {
+			definitions[i].ConsumerGroup = fmt.Sprintf("%s-%s", cfg.Kafka.ConsumerGroupID, definitions[i].Key)
+		}
+	}
+
+	return definitions
+}
+
+// defaultChannelDefinitions channels used without config file: email, push and webhook,
+// and Telegram, Slack and SMS when their providers are configured
+func defaultChannelDefinitions(cfg *config.Config) []config.ChannelDefinition {
+	// Без VAPID ключа уведомления push только пишутся в лог
+	pushProcessor := ProcessorConsole
+	if cfg.WebPush.VAPIDPrivateKey != "" {
+		pushProcessor = ProcessorWebPush
+	}
+
+	definitions := []config.ChannelDefinition{
+		{Name: "Email processor", Key: entity.ChannelEmail, Topic: cfg.Kafka.TopicEmailNotifications, Processor: ProcessorEmail,
+			QuietHours: true, Digest: true, ChannelConfig: cfg.EmailChannel},
+		{Name: "Push processor", Key: entity.ChannelPush, Topic: cfg.Kafka.TopicPushNotifications, Processor: pushProcessor,
+			QuietHours: true, ChannelConfig: cfg.PushChannel},
+		{Name: "Webhook processor", Key: entity.ChannelWebhook, Topic: cfg.Kafka.TopicWebhookNotifications, Processor: ProcessorWebhook,
+			ChannelConfig: cfg.WebhookChannel},
+	}
+
+	if cfg.Telegram.BotToken != "" {
+		definitions = append(definitions, config.ChannelDefinition{Name: "Telegram processor", Key: entity.ChannelTelegram,
+			Topic: cfg.Kafka.TopicTelegramNotifications, Processor: ProcessorTelegram, ChannelConfig: cfg.TelegramChannel})
+	}
+	if cfg.Slack.WebhookURL != "" {
+		definitions = append(definitions, config.ChannelDefinition{Name: "Slack processor", Key: entity.ChannelSlack,
+			Topic: cfg.Kafka.TopicSlackNotifications, Processor: ProcessorSlack, ChannelConfig: cfg.SlackChannel})
+	}
+	if cfg.Sms.Provider != sms_senders.ProviderNone {
+		definitions = append(definitions, config.ChannelDefinition{Name: "SMS processor", Key: entity.ChannelSms,
+			Topic: cfg.Kafka.TopicSmsNotifications, Processor: ProcessorSms, QuietHours: true, ChannelConfig: cfg.SmsChannel})
+	}
+
+	return definitions
+}
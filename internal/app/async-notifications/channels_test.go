@@ -0,0 +1,93 @@
+package async_notifications
+
+import (
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	notifications_processor "github.com/mwsbkru/evrone-go-final/internal/notifications-processor"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateChannelDefinitions(t *testing.T) {
+	email := config.ChannelDefinition{Name: "Email processor", Key: "email", Topic: "notifications_email", Processor: ProcessorEmail, Digest: true}
+	push := config.ChannelDefinition{Name: "Push processor", Key: "push", Topic: "notifications_push", Processor: ProcessorConsole}
+
+	tests := []struct {
+		name        string
+		definitions []config.ChannelDefinition
+		valid       bool
+	}{
+		{name: "distinct channels", definitions: []config.ChannelDefinition{email, push}, valid: true},
+		{name: "duplicate name", definitions: []config.ChannelDefinition{email, {Name: email.Name, Key: "ops", Topic: "notifications_ops"}}},
+		{name: "duplicate key", definitions: []config.ChannelDefinition{push, {Name: "Ops alerts", Key: push.Key, Topic: "notifications_ops"}}},
+		{name: "second digest channel", definitions: []config.ChannelDefinition{email, {Name: "Push digests", Key: "push", Topic: "notifications_push", Digest: true}}},
+		{name: "without topic", definitions: []config.ChannelDefinition{{Name: "Ops alerts", Key: "ops"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateChannelDefinitions(tt.definitions)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestChannelDefinitionsConsumerGroups(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Kafka.ConsumerGroupID = "notifications"
+	cfg.Kafka.TopicEmailNotifications = "notifications_email"
+	cfg.Kafka.TopicPushNotifications = "notifications_push"
+	cfg.Kafka.TopicWebhookNotifications = "notifications_webhook"
+	cfg.Sms.Provider = "none"
+
+	groups := map[string]string{}
+	for _, definition := range channelDefinitions(cfg) {
+		groups[definition.Key] = definition.ConsumerGroup
+	}
+	// Каналы из env сохраняют общую группу и ее offset'ы
+	assert.Equal(t, map[string]string{
+		entity.ChannelEmail:   "",
+		entity.ChannelPush:    "",
+		entity.ChannelWebhook: "",
+	}, groups)
+
+	cfg.Channels = []config.ChannelDefinition{
+		{Name: "Ops alerts", Key: "ops", Topic: "notifications_ops"},
+		{Name: "Push processor", Key: "push", Topic: "notifications_push", ConsumerGroup: "push-group"},
+	}
+	definitions := channelDefinitions(cfg)
+	require.Len(t, definitions, 2)
+	assert.Equal(t, "notifications-ops", definitions[0].ConsumerGroup)
+	assert.Equal(t, "push-group", definitions[1].ConsumerGroup)
+	// Определения из конфига не меняются
+	assert.Empty(t, cfg.Channels[0].ConsumerGroup)
+}
+
+func TestProcessorFactories(t *testing.T) {
+	deps := &ChannelDependencies{Cfg: &config.Config{}}
+
+	processor, err := processorFactories[ProcessorConsole](deps, config.ChannelDefinition{Key: "ops"})
+	require.NoError(t, err)
+	assert.Equal(t, "ops", processor.(*notifications_processor.ConsoleNotificationsProcessor).Name)
+
+	processor, err = processorFactories[ProcessorConsole](deps, config.ChannelDefinition{Key: "push", Options: map[string]string{"name": "browser"}})
+	require.NoError(t, err)
+	assert.Equal(t, "browser", processor.(*notifications_processor.ConsoleNotificationsProcessor).Name)
+
+	_, err = processorFactories[ProcessorSlack](deps, config.ChannelDefinition{Key: "slack"})
+	assert.Error(t, err)
+	_, err = processorFactories[ProcessorSlack](deps, config.ChannelDefinition{Key: "slack", Options: map[string]string{"webhook_url": "https://hooks.slack.com/services/T000/B000/XXXX"}})
+	assert.NoError(t, err)
+
+	_, err = processorFactories[ProcessorTelegram](deps, config.ChannelDefinition{Key: "telegram"})
+	assert.Error(t, err)
+	_, err = processorFactories[ProcessorWebPush](deps, config.ChannelDefinition{Key: "push"})
+	assert.Error(t, err)
+}
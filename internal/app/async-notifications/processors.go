@@ -0,0 +1,210 @@
+package async_notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	health_checkers "github.com/mwsbkru/evrone-go-final/internal/health-checkers"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/redis"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/smpp"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/smtp"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/webpush"
+	notifications_processor "github.com/mwsbkru/evrone-go-final/internal/notifications-processor"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+	sms_messages_store "github.com/mwsbkru/evrone-go-final/internal/sms-messages-store"
+	sms_senders "github.com/mwsbkru/evrone-go-final/internal/sms-senders"
+)
+
+// Types of processors available to channels in config
+const (
+	ProcessorEmail    = "email"
+	ProcessorConsole  = "console"
+	ProcessorWebhook  = "webhook"
+	ProcessorWebPush  = "webpush"
+	ProcessorSms      = "sms"
+	ProcessorTelegram = "telegram"
+	ProcessorSlack    = "slack"
+)
+
+// ProcessorFactory builds processor of the channel declared in config
+type ProcessorFactory func(deps *ChannelDependencies, definition config.ChannelDefinition) (service.NotificationsProcessor, error)
+
+var processorFactories = map[string]ProcessorFactory{
+	ProcessorEmail:    newEmailProcessor,
+	ProcessorConsole:  newConsoleProcessor,
+	ProcessorWebhook:  newWebhookProcessor,
+	ProcessorWebPush:  newWebPushProcessor,
+	ProcessorSms:      newSmsProcessor,
+	ProcessorTelegram: newTelegramProcessor,
+	ProcessorSlack:    newSlackProcessor,
+}
+
+// RegisterProcessorFactory makes processor type available to channels in config, it must be called before Run
+func RegisterProcessorFactory(processorType string, factory ProcessorFactory) {
+	processorFactories[processorType] = factory
+}
+
+// ChannelDependencies are shared by processors of channels, clients of providers are created on first use
+type ChannelDependencies struct {
+	Cfg               *config.Config
+	RedisClient       *redis.Client
+	StatusTracker     service.NotificationsStatusTracker
+	Webhooks          *service.WebhooksService
+	PushSubscriptions *service.PushSubscriptionsService
+	// Vapid is nil when Web Push isn't configured
+	Vapid *webpush.Vapid
+
+	smtpClient     *smtp.Client
+	smsSender      service.SmsSender
+	smsMessages    service.SmsMessagesStore
+	smsReceipts    *service.SmsReceiptsService
+	healthCheckers []service.HealthChecker
+	closers        []func()
+}
+
+// SmtpClient connects to SMTP server once for all email channels
+func (d *ChannelDependencies) SmtpClient() (*smtp.Client, error) {
+	if d.smtpClient != nil {
+		return d.smtpClient, nil
+	}
+
+	smtpClient, err := smtp.NewClient(d.Cfg)
+	if err != nil {
+		return nil, fmt.Errorf("can't initialize SMTP client: %w", err)
+	}
+	d.smtpClient = smtpClient
+	d.healthCheckers = append(d.healthCheckers, health_checkers.NewSmtpHealthChecker(smtpClient))
+	d.onClose(func() { smtpClient.Close() })
+
+	return smtpClient, nil
+}
+
+// Sms creates sender of the configured provider, SMPP receipts are applied by receipts service
+func (d *ChannelDependencies) Sms() (service.SmsSender, service.SmsMessagesStore, error) {
+	if d.smsSender != nil {
+		return d.smsSender, d.smsMessages, nil
+	}
+
+	d.smsMessages = sms_messages_store.NewRedisSmsMessagesStore(d.RedisClient.GetClient(), d.Cfg)
+	d.smsReceipts = service.NewSmsReceiptsService(d.smsMessages, d.StatusTracker)
+
+	switch d.Cfg.Sms.Provider {
+	case sms_senders.ProviderSmpp:
+		smppClient, err := smpp.NewClient(d.Cfg, d.applySmppReceipt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't init SMPP client: %w", err)
+		}
+		d.onClose(func() { smppClient.Close() })
+		d.smsSender = sms_senders.NewSmppSmsSender(d.Cfg, smppClient)
+	case sms_senders.ProviderHttp:
+		d.smsSender = sms_senders.NewHttpSmsSender(d.Cfg)
+	default:
+		return nil, nil, fmt.Errorf("SMS provider %q can't send SMS", d.Cfg.Sms.Provider)
+	}
+
+	return d.smsSender, d.smsMessages, nil
+}
+
+func (d *ChannelDependencies) applySmppReceipt(receipt smpp.Receipt) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.Cfg.Sms.SmppTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	err := d.smsReceipts.Handle(ctx, entity.SmsReceipt{MessageID: receipt.MessageID, State: receipt.State, ErrorCode: receipt.Error})
+	if err != nil {
+		slog.Warn("Can't apply SMS receipt", slog.String("message_id", receipt.MessageID), slog.String("error", err.Error()))
+	}
+}
+
+// SmsReceiptsForHttp returns receipts service, when SMS are sent through HTTP gateway,
+// SMPP receipts come in the session
+func (d *ChannelDependencies) SmsReceiptsForHttp() *service.SmsReceiptsService {
+	if d.smsSender == nil || d.Cfg.Sms.Provider != sms_senders.ProviderHttp {
+		return nil
+	}
+	return d.smsReceipts
+}
+
+func (d *ChannelDependencies) onClose(closer func()) {
+	d.closers = append(d.closers, closer)
+}
+
+// Close closes clients in reverse order of creation
+func (d *ChannelDependencies) Close() {
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		d.closers[i]()
+	}
+}
+
+func newEmailProcessor(deps *ChannelDependencies, _ config.ChannelDefinition) (service.NotificationsProcessor, error) {
+	smtpClient, err := deps.SmtpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications_processor.NewEmailNotificationsProcessor(deps.Cfg, smtpClient), nil
+}
+
+// newConsoleProcessor options: name printed with notifications, channel key by default
+func newConsoleProcessor(_ *ChannelDependencies, definition config.ChannelDefinition) (service.NotificationsProcessor, error) {
+	name := definition.Options["name"]
+	if name == "" {
+		name = definition.Key
+	}
+
+	return &notifications_processor.ConsoleNotificationsProcessor{Name: name}, nil
+}
+
+func newWebhookProcessor(deps *ChannelDependencies, _ config.ChannelDefinition) (service.NotificationsProcessor, error) {
+	return notifications_processor.NewWebhookNotificationsProcessor(deps.Cfg, deps.Webhooks), nil
+}
+
+func newWebPushProcessor(deps *ChannelDependencies, _ config.ChannelDefinition) (service.NotificationsProcessor, error) {
+	if deps.Vapid == nil {
+		return nil, errors.New("WEBPUSH_VAPID_PRIVATE_KEY isn't set")
+	}
+
+	return notifications_processor.NewWebPushNotificationsProcessor(deps.Cfg, deps.Vapid, deps.PushSubscriptions), nil
+}
+
+func newSmsProcessor(deps *ChannelDependencies, _ config.ChannelDefinition) (service.NotificationsProcessor, error) {
+	sender, messages, err := deps.Sms()
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications_processor.NewSmsNotificationsProcessor(deps.Cfg, sender, messages), nil
+}
+
+// newTelegramProcessor options: api_url and bot_token override TELEGRAM_* variables
+func newTelegramProcessor(deps *ChannelDependencies, definition config.ChannelDefinition) (service.NotificationsProcessor, error) {
+	cfg := *deps.Cfg
+	overrideOption(&cfg.Telegram.APIURL, definition.Options, "api_url")
+	overrideOption(&cfg.Telegram.BotToken, definition.Options, "bot_token")
+	if cfg.Telegram.BotToken == "" {
+		return nil, errors.New("bot token of Telegram isn`t set")
+	}
+
+	return notifications_processor.NewTelegramNotificationsProcessor(&cfg), nil
+}
+
+// newSlackProcessor options: webhook_url overrides SLACK_WEBHOOK_URL, so channels can post to different Slack channels
+func newSlackProcessor(deps *ChannelDependencies, definition config.ChannelDefinition) (service.NotificationsProcessor, error) {
+	cfg := *deps.Cfg
+	overrideOption(&cfg.Slack.WebhookURL, definition.Options, "webhook_url")
+	if cfg.Slack.WebhookURL == "" {
+		return nil, errors.New("webhook URL of Slack isn`t set")
+	}
+
+	return notifications_processor.NewSlackNotificationsProcessor(&cfg), nil
+}
+
+func overrideOption(target *string, options map[string]string, name string) {
+	if value, ok := options[name]; ok && value != "" {
+		*target = value
+	}
+}
@@ -0,0 +1,103 @@
+package notifications_router
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/controller/http"
+	dead_notifications_processor "github.com/mwsbkru/evrone-go-final/internal/dead-notifications-processor"
+	fallback_chains_store "github.com/mwsbkru/evrone-go-final/internal/fallback-chains-store"
+	health_checkers "github.com/mwsbkru/evrone-go-final/internal/health-checkers"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/kafka"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/redis"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	notifications_observer "github.com/mwsbkru/evrone-go-final/internal/notifications-observer"
+	notifications_processor "github.com/mwsbkru/evrone-go-final/internal/notifications-processor"
+	preferences_store "github.com/mwsbkru/evrone-go-final/internal/preferences-store"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+	status_tracker "github.com/mwsbkru/evrone-go-final/internal/status-tracker"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+	ws_connections_registry "github.com/mwsbkru/evrone-go-final/internal/ws-connections-registry"
+)
+
+func Run(ctx context.Context, cfg *config.Config) error {
+	tracingProvider, err := tracing.NewProvider(ctx, cfg, "notifications-router")
+	if err != nil {
+		return fmt.Errorf("can't init tracing: %w", err)
+	}
+	defer tracingProvider.Close()
+
+	kafkaClient, err := kafka.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("can't init Kafka client: %w", err)
+	}
+	defer kafkaClient.Close()
+
+	redisClient, err := redis.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("can't init Redis client: %w", err)
+	}
+	defer redisClient.Close()
+
+	err = tools.EnsureTopicExists(cfg.Kafka.TopicDeadNotifications, kafkaClient.GetClient())
+	if err != nil {
+		return fmt.Errorf("can't prepare topic for dead notifications: %w", err)
+	}
+
+	producer, err := kafka.NewProducer([]string{cfg.Kafka.Brokers})
+	if err != nil {
+		return fmt.Errorf("can't init Kafka producer: %w", err)
+	}
+	defer producer.Close()
+
+	observer, err := notifications_observer.NewKafkaPriorityNotificationsObserver(cfg.Kafka.TopicNotificationRequests, cfg.Kafka.RouterConsumerGroupID, cfg, kafkaClient.GetClient())
+	if err != nil {
+		return fmt.Errorf("can't init Kafka observer of notification requests: %w", err)
+	}
+	defer observer.Close()
+
+	preferencesService := service.NewPreferencesService(preferences_store.NewRedisPreferencesStore(redisClient.GetClient()))
+	statusTracker := status_tracker.NewRedisStatusTracker(redisClient.GetClient(), cfg)
+	channelTopics, err := service.ChannelTopics(cfg)
+	if err != nil {
+		return fmt.Errorf("can't resolve channel topics: %w", err)
+	}
+	fallbackChains, err := service.ParseFallbackChains(cfg.Fallback.Chains, service.ChannelKeys(channelTopics))
+	if err != nil {
+		return fmt.Errorf("can't parse fallback chains: %w", err)
+	}
+	// Роутер не держит WS соединений, о присутствии отвечает реестр всех инстансов
+	wsConnectionsRegistry := ws_connections_registry.NewRedisWsConnectionsRegistry(redisClient.GetClient(), cfg)
+	fallbackCoordinator := service.NewFallbackCoordinator(cfg, fallbackChains,
+		fallback_chains_store.NewRedisFallbackChainsStore(redisClient.GetClient()), statusTracker, wsConnectionsRegistry)
+	routerProcessor := notifications_processor.NewKafkaRouterNotificationsProcessor(cfg, channelTopics, producer.GetProducer(), preferencesService, statusTracker, fallbackCoordinator)
+	deadProcessor := dead_notifications_processor.NewKafkaDeadNotificationsProcessor(producer.GetProducer(), cfg)
+
+	routerChannel := service.NewNotificationChannel(cfg, "Router", observer, routerProcessor, deadProcessor)
+	notificationsChannels := []*service.NotificationsChannel{routerChannel}
+	notificationsService := service.NewNotificationsService(notificationsChannels)
+
+	healthService := service.NewHealthService([]service.HealthChecker{
+		health_checkers.NewKafkaHealthChecker(kafkaClient.GetClient()),
+		health_checkers.NewRedisHealthChecker(redisClient.GetClient()),
+		health_checkers.NewConsumerSessionsHealthChecker(notificationsChannels),
+	})
+
+	go fallbackCoordinator.Run(ctx, routerProcessor)
+	go http.ServeService(ctx, cfg, http.ServiceAPI{
+		Health: healthService,
+		Status: service.NewStatusService(statusTracker),
+	})
+
+	// Останавливает чтение из Kafka и дожидается запросов в обработке
+	notificationsService.Run(ctx)
+
+	slog.Info("Flushing router producer")
+	if err := producer.Close(); err != nil {
+		slog.Error("Can't flush router producer", slog.String("error", err.Error()))
+	}
+
+	return nil
+}
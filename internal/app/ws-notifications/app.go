@@ -8,43 +8,106 @@ import (
 	"github.com/mwsbkru/evrone-go-final/config"
 	"github.com/mwsbkru/evrone-go-final/internal/controller/http"
 	dead_notifications_processor "github.com/mwsbkru/evrone-go-final/internal/dead-notifications-processor"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	health_checkers "github.com/mwsbkru/evrone-go-final/internal/health-checkers"
 	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/kafka"
 	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/redis"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	notifications_inbox "github.com/mwsbkru/evrone-go-final/internal/notifications-inbox"
 	notifications_observer "github.com/mwsbkru/evrone-go-final/internal/notifications-observer"
 	notifications_processor "github.com/mwsbkru/evrone-go-final/internal/notifications-processor"
+	notifications_scheduler "github.com/mwsbkru/evrone-go-final/internal/notifications-scheduler"
+	preferences_store "github.com/mwsbkru/evrone-go-final/internal/preferences-store"
+	rate_limiter "github.com/mwsbkru/evrone-go-final/internal/rate-limiter"
 	"github.com/mwsbkru/evrone-go-final/internal/service"
+	status_tracker "github.com/mwsbkru/evrone-go-final/internal/status-tracker"
 	"github.com/mwsbkru/evrone-go-final/internal/tools"
+	ws_connections_registry "github.com/mwsbkru/evrone-go-final/internal/ws-connections-registry"
+	ws_notifications_janitor "github.com/mwsbkru/evrone-go-final/internal/ws-notifications-janitor"
 	ws_notifications_receivers "github.com/mwsbkru/evrone-go-final/internal/ws-notifications-receivers"
 )
 
 func Run(ctx context.Context, cfg *config.Config) error {
+	tracingProvider, err := tracing.NewProvider(ctx, cfg, "ws-notifications")
+	if err != nil {
+		return fmt.Errorf("can't init tracing: %w", err)
+	}
+	defer tracingProvider.Close()
+
 	kafkaClient, err := kafka.NewClient(cfg)
 	if err != nil {
 		return fmt.Errorf("can't init Kafka client: %w", err)
 	}
 	defer kafkaClient.Close()
 
-	redisClient := redis.NewClient(cfg)
+	redisClient, err := redis.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("can't init Redis client: %w", err)
+	}
 	defer redisClient.Close()
 
-	notificationsChannelWs, consumerWs, producer, err := initializeWSNotificationChannel(cfg, kafkaClient, redisClient)
+	scheduler := notifications_scheduler.NewRedisNotificationsScheduler(redisClient.GetClient())
+	preferencesService := service.NewPreferencesService(preferences_store.NewRedisPreferencesStore(redisClient.GetClient()))
+	statusTracker := status_tracker.NewRedisStatusTracker(redisClient.GetClient(), cfg)
+
+	notificationsChannelWs, observerWs, producer, err := initializeWSNotificationChannel(cfg, kafkaClient, redisClient, scheduler, preferencesService, statusTracker)
 	if err != nil {
 		return fmt.Errorf("can't initialize WS notification channel: %w", err)
 	}
-	defer consumerWs.Close()
+	defer observerWs.Close()
 	defer producer.Close()
 
+	// Переносим ключи прошлой версии до чтения из Kafka, иначе записи старых стримов получат новые ID
+	janitor := ws_notifications_janitor.NewRedisWsNotificationsJanitor(redisClient.GetClient(), cfg)
+	if err := janitor.MigrateLegacyKeys(ctx); err != nil {
+		slog.Error("Can't migrate legacy Redis keys", slog.String("error", err.Error()))
+	}
+
 	notificationsChannels := []*service.NotificationsChannel{notificationsChannelWs}
 	notificationsService := service.NewNotificationsService(notificationsChannels)
-	go notificationsService.Run(ctx)
+	notificationsServiceDone := make(chan struct{})
+	go func() {
+		defer close(notificationsServiceDone)
+		notificationsService.Run(ctx)
+	}()
+
+	schedulerService := service.NewSchedulerService(cfg, scheduler, notificationsChannels)
+	go schedulerService.Run(ctx)
+
+	go janitor.Run(ctx)
 
 	slog.Info("Starting http server...")
-	wsNotificationsReceiver := ws_notifications_receivers.NewRedisWsNotificationsReceiver(redisClient.GetClient(), cfg)
-	wsNotificationsService := service.NewWsNotificationsService(wsNotificationsReceiver)
+	wsNotificationsReceiver := ws_notifications_receivers.NewRedisWsNotificationsReceiver(redisClient.GetClient(), cfg,
+		dead_notifications_processor.NewKafkaDeadNotificationsProcessor(producer.GetProducer(), cfg))
+	wsConnectionsRegistry := ws_connections_registry.NewRedisWsConnectionsRegistry(redisClient.GetClient(), cfg)
+	wsNotificationsService := service.NewWsNotificationsService(cfg, wsNotificationsReceiver, wsConnectionsRegistry)
 	wsNotificationsService.Run(ctx)
 
-	server := http.NewServer(cfg, wsNotificationsService)
-	http.Serve(ctx, server, cfg)
+	inboxService := service.NewInboxService(notifications_inbox.NewRedisNotificationsInbox(redisClient.GetClient(), cfg))
+
+	healthService := service.NewHealthService([]service.HealthChecker{
+		health_checkers.NewKafkaHealthChecker(kafkaClient.GetClient()),
+		health_checkers.NewRedisHealthChecker(redisClient.GetClient()),
+		health_checkers.NewConsumerSessionsHealthChecker(notificationsChannels),
+	})
+
+	statusService := service.NewStatusService(statusTracker)
+
+	server := http.NewServer(cfg, wsNotificationsService, inboxService, healthService, schedulerService, preferencesService, statusService)
+	err = http.Serve(ctx, server, cfg)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Waiting for in-flight WS notifications")
+	<-notificationsServiceDone
+
+	slog.Info("Flushing dead notifications producer")
+	if err := producer.Close(); err != nil {
+		slog.Error("Can't flush dead notifications producer", slog.String("error", err.Error()))
+	}
+
+	wsNotificationsService.Shutdown()
 
 	return nil
 }
@@ -54,27 +117,35 @@ func initializeWSNotificationChannel(
 	cfg *config.Config,
 	kafkaClient *kafka.Client,
 	redisClient *redis.Client,
-) (*service.NotificationsChannel, *kafka.Consumer, *kafka.Producer, error) {
-	consumerWs, err := kafka.NewConsumer(cfg.Kafka.ConsumerGroupID, kafkaClient.GetClient())
+	scheduler service.NotificationsScheduler,
+	preferencesService *service.PreferencesService,
+	statusTracker service.NotificationsStatusTracker,
+) (*service.NotificationsChannel, *notifications_observer.KafkaPriorityNotificationsObserver, *kafka.Producer, error) {
+	err := tools.EnsureTopicExists(cfg.Kafka.TopicDeadNotifications, kafkaClient.GetClient())
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("can't init Kafka WebSocket consumer: %w", err)
+		return nil, nil, nil, fmt.Errorf("can't prepare topic for dead notifications: %w", err)
 	}
 
-	err = tools.EnsureTopicExists(cfg.Kafka.TopicDeadNotifications, kafkaClient.GetClient())
+	topicWsNotifications := cfg.Kafka.TopicWSNotifications
+	kafkaObserverWs, err := notifications_observer.NewKafkaPriorityNotificationsObserver(topicWsNotifications, cfg.Kafka.ConsumerGroupID, cfg, kafkaClient.GetClient())
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("can't prepare topic for dead notifications: %w", err)
+		return nil, nil, nil, fmt.Errorf("can't init Kafka WebSocket observer: %w", err)
 	}
 
 	producer, err := kafka.NewProducer([]string{cfg.Kafka.Brokers})
 	if err != nil {
-		consumerWs.Close()
+		kafkaObserverWs.Close()
 		return nil, nil, nil, fmt.Errorf("can't init Kafka producer: %w", err)
 	}
 
-	topicWsNotifications := cfg.Kafka.TopicWSNotifications
-	kafkaObserverWs := notifications_observer.NewKafkaNotificationsObserver(topicWsNotifications, cfg, consumerWs.GetConsumer())
-	processorWs := notifications_processor.NewRedisWSNotificationsProcessor(redisClient.GetClient())
+	processorWs := notifications_processor.NewRedisWSNotificationsProcessor(redisClient.GetClient(), cfg)
 	deadProcessorWs := dead_notifications_processor.NewKafkaDeadNotificationsProcessor(producer.GetProducer(), cfg)
-	channel := service.NewNotificationChannel(cfg, "WS processor", kafkaObserverWs, processorWs, deadProcessorWs)
-	return channel, consumerWs, producer, nil
+	channel := service.NewNotificationChannel(cfg, "WS processor", kafkaObserverWs, processorWs, deadProcessorWs,
+		service.WithConcurrency(cfg.WSChannel.Workers, cfg.WSChannel.QueueSize),
+		service.WithOrdering(cfg.NotificationsOrdered || cfg.WSChannel.Ordered),
+		service.WithRateLimits(rate_limiter.NewRedisRateLimiter(redisClient.GetClient()), service.RateLimitsFromConfig(cfg.WSChannel)),
+		service.WithScheduler(scheduler),
+		service.WithPreferences(preferencesService, entity.ChannelWS),
+		service.WithStatusTracker(statusTracker, entity.ChannelWS))
+	return channel, kafkaObserverWs, producer, nil
 }
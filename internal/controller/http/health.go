@@ -0,0 +1,47 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/entity/dto"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+// Liveness tells that process is up and serves HTTP, dependencies aren't checked
+func Liveness(writer http.ResponseWriter, _ *http.Request) {
+	writeJSON(writer, http.StatusOK, entity.HealthReport{Status: entity.HealthStatusOK})
+}
+
+// Readiness checks dependencies of the service
+func Readiness(healthService *service.HealthService) func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		report := healthService.Ready(request.Context())
+
+		code := http.StatusOK
+		if report.Status != entity.HealthStatusOK {
+			code = http.StatusServiceUnavailable
+			slog.Warn("Readiness check failed", slog.Any("checks", report.Checks))
+		}
+
+		writeJSON(writer, code, report)
+	}
+}
+
+func writeJSON(writer http.ResponseWriter, code int, payload any) {
+	responseBody, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("can`t prepare HTTP response", slog.String("error", err.Error()))
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(code)
+	writer.Write(responseBody)
+}
+
+func writeError(writer http.ResponseWriter, code int, message string) {
+	writeJSON(writer, code, dto.ErrorResponse{Code: code, Message: message})
+}
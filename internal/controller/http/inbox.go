@@ -0,0 +1,94 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity/dto"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+func (s *Server) ListNotifications(writer http.ResponseWriter, request *http.Request) {
+	userEmail := request.PathValue("email")
+	query := request.URL.Query()
+
+	var limit int64
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		parsedLimit, err := strconv.ParseInt(rawLimit, 10, 64)
+		if err != nil || parsedLimit <= 0 {
+			s.respondWithError(writer, http.StatusBadRequest, "get param limit must be a positive integer")
+			return
+		}
+		limit = parsedLimit
+	}
+
+	ascending := false
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		ascending = true
+	default:
+		s.respondWithError(writer, http.StatusBadRequest, "get param order must be asc or desc")
+		return
+	}
+
+	page, err := s.inboxService.List(request.Context(), userEmail, query.Get("cursor"), limit, ascending)
+	if err != nil {
+		s.respondWithInboxError(writer, err)
+		return
+	}
+
+	s.respondWithJSON(writer, http.StatusOK, page)
+}
+
+func (s *Server) CountUnreadNotifications(writer http.ResponseWriter, request *http.Request) {
+	count, err := s.inboxService.UnreadCount(request.Context(), request.PathValue("email"))
+	if err != nil {
+		s.respondWithInboxError(writer, err)
+		return
+	}
+
+	s.respondWithJSON(writer, http.StatusOK, dto.UnreadCountResponse{UnreadCount: count})
+}
+
+func (s *Server) MarkNotificationsRead(writer http.ResponseWriter, request *http.Request) {
+	var body dto.MarkReadRequest
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		s.respondWithError(writer, http.StatusBadRequest, "request body must be JSON object with field id")
+		return
+	}
+
+	err = s.inboxService.MarkRead(request.Context(), request.PathValue("email"), body.ID)
+	if err != nil {
+		s.respondWithInboxError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) DeleteNotification(writer http.ResponseWriter, request *http.Request) {
+	err := s.inboxService.Delete(request.Context(), request.PathValue("email"), request.PathValue("id"))
+	if err != nil {
+		s.respondWithInboxError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) respondWithInboxError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidNotificationID):
+		s.respondWithError(writer, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrNotificationNotFound):
+		s.respondWithError(writer, http.StatusNotFound, err.Error())
+	default:
+		slog.Error("inbox request failed", slog.String("error", err.Error()))
+		s.respondWithError(writer, http.StatusInternalServerError, "internal error")
+	}
+}
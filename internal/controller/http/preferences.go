@@ -0,0 +1,126 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/entity/dto"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+type preferencesHandler struct {
+	preferencesService *service.PreferencesService
+}
+
+// registerPreferencesRoutes registers CRUD of user notification preferences
+func registerPreferencesRoutes(router *http.ServeMux, preferencesService *service.PreferencesService) {
+	handler := &preferencesHandler{preferencesService: preferencesService}
+
+	router.HandleFunc("GET /users/{email}/preferences", handler.Get)
+	router.HandleFunc("PUT /users/{email}/preferences", handler.Replace)
+	router.HandleFunc("PUT /users/{email}/preferences/{category}/{channel}", handler.Set)
+	router.HandleFunc("DELETE /users/{email}/preferences/{category}/{channel}", handler.Delete)
+	router.HandleFunc("PUT /users/{email}/preferences/quiet-hours", handler.SetQuietHours)
+	router.HandleFunc("DELETE /users/{email}/preferences/quiet-hours", handler.DeleteQuietHours)
+}
+
+func (h *preferencesHandler) Get(writer http.ResponseWriter, request *http.Request) {
+	preferences, err := h.preferencesService.Get(request.Context(), request.PathValue("email"))
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, preferences)
+}
+
+func (h *preferencesHandler) Replace(writer http.ResponseWriter, request *http.Request) {
+	var body dto.ReplacePreferencesRequest
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, "request body must be JSON object with field rules")
+		return
+	}
+
+	err = h.preferencesService.Replace(request.Context(), request.PathValue("email"), body.Rules)
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (h *preferencesHandler) Set(writer http.ResponseWriter, request *http.Request) {
+	var body dto.SetPreferenceRequest
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil || body.Enabled == nil {
+		writeError(writer, http.StatusBadRequest, "request body must be JSON object with boolean field enabled")
+		return
+	}
+
+	rule := entity.PreferenceRule{
+		Category: request.PathValue("category"),
+		Channel:  request.PathValue("channel"),
+		Enabled:  *body.Enabled,
+	}
+	err = h.preferencesService.Set(request.Context(), request.PathValue("email"), rule)
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (h *preferencesHandler) Delete(writer http.ResponseWriter, request *http.Request) {
+	err := h.preferencesService.Delete(request.Context(), request.PathValue("email"), request.PathValue("category"), request.PathValue("channel"))
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (h *preferencesHandler) SetQuietHours(writer http.ResponseWriter, request *http.Request) {
+	var body entity.QuietHours
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, "request body must be JSON object with fields start, end and time_zone")
+		return
+	}
+
+	err = h.preferencesService.SetQuietHours(request.Context(), request.PathValue("email"), body)
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (h *preferencesHandler) DeleteQuietHours(writer http.ResponseWriter, request *http.Request) {
+	err := h.preferencesService.DeleteQuietHours(request.Context(), request.PathValue("email"))
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (h *preferencesHandler) respondWithError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPreference):
+		writeError(writer, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrPreferenceNotFound):
+		writeError(writer, http.StatusNotFound, err.Error())
+	default:
+		slog.Error("preferences request failed", slog.String("error", err.Error()))
+		writeError(writer, http.StatusInternalServerError, "internal error")
+	}
+}
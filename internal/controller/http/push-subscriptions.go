@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/entity/dto"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+type pushSubscriptionsHandler struct {
+	pushSubscriptionsService *service.PushSubscriptionsService
+}
+
+// registerPushSubscriptionsRoutes registers VAPID key for browsers and management of their Web Push subscriptions.
+// Subscriptions of the user are managed with token bound to the email, they aren't served without token secret.
+func registerPushSubscriptionsRoutes(router *http.ServeMux, pushSubscriptionsService *service.PushSubscriptionsService, tokenSecret string) {
+	handler := &pushSubscriptionsHandler{pushSubscriptionsService: pushSubscriptionsService}
+
+	router.HandleFunc("GET /push/vapid-public-key", handler.VapidPublicKey)
+	if tokenSecret == "" {
+		slog.Warn("Push subscriptions endpoints are disabled, USER_TOKEN_SECRET isn't set")
+		return
+	}
+	router.HandleFunc("GET /users/{email}/push-subscriptions", userAuthorized(tokenSecret, handler.List))
+	router.HandleFunc("POST /users/{email}/push-subscriptions", userAuthorized(tokenSecret, handler.Register))
+	router.HandleFunc("DELETE /users/{email}/push-subscriptions/{id}", userAuthorized(tokenSecret, handler.Delete))
+}
+
+func (h *pushSubscriptionsHandler) VapidPublicKey(writer http.ResponseWriter, _ *http.Request) {
+	publicKey, err := h.pushSubscriptionsService.VapidPublicKey()
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, dto.VapidPublicKeyResponse{PublicKey: publicKey})
+}
+
+func (h *pushSubscriptionsHandler) List(writer http.ResponseWriter, request *http.Request) {
+	subscriptions, err := h.pushSubscriptionsService.List(request.Context(), request.PathValue("email"))
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, subscriptions)
+}
+
+func (h *pushSubscriptionsHandler) Register(writer http.ResponseWriter, request *http.Request) {
+	var body entity.PushSubscription
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, "request body must be JSON of PushSubscription with fields endpoint and keys")
+		return
+	}
+
+	subscription, err := h.pushSubscriptionsService.Register(request.Context(), request.PathValue("email"), body)
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writeJSON(writer, http.StatusCreated, subscription)
+}
+
+func (h *pushSubscriptionsHandler) Delete(writer http.ResponseWriter, request *http.Request) {
+	err := h.pushSubscriptionsService.Delete(request.Context(), request.PathValue("email"), request.PathValue("id"))
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (h *pushSubscriptionsHandler) respondWithError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPushSubscription):
+		writeError(writer, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrPushSubscriptionNotFound), errors.Is(err, service.ErrWebPushNotConfigured):
+		writeError(writer, http.StatusNotFound, err.Error())
+	default:
+		slog.Error("push subscriptions request failed", slog.String("error", err.Error()))
+		writeError(writer, http.StatusInternalServerError, "internal error")
+	}
+}
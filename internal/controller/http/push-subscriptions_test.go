@@ -0,0 +1,61 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPushSubscriptionsRequireTokenOfUser(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{name: "without token", status: http.StatusUnauthorized},
+		{name: "token of another user", authorization: "Bearer " + UserToken("token-secret", "attacker@example.com"), status: http.StatusUnauthorized},
+		{name: "token signed with another secret", authorization: "Bearer " + UserToken("other-secret", "user@example.com"), status: http.StatusUnauthorized},
+		{name: "token of the user", authorization: "Bearer " + UserToken("token-secret", "user@example.com"), status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &service.MockPushSubscriptionsStore{}
+			store.On("List", mock.Anything, "user@example.com").Return([]entity.PushSubscription{}, nil)
+			router := http.NewServeMux()
+			registerPushSubscriptionsRoutes(router, service.NewPushSubscriptionsService(&config.Config{}, store, "public-key"), "token-secret")
+
+			request := httptest.NewRequest(http.MethodGet, "/users/user@example.com/push-subscriptions", nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			if tt.status == http.StatusUnauthorized {
+				store.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestPushSubscriptionsAreNotServedWithoutTokenSecret(t *testing.T) {
+	router := http.NewServeMux()
+	registerPushSubscriptionsRoutes(router, service.NewPushSubscriptionsService(&config.Config{}, nil, "public-key"), "")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/users/user@example.com/push-subscriptions", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/push/vapid-public-key", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -2,22 +2,109 @@ package http
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func Serve(ctx context.Context, server *Server, cfg *config.Config) {
+func Serve(ctx context.Context, server *Server, cfg *config.Config) error {
 	router := http.NewServeMux()
 
 	router.HandleFunc("GET /notifications/subscribe", server.SubscribeNotifications(ctx))
+	router.HandleFunc("GET /users/{email}/notifications", server.ListNotifications)
+	router.HandleFunc("GET /users/{email}/notifications/unread-count", server.CountUnreadNotifications)
+	router.HandleFunc("POST /users/{email}/notifications/read", server.MarkNotificationsRead)
+	router.HandleFunc("DELETE /users/{email}/notifications/{id}", server.DeleteNotification)
+	router.HandleFunc("DELETE /scheduled-notifications/{id}", CancelScheduledNotification(server.schedulerService))
+	registerPreferencesRoutes(router, server.preferencesService)
+	router.HandleFunc("GET /notifications/{correlation_id}/status", GetNotificationStatus(server.statusService))
+	router.Handle("GET /metrics", promhttp.Handler())
+	router.HandleFunc("GET /healthz", Liveness)
+	router.HandleFunc("GET /readyz", Readiness(server.healthService))
 
 	srv := &http.Server{Handler: router, Addr: fmt.Sprintf("%s:%s", cfg.WS.Host, cfg.WS.Port)}
+	return listenAndServe(ctx, srv, cfg)
+}
 
-	err := srv.ListenAndServe()
+// ServiceAPI services served by HTTP listener of services without own HTTP API, nil ones aren't served
+type ServiceAPI struct {
+	Health      *service.HealthService
+	Scheduler   *service.SchedulerService
+	Preferences *service.PreferencesService
+	Status      *service.StatusService
+	Webhooks    *service.WebhooksService
+	SmsReceipts *service.SmsReceiptsService
+	// PushSubscriptions stores subscriptions of browsers for Web Push
+	PushSubscriptions *service.PushSubscriptionsService
+}
+
+// ServeService serves metrics, probes and API of services, which don't have own HTTP API
+func ServeService(ctx context.Context, cfg *config.Config, api ServiceAPI) {
+	router := http.NewServeMux()
+
+	if api.Scheduler != nil {
+		router.HandleFunc("DELETE /scheduled-notifications/{id}", CancelScheduledNotification(api.Scheduler))
+	}
+	if api.Preferences != nil {
+		registerPreferencesRoutes(router, api.Preferences)
+	}
+	if api.Status != nil {
+		router.HandleFunc("GET /notifications/{correlation_id}/status", GetNotificationStatus(api.Status))
+	}
+	if api.Webhooks != nil {
+		registerWebhooksRoutes(router, api.Webhooks, cfg.ServiceHTTP.UserTokenSecret)
+	}
+	if api.SmsReceipts != nil {
+		if cfg.Sms.GatewayReceiptsToken != "" {
+			router.HandleFunc("POST /sms/receipts", HandleSmsReceipt(api.SmsReceipts, cfg.Sms.GatewayReceiptsToken))
+		} else {
+			slog.Warn("SMS receipts endpoint is disabled, SMS_GATEWAY_RECEIPTS_TOKEN isn't set")
+		}
+	}
+	if api.PushSubscriptions != nil {
+		registerPushSubscriptionsRoutes(router, api.PushSubscriptions, cfg.ServiceHTTP.UserTokenSecret)
+	}
+
+	router.Handle("GET /metrics", promhttp.Handler())
+	router.HandleFunc("GET /healthz", Liveness)
+	router.HandleFunc("GET /readyz", Readiness(api.Health))
+
+	srv := &http.Server{Handler: router, Addr: fmt.Sprintf("%s:%s", cfg.ServiceHTTP.Host, cfg.ServiceHTTP.Port)}
+	err := listenAndServe(ctx, srv, cfg)
 	if err != nil {
 		slog.Error("srv.ListenAndServe", "err", err)
 	}
 }
+
+// listenAndServe serves until ctx is done, then waits for active requests up to shutdown timeout
+func listenAndServe(ctx context.Context, srv *http.Server, cfg *config.Config) error {
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+		defer cancel()
+
+		slog.Info("Shutting down HTTP server", slog.String("addr", srv.Addr))
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil {
+			slog.Error("srv.Shutdown", "err", err)
+		}
+	}()
+
+	err := srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("can't serve HTTP on %s: %w", srv.Addr, err)
+	}
+
+	<-shutdownDone
+	return nil
+}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+// CancelScheduledNotification removes notification from the schedule by its ID
+func CancelScheduledNotification(schedulerService *service.SchedulerService) func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		err := schedulerService.Cancel(request.Context(), request.PathValue("id"))
+		switch {
+		case err == nil:
+			writer.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, service.ErrScheduledNotificationNotFound):
+			writeError(writer, http.StatusNotFound, err.Error())
+		default:
+			slog.Error("scheduled notification cancel failed", slog.String("error", err.Error()))
+			writeError(writer, http.StatusInternalServerError, "internal error")
+		}
+	}
+}
@@ -16,10 +16,15 @@ import (
 type Server struct {
 	cfg                    *config.Config
 	wsNotificationsService *service.WsNotificationsService
+	inboxService           *service.InboxService
+	healthService          *service.HealthService
+	schedulerService       *service.SchedulerService
+	preferencesService     *service.PreferencesService
+	statusService          *service.StatusService
 	upgrader               *websocket.Upgrader
 }
 
-func NewServer(cfg *config.Config, wsNotificationsService *service.WsNotificationsService) *Server {
+func NewServer(cfg *config.Config, wsNotificationsService *service.WsNotificationsService, inboxService *service.InboxService, healthService *service.HealthService, schedulerService *service.SchedulerService, preferencesService *service.PreferencesService, statusService *service.StatusService) *Server {
 	upgrader := websocket.Upgrader{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
@@ -28,7 +33,7 @@ func NewServer(cfg *config.Config, wsNotificationsService *service.WsNotificatio
 			return !cfg.WS.CheckOrigin || origin == cfg.WS.AllowedOrigin
 		},
 	}
-	return &Server{cfg: cfg, wsNotificationsService: wsNotificationsService, upgrader: &upgrader}
+	return &Server{cfg: cfg, wsNotificationsService: wsNotificationsService, inboxService: inboxService, healthService: healthService, schedulerService: schedulerService, preferencesService: preferencesService, statusService: statusService, upgrader: &upgrader}
 }
 
 func (s *Server) SubscribeNotifications(ctx context.Context) func(http.ResponseWriter, *http.Request) {
@@ -64,3 +69,7 @@ func (s *Server) respondWithError(writer http.ResponseWriter, code int, message
 	writer.WriteHeader(code)
 	writer.Write(responseBody)
 }
+
+func (s *Server) respondWithJSON(writer http.ResponseWriter, code int, payload any) {
+	writeJSON(writer, code, payload)
+}
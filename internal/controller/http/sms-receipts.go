@@ -0,0 +1,42 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+// HandleSmsReceipt accepts delivery receipt from HTTP SMS gateway, state uses SMPP names, e.g. DELIVRD or UNDELIV.
+// Gateway authenticates with the shared token in header "Authorization: Bearer <token>".
+func HandleSmsReceipt(receiptsService *service.SmsReceiptsService, token string) func(http.ResponseWriter, *http.Request) {
+	expected := []byte("Bearer " + token)
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(request.Header.Get("Authorization")), expected) != 1 {
+			writeError(writer, http.StatusUnauthorized, "invalid SMS gateway token")
+			return
+		}
+
+		var receipt entity.SmsReceipt
+		err := json.NewDecoder(request.Body).Decode(&receipt)
+		if err != nil || receipt.MessageID == "" || receipt.State == "" {
+			writeError(writer, http.StatusBadRequest, "request body must be JSON object with fields message_id and state")
+			return
+		}
+
+		err = receiptsService.Handle(request.Context(), receipt)
+		switch {
+		case err == nil:
+			writer.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, service.ErrSmsMessageNotFound):
+			writeError(writer, http.StatusNotFound, err.Error())
+		default:
+			slog.Error("SMS receipt request failed", slog.String("error", err.Error()))
+			writeError(writer, http.StatusInternalServerError, "internal error")
+		}
+	}
+}
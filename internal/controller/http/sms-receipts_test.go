@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleSmsReceiptRequiresGatewayToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{name: "without token", authorization: "", status: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer wrong", status: http.StatusUnauthorized},
+		{name: "token without scheme", authorization: "receipts-token", status: http.StatusUnauthorized},
+		{name: "valid token", authorization: "Bearer receipts-token", status: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := &service.MockSmsMessagesStore{}
+			messages.On("Get", mock.Anything, "m1").Return("corr", true, nil)
+			messages.On("SaveState", mock.Anything, "corr", "m1", entity.SmsStateDelivered).
+				Return(entity.SmsParts{Parts: 1, States: map[string]string{"m1": entity.SmsStateDelivered}}, nil)
+			tracker := &service.MockNotificationsStatusTracker{}
+			tracker.On("Track", mock.Anything, "corr", entity.ChannelSms, mock.Anything).Return(nil)
+			handler := HandleSmsReceipt(service.NewSmsReceiptsService(messages, tracker), "receipts-token")
+
+			request := httptest.NewRequest(http.MethodPost, "/sms/receipts", strings.NewReader(`{"message_id": "m1", "state": "DELIVRD"}`))
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			handler(recorder, request)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			if tt.status == http.StatusUnauthorized {
+				messages.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
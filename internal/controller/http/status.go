@@ -0,0 +1,25 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+// GetNotificationStatus returns statuses of one logical notification across channels
+func GetNotificationStatus(statusService *service.StatusService) func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		status, err := statusService.Get(request.Context(), request.PathValue("correlation_id"))
+		switch {
+		case err == nil:
+			writeJSON(writer, http.StatusOK, status)
+		case errors.Is(err, service.ErrStatusNotFound):
+			writeError(writer, http.StatusNotFound, err.Error())
+		default:
+			slog.Error("notification status request failed", slog.String("error", err.Error()))
+			writeError(writer, http.StatusInternalServerError, "internal error")
+		}
+	}
+}
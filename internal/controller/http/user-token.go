@@ -0,0 +1,29 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+)
+
+// UserToken returns token of the user for endpoints under /users/{email}: hex HMAC-SHA256 of the email
+func UserToken(secret string, userEmail string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(userEmail))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// userAuthorized passes requests with header "Authorization: Bearer <token>" issued for the email of the path
+func userAuthorized(secret string, next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		expected := []byte("Bearer " + UserToken(secret, request.PathValue("email")))
+		if subtle.ConstantTimeCompare([]byte(request.Header.Get("Authorization")), expected) != 1 {
+			writeError(writer, http.StatusUnauthorized, "invalid token of the user")
+			return
+		}
+
+		next(writer, request)
+	}
+}
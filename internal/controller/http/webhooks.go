@@ -0,0 +1,78 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity/dto"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+type webhooksHandler struct {
+	webhooksService *service.WebhooksService
+}
+
+// registerWebhooksRoutes registers management of user webhook subscriptions.
+// Webhooks of the user are managed with token bound to the email, they aren't served without token secret.
+func registerWebhooksRoutes(router *http.ServeMux, webhooksService *service.WebhooksService, tokenSecret string) {
+	if tokenSecret == "" {
+		slog.Warn("Webhooks endpoints are disabled, USER_TOKEN_SECRET isn't set")
+		return
+	}
+	handler := &webhooksHandler{webhooksService: webhooksService}
+
+	router.HandleFunc("GET /users/{email}/webhooks", userAuthorized(tokenSecret, handler.List))
+	router.HandleFunc("POST /users/{email}/webhooks", userAuthorized(tokenSecret, handler.Create))
+	router.HandleFunc("DELETE /users/{email}/webhooks/{id}", userAuthorized(tokenSecret, handler.Delete))
+}
+
+func (h *webhooksHandler) List(writer http.ResponseWriter, request *http.Request) {
+	subscriptions, err := h.webhooksService.List(request.Context(), request.PathValue("email"))
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, subscriptions)
+}
+
+func (h *webhooksHandler) Create(writer http.ResponseWriter, request *http.Request) {
+	var body dto.CreateWebhookRequest
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, "request body must be JSON object with field url")
+		return
+	}
+
+	subscription, err := h.webhooksService.Create(request.Context(), request.PathValue("email"), body.URL, body.Secret, body.Categories)
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writeJSON(writer, http.StatusCreated, subscription)
+}
+
+func (h *webhooksHandler) Delete(writer http.ResponseWriter, request *http.Request) {
+	err := h.webhooksService.Delete(request.Context(), request.PathValue("email"), request.PathValue("id"))
+	if err != nil {
+		h.respondWithError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (h *webhooksHandler) respondWithError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidWebhook):
+		writeError(writer, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrWebhookNotFound):
+		writeError(writer, http.StatusNotFound, err.Error())
+	default:
+		slog.Error("webhooks request failed", slog.String("error", err.Error()))
+		writeError(writer, http.StatusInternalServerError, "internal error")
+	}
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWebhooksRequireTokenOfUser(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		authorization string
+		status        int
+	}{
+		{name: "create without token", method: http.MethodPost, path: "/users/user@example.com/webhooks",
+			body: `{"url": "https://partner.example.com/hooks"}`, status: http.StatusUnauthorized},
+		{name: "create with token of another user", method: http.MethodPost, path: "/users/user@example.com/webhooks",
+			body: `{"url": "https://partner.example.com/hooks"}`, authorization: "Bearer " + UserToken("token-secret", "attacker@example.com"), status: http.StatusUnauthorized},
+		{name: "delete without token", method: http.MethodDelete, path: "/users/user@example.com/webhooks/w1", status: http.StatusUnauthorized},
+		{name: "list with wrong token", method: http.MethodGet, path: "/users/user@example.com/webhooks", authorization: "Bearer wrong", status: http.StatusUnauthorized},
+		{name: "create with token of the user", method: http.MethodPost, path: "/users/user@example.com/webhooks",
+			body: `{"url": "https://partner.example.com/hooks"}`, authorization: "Bearer " + UserToken("token-secret", "user@example.com"), status: http.StatusCreated},
+		{name: "delete with token of the user", method: http.MethodDelete, path: "/users/user@example.com/webhooks/w1",
+			authorization: "Bearer " + UserToken("token-secret", "user@example.com"), status: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &service.MockWebhooksStore{}
+			store.On("Save", mock.Anything, mock.Anything).Return(nil)
+			store.On("Delete", mock.Anything, "user@example.com", "w1").Return(true, nil)
+			router := http.NewServeMux()
+			registerWebhooksRoutes(router, service.NewWebhooksService(&config.Config{}, store), "token-secret")
+
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			if tt.status == http.StatusUnauthorized {
+				assert.Empty(t, store.Calls)
+			}
+		})
+	}
+}
+
+func TestWebhooksAreNotServedWithoutTokenSecret(t *testing.T) {
+	router := http.NewServeMux()
+	registerWebhooksRoutes(router, service.NewWebhooksService(&config.Config{}, nil), "")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/user@example.com/webhooks", nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/mwsbkru/evrone-go-final/config"
 	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
 
 	"github.com/IBM/sarama"
 )
@@ -31,9 +33,11 @@ func (k *KafkaDeadNotificationsProcessor) Process(notification *entity.Notificat
 	payload := struct {
 		Notification *entity.Notification `json:"notification"`
 		Error        string               `json:"error"`
+		Reason       string               `json:"reason"`
 	}{
 		Notification: notification,
 		Error:        err.Error(),
+		Reason:       service.DeadNotificationReason(err),
 	}
 
 	// Преобразуем структуру в JSON
@@ -44,8 +48,10 @@ func (k *KafkaDeadNotificationsProcessor) Process(notification *entity.Notificat
 
 	// Создаем сообщение для Kafka
 	msg := &sarama.ProducerMessage{
-		Topic: k.cfg.Kafka.TopicDeadNotifications, // имя топика Kafka
-		Value: sarama.StringEncoder(payloadJSON),
+		Topic:   k.cfg.Kafka.TopicDeadNotifications, // имя топика Kafka
+		Key:     sarama.StringEncoder(notification.UserEmail),
+		Value:   sarama.StringEncoder(payloadJSON),
+		Headers: tracing.KafkaHeadersFromNotification(notification),
 	}
 
 	// Отправляем сообщение в Kafka
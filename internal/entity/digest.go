@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+const (
+	DigestWindowHourly = "hourly"
+	DigestWindowDaily  = "daily"
+)
+
+// DigestWindow notifications of the user and category collected until End
+type DigestWindow struct {
+	UserEmail string    `json:"user_email"`
+	Category  string    `json:"category"`
+	End       time.Time `json:"end"`
+}
+
+// Digest collected notifications of the window, Total may exceed amount of Notifications
+type Digest struct {
+	DigestWindow
+	Notifications []Notification
+	Total         int
+}
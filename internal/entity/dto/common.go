@@ -1,7 +1,42 @@
 package dto
 
+import "github.com/mwsbkru/evrone-go-final/internal/entity"
+
 // ErrorResponse represents error response body
 type ErrorResponse struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
+
+// MarkReadRequest represents request body for marking inbox notifications as read
+type MarkReadRequest struct {
+	ID string `json:"id"`
+}
+
+// UnreadCountResponse represents response body with count of unread inbox notifications
+type UnreadCountResponse struct {
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// SetPreferenceRequest represents request body for opting user in or out of category in channel
+type SetPreferenceRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// ReplacePreferencesRequest represents request body for replacing all preferences of user
+type ReplacePreferencesRequest struct {
+	Rules []entity.PreferenceRule `json:"rules"`
+}
+
+// CreateWebhookRequest represents request body for registering webhook of user,
+// secret is generated when it isn't passed
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	Categories []string `json:"categories"`
+}
+
+// VapidPublicKeyResponse represents application server key, browsers pass it to pushManager.subscribe
+type VapidPublicKeyResponse struct {
+	PublicKey string `json:"public_key"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// FallbackStep channel of fallback chain, Timeout is time to wait for delivery before the next step.
+// Zero Timeout waits for the final outcome of the channel.
+type FallbackStep struct {
+	Channel string        `json:"channel"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+type FallbackChain struct {
+	Name  string         `json:"name"`
+	Steps []FallbackStep `json:"steps"`
+}
+
+// FallbackState progress of notification through its fallback chain
+type FallbackState struct {
+	Notification *Notification `json:"notification"`
+	Chain        FallbackChain `json:"chain"`
+	Step         int           `json:"step"`
+	StepStarted  time.Time     `json:"step_started"`
+}
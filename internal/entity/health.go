@@ -0,0 +1,12 @@
+package entity
+
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+)
+
+// HealthReport result of dependency checks, Checks holds "ok" or error text per dependency
+type HealthReport struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
@@ -0,0 +1,15 @@
+package entity
+
+// InboxNotification notification stored in the user's inbox
+type InboxNotification struct {
+	ID           string       `json:"id"`
+	Read         bool         `json:"read"`
+	Notification Notification `json:"notification"`
+}
+
+// InboxPage one page of the user's inbox
+type InboxPage struct {
+	Items       []InboxNotification `json:"items"`
+	NextCursor  string              `json:"next_cursor,omitempty"`
+	UnreadCount int64               `json:"unread_count"`
+}
@@ -1,9 +1,62 @@
 package entity
 
+import "time"
+
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
 type Notification struct {
-	UserEmail    string `json:"user_email"`
-	Subject      string `json:"subject"`
-	Body         string `json:"body"`
+	// ID identifies notification, it's required to cancel scheduled notification.
+	// When it's empty, scheduler generates it and reports it in the status of CorrelationID.
+	ID        string `json:"id,omitempty"`
+	UserEmail string `json:"user_email"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+	// Phone of the user in international format, it's required by SMS channel
+	Phone string `json:"phone,omitempty"`
+	// Category groups notifications for user preferences, e.g. security or marketing
+	Category string `json:"category,omitempty"`
+	// CorrelationID is shared by notifications of one request fanned out to several channels
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Channels of notification request: email, push, ws or auto to take them from user preferences.
+	// It's used by router only.
+	Channels []string `json:"channels,omitempty"`
+	// Fallback is name of fallback chain, channels of the chain are tried one by one instead of Channels
+	Fallback string `json:"fallback,omitempty"`
+	// Digest marks summary of collected notifications, it isn't collected again
+	Digest bool `json:"digest,omitempty"`
+	// Priority is high, normal or low, by default it's taken from the topic lane notification came from
+	Priority string `json:"priority,omitempty"`
+	// SendAt postpones processing until the given time
+	SendAt *time.Time `json:"send_at,omitempty"`
+	// ExpiresAt notification isn't delivered after this time, TTLSeconds sets it relative to the time notification was produced
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	TTLSeconds   int        `json:"ttl_seconds,omitempty"`
 	CurrentRetry int
 	Channel      string
+	// DeliveryID is generated once for notification without ID and CorrelationID, receivers deduplicate retries by it
+	DeliveryID string `json:"delivery_id,omitempty"`
+	// DeliveredTo IDs of user's webhooks or push subscriptions, which already accepted notification, retries skip them
+	DeliveredTo []string `json:"delivered_to,omitempty"`
+	// RecipientRateLimitCounted notification already took hit of recipient limit, its retries take only hits of channel limit
+	RecipientRateLimitCounted bool `json:"-"`
+	// TraceContext W3C trace context, travels in Kafka headers and in a separate field of Redis stream entry
+	TraceContext map[string]string `json:"-"`
+}
+
+// ResolveExpiry sets ExpiresAt from TTLSeconds, explicit ExpiresAt wins
+func (n *Notification) ResolveExpiry(producedAt time.Time) {
+	if n.ExpiresAt != nil || n.TTLSeconds <= 0 {
+		return
+	}
+
+	expiresAt := producedAt.Add(time.Duration(n.TTLSeconds) * time.Second)
+	n.ExpiresAt = &expiresAt
+}
+
+func (n *Notification) Expired(now time.Time) bool {
+	return n.ExpiresAt != nil && !now.Before(*n.ExpiresAt)
 }
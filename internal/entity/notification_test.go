@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveExpiry(t *testing.T) {
+	producedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	explicit := producedAt.Add(time.Hour)
+
+	tests := []struct {
+		name         string
+		notification Notification
+		expected     *time.Time
+	}{
+		{name: "TTL relative to production", notification: Notification{TTLSeconds: 60}, expected: ptr(producedAt.Add(time.Minute))},
+		{name: "explicit expiry wins", notification: Notification{TTLSeconds: 60, ExpiresAt: &explicit}, expected: &explicit},
+		{name: "without TTL", notification: Notification{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.notification.ResolveExpiry(producedAt)
+
+			if tt.expected == nil {
+				assert.Nil(t, tt.notification.ExpiresAt)
+				return
+			}
+			require.NotNil(t, tt.notification.ExpiresAt)
+			assert.Equal(t, *tt.expected, *tt.notification.ExpiresAt)
+		})
+	}
+}
+
+func TestExpired(t *testing.T) {
+	expiresAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	notification := Notification{ExpiresAt: &expiresAt}
+
+	assert.False(t, notification.Expired(expiresAt.Add(-time.Nanosecond)))
+	assert.True(t, notification.Expired(expiresAt))
+	assert.False(t, (&Notification{}).Expired(expiresAt), "notification without expiry never expires")
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
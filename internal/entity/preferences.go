@@ -0,0 +1,103 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+	// Базовый образ alpine не содержит базы часовых поясов
+	_ "time/tzdata"
+)
+
+const quietHoursLayout = "15:04"
+
+// PreferenceWildcard matches any category or channel
+const PreferenceWildcard = "*"
+
+// Keys of built-in channels, channels declared in config file may use other keys
+const (
+	ChannelEmail    = "email"
+	ChannelPush     = "push"
+	ChannelWS       = "ws"
+	ChannelWebhook  = "webhook"
+	ChannelSms      = "sms"
+	ChannelTelegram = "telegram"
+	ChannelSlack    = "slack"
+)
+
+// PreferenceRule opts user in or out of the category of notifications in the channel
+type PreferenceRule struct {
+	Category string `json:"category"`
+	Channel  string `json:"channel"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// Preferences rules of the user, the most specific rule wins: category and channel,
+// then category with any channel, then any category with channel, then any of both.
+// Without matching rule notification is allowed.
+type Preferences struct {
+	UserEmail string           `json:"user_email"`
+	Rules     []PreferenceRule `json:"rules"`
+	// QuietHours non-urgent notifications are deferred until the end of quiet hours
+	QuietHours *QuietHours `json:"quiet_hours,omitempty"`
+}
+
+// QuietHours daily window in user's time zone, Start and End are HH:MM, window may cross midnight
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"time_zone"`
+}
+
+func (q *QuietHours) Validate() error {
+	if _, err := time.Parse(quietHoursLayout, q.Start); err != nil {
+		return fmt.Errorf("start must be HH:MM: %w", err)
+	}
+	if _, err := time.Parse(quietHoursLayout, q.End); err != nil {
+		return fmt.Errorf("end must be HH:MM: %w", err)
+	}
+	if _, err := q.location(); err != nil {
+		return fmt.Errorf("time_zone must be IANA time zone: %w", err)
+	}
+
+	return nil
+}
+
+// location loads time zone of quiet hours. Empty name and Local are refused, LoadLocation turns them
+// into UTC and zone of the server, so quiet hours would depend on deployment host.
+func (q *QuietHours) location() (*time.Location, error) {
+	if q.TimeZone == "" || q.TimeZone == "Local" {
+		return nil, fmt.Errorf("time zone %q isn't IANA time zone name", q.TimeZone)
+	}
+	return time.LoadLocation(q.TimeZone)
+}
+
+// Until returns the end of quiet hours, if now is inside them
+func (q *QuietHours) Until(now time.Time) (time.Time, bool) {
+	start, errStart := time.Parse(quietHoursLayout, q.Start)
+	end, errEnd := time.Parse(quietHoursLayout, q.End)
+	location, errLocation := q.location()
+	if errStart != nil || errEnd != nil || errLocation != nil {
+		return time.Time{}, false
+	}
+
+	local := now.In(location)
+	current := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	day := local.Day()
+	switch {
+	case startMinute == endMinute:
+		return time.Time{}, false
+	case startMinute < endMinute:
+		if current < startMinute || current >= endMinute {
+			return time.Time{}, false
+		}
+	case current >= startMinute:
+		// Окно переходит через полночь и закончится завтра
+		day++
+	case current >= endMinute:
+		return time.Time{}, false
+	}
+
+	return time.Date(local.Year(), local.Month(), day, end.Hour(), end.Minute(), 0, 0, location), true
+}
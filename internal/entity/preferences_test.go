@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuietHoursValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeZone string
+		valid    bool
+	}{
+		{name: "IANA zone", timeZone: "Europe/Moscow", valid: true},
+		{name: "UTC", timeZone: "UTC", valid: true},
+		{name: "empty zone", timeZone: "", valid: false},
+		{name: "local zone of server", timeZone: "Local", valid: false},
+		{name: "unknown zone", timeZone: "Mars/Olympus", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quietHours := QuietHours{Start: "22:00", End: "08:00", TimeZone: tt.timeZone}
+			err := quietHours.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
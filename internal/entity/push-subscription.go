@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// PushSubscriptionKeys keys of browser PushSubscription in base64url
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+// PushSubscription Web Push subscription of the user's browser, it's JSON of PushSubscription.toJSON()
+type PushSubscription struct {
+	// ID is derived from endpoint, so repeated registration of the browser replaces its subscription
+	ID             string               `json:"id"`
+	UserEmail      string               `json:"user_email"`
+	Endpoint       string               `json:"endpoint"`
+	ExpirationTime *int64               `json:"expirationTime,omitempty"`
+	Keys           PushSubscriptionKeys `json:"keys"`
+	CreatedAt      time.Time            `json:"created_at"`
+}
@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// RateLimit at most Limit hits of Key per Period
+type RateLimit struct {
+	Key    string
+	Limit  int
+	Period time.Duration
+}
@@ -0,0 +1,63 @@
+package entity
+
+// Final message states of SMPP delivery receipt, HTTP gateways report them in the same form
+const (
+	SmsStateDelivered   = "DELIVRD"
+	SmsStateExpired     = "EXPIRED"
+	SmsStateDeleted     = "DELETED"
+	SmsStateUndelivered = "UNDELIV"
+	SmsStateRejected    = "REJECTD"
+	SmsStateUnknown     = "UNKNOWN"
+)
+
+// SmsReceipt delivery receipt of one SMS segment
+type SmsReceipt struct {
+	MessageID string `json:"message_id"`
+	State     string `json:"state"`
+	// ErrorCode is network specific error of undelivered message
+	ErrorCode string `json:"error,omitempty"`
+}
+
+// ChannelStatus maps final state of the message to status, intermediate states like ENROUTE aren't final
+func (s *SmsReceipt) ChannelStatus() (string, bool) {
+	switch s.State {
+	case SmsStateDelivered:
+		return StatusDelivered, true
+	case SmsStateExpired, SmsStateDeleted, SmsStateUndelivered, SmsStateRejected, SmsStateUnknown:
+		return StatusFailed, true
+	default:
+		return "", false
+	}
+}
+
+// SmsParts final states of submitted parts of one SMS by their message IDs
+type SmsParts struct {
+	// Parts is number of parts of the whole message, some of them could be never submitted
+	Parts  int
+	States map[string]string
+}
+
+// ChannelStatus aggregates states of parts: SMS is delivered once every part is delivered,
+// it failed once any part failed or wasn't submitted at all. Pending part has empty state.
+func (s *SmsParts) ChannelStatus() (string, bool) {
+	delivered := 0
+	for _, state := range s.States {
+		receipt := SmsReceipt{State: state}
+		status, final := receipt.ChannelStatus()
+		if final && status == StatusFailed {
+			return StatusFailed, true
+		}
+		if final {
+			delivered++
+		}
+	}
+
+	switch {
+	case len(s.States) < s.Parts:
+		return StatusFailed, true
+	case delivered == len(s.States):
+		return StatusDelivered, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// ChannelsAuto resolves channels of notification request from user preferences
+const ChannelsAuto = "auto"
+
+// Statuses of notification in one channel
+const (
+	StatusRouted    = "routed"
+	StatusScheduled = "scheduled"
+	StatusDigested  = "digested"
+	StatusDelivered = "delivered"
+	// StatusSuppressed user opted out of the notification in the channel, it isn't a failure
+	StatusSuppressed = "suppressed"
+	StatusFailed     = "failed"
+)
+
+type ChannelStatus struct {
+	Status string `json:"status"`
+	// Reason of failure, e.g. expired or suppressed
+	Reason string `json:"reason,omitempty"`
+	// ScheduledID is ID of scheduled notification, it's passed to cancel the notification
+	ScheduledID string    `json:"scheduled_id,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NotificationStatus statuses of one logical notification across channels
+type NotificationStatus struct {
+	CorrelationID string                   `json:"correlation_id"`
+	Channels      map[string]ChannelStatus `json:"channels"`
+}
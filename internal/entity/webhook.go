@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"slices"
+	"time"
+)
+
+// WebhookSubscription URL, which receives notifications of the user as signed HTTP POSTs
+type WebhookSubscription struct {
+	ID        string `json:"id"`
+	UserEmail string `json:"user_email"`
+	URL       string `json:"url"`
+	// Secret signs requests, it is shown only in response to registration
+	Secret string `json:"secret,omitempty"`
+	// Categories limits notifications sent to the URL, empty means any category
+	Categories []string  `json:"categories,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Matches tells whether notification of the category has to be sent to the subscription
+func (w *WebhookSubscription) Matches(category string) bool {
+	return len(w.Categories) == 0 || slices.Contains(w.Categories, category)
+}
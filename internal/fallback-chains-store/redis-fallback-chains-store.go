@@ -0,0 +1,116 @@
+package fallback_chains_store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// claimScript returns states due for check and moves them forward by lease, so other instances skip them meanwhile
+var claimScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+local leaseEnd = tonumber(ARGV[1]) + tonumber(ARGV[3])
+local result = {}
+for _, id in ipairs(ids) do
+	local state = redis.call('HGET', KEYS[2], id)
+	if state then
+		redis.call('ZADD', KEYS[1], leaseEnd, id)
+		table.insert(result, state)
+	else
+		redis.call('ZREM', KEYS[1], id)
+	end
+end
+return result
+`)
+
+// createScript saves state only if the chain isn't saved yet
+var createScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[2], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+type RedisFallbackChainsStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisFallbackChainsStore(client redis.UniversalClient) *RedisFallbackChainsStore {
+	return &RedisFallbackChainsStore{client: client}
+}
+
+func (r *RedisFallbackChainsStore) Create(ctx context.Context, state entity.FallbackState, checkAt time.Time) (bool, error) {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return false, fmt.Errorf("can`t marshal fallback state: %w", err)
+	}
+
+	correlationID := state.Notification.CorrelationID
+	keys := []string{tools.REDIS_FALLBACK_CHAINS_QUEUE, tools.REDIS_FALLBACK_CHAINS_STATES}
+	created, err := createScript.Run(ctx, r.client, keys, correlationID, payload, checkAt.UnixMilli()).Bool()
+	if err != nil {
+		return false, fmt.Errorf("can`t create fallback state of %s: %w", correlationID, err)
+	}
+
+	return created, nil
+}
+
+func (r *RedisFallbackChainsStore) Save(ctx context.Context, state entity.FallbackState, checkAt time.Time) error {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("can`t marshal fallback state: %w", err)
+	}
+
+	correlationID := state.Notification.CorrelationID
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, tools.REDIS_FALLBACK_CHAINS_STATES, correlationID, payload)
+		pipe.ZAdd(ctx, tools.REDIS_FALLBACK_CHAINS_QUEUE, redis.Z{Score: float64(checkAt.UnixMilli()), Member: correlationID})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can`t save fallback state of %s: %w", correlationID, err)
+	}
+
+	return nil
+}
+
+func (r *RedisFallbackChainsStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.FallbackState, error) {
+	keys := []string{tools.REDIS_FALLBACK_CHAINS_QUEUE, tools.REDIS_FALLBACK_CHAINS_STATES}
+	payloads, err := claimScript.Run(ctx, r.client, keys, time.Now().UnixMilli(), limit, lease.Milliseconds()).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("can`t claim fallback states: %w", err)
+	}
+
+	states := make([]entity.FallbackState, 0, len(payloads))
+	for _, payload := range payloads {
+		var state entity.FallbackState
+		if err := json.Unmarshal([]byte(payload), &state); err != nil || state.Notification == nil {
+			slog.Error("Skip malformed fallback state", slog.String("state", payload))
+			continue
+		}
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+func (r *RedisFallbackChainsStore) Delete(ctx context.Context, correlationID string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, tools.REDIS_FALLBACK_CHAINS_QUEUE, correlationID)
+		pipe.HDel(ctx, tools.REDIS_FALLBACK_CHAINS_STATES, correlationID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can`t delete fallback state of %s: %w", correlationID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package health_checkers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+// ConsumerSessionsHealthChecker checks that observers of all notifications channels have active consumer group sessions
+type ConsumerSessionsHealthChecker struct {
+	channels []*service.NotificationsChannel
+}
+
+func NewConsumerSessionsHealthChecker(channels []*service.NotificationsChannel) *ConsumerSessionsHealthChecker {
+	return &ConsumerSessionsHealthChecker{channels: channels}
+}
+
+func (c *ConsumerSessionsHealthChecker) Name() string {
+	return "kafka_consumer_sessions"
+}
+
+func (c *ConsumerSessionsHealthChecker) Check(_ context.Context) error {
+	for _, channel := range c.channels {
+		if !channel.ListeningActive() {
+			return fmt.Errorf("consumer group session of channel %s is not active", channel.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package health_checkers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/IBM/sarama"
+)
+
+// KafkaHealthChecker checks brokers availability with metadata request
+type KafkaHealthChecker struct {
+	client sarama.Client
+}
+
+func NewKafkaHealthChecker(client sarama.Client) *KafkaHealthChecker {
+	return &KafkaHealthChecker{client: client}
+}
+
+func (k *KafkaHealthChecker) Name() string {
+	return "kafka"
+}
+
+func (k *KafkaHealthChecker) Check(ctx context.Context) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- k.client.RefreshMetadata()
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return fmt.Errorf("metadata request failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("metadata request timed out: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,30 @@
+package health_checkers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisHealthChecker checks Redis availability with PING
+type RedisHealthChecker struct {
+	client redis.UniversalClient
+}
+
+func NewRedisHealthChecker(client redis.UniversalClient) *RedisHealthChecker {
+	return &RedisHealthChecker{client: client}
+}
+
+func (r *RedisHealthChecker) Name() string {
+	return "redis"
+}
+
+func (r *RedisHealthChecker) Check(ctx context.Context) error {
+	err := r.client.Ping(ctx).Err()
+	if err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+
+	return nil
+}
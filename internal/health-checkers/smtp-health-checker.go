@@ -0,0 +1,39 @@
+package health_checkers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/smtp"
+)
+
+// SmtpHealthChecker checks SMTP connection with NOOP
+type SmtpHealthChecker struct {
+	client *smtp.Client
+}
+
+func NewSmtpHealthChecker(client *smtp.Client) *SmtpHealthChecker {
+	return &SmtpHealthChecker{client: client}
+}
+
+func (s *SmtpHealthChecker) Name() string {
+	return "smtp"
+}
+
+func (s *SmtpHealthChecker) Check(ctx context.Context) error {
+	result := make(chan error, 1)
+	// NOOP ждет свободного соединения пула, поэтому ожидание ограничено контекстом проверки
+	go func() {
+		result <- s.client.Noop()
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return fmt.Errorf("NOOP failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("NOOP timed out: %w", ctx.Err())
+	}
+}
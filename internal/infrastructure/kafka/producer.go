@@ -2,20 +2,24 @@ package kafka
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/IBM/sarama"
 )
 
 // Producer wraps Kafka producer functionality
 type Producer struct {
-	producer sarama.SyncProducer
+	producer  sarama.SyncProducer
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // NewProducer creates a new Kafka sync producer
 func NewProducer(brokers []string) (*Producer, error) {
 	producerConfig := sarama.NewConfig()
 	producerConfig.Producer.Return.Successes = true
-	producerConfig.Producer.Partitioner = sarama.NewRandomPartitioner
+	// Сообщения с ключом (email получателя) попадают в одну партицию, без ключа - в случайную
+	producerConfig.Producer.Partitioner = sarama.NewHashPartitioner
 
 	producer, err := sarama.NewSyncProducer(brokers, producerConfig)
 	if err != nil {
@@ -25,9 +29,12 @@ func NewProducer(brokers []string) (*Producer, error) {
 	return &Producer{producer: producer}, nil
 }
 
-// Close closes the producer
+// Close waits for unfinished sends and closes the producer, repeated calls are no-op
 func (p *Producer) Close() error {
-	return p.producer.Close()
+	p.closeOnce.Do(func() {
+		p.closeErr = p.producer.Close()
+	})
+	return p.closeErr
 }
 
 // GetProducer returns the underlying sarama.SyncProducer
@@ -1,24 +1,76 @@
 package redis
 
 import (
+	"crypto/tls"
+	"fmt"
+	"time"
+
 	"github.com/mwsbkru/evrone-go-final/config"
 
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
 // Client wraps Redis client functionality
 type Client struct {
-	client *redis.Client
+	client redis.UniversalClient
 }
 
-// NewClient creates a new Redis client based on the provided configuration
-func NewClient(cfg *config.Config) *Client {
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: cfg.Redis.Addr,
-		DB:   cfg.Redis.DB,
-	})
+// NewClient creates a new standalone, sentinel or cluster Redis client based on the provided configuration
+func NewClient(cfg *config.Config) (*Client, error) {
+	addrs := cfg.Redis.Addrs
+	if len(addrs) == 0 && cfg.Redis.Addr != "" {
+		addrs = []string{cfg.Redis.Addr}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no Redis addresses configured")
+	}
+
+	timeout := time.Duration(cfg.Redis.TimeoutSeconds) * time.Second
+	options := &redis.UniversalOptions{
+		Addrs:            addrs,
+		DB:               cfg.Redis.DB,
+		Username:         cfg.Redis.Username,
+		Password:         cfg.Redis.Password,
+		SentinelUsername: cfg.Redis.SentinelUsername,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		MasterName:       cfg.Redis.MasterName,
+		MaxRetries:       cfg.Redis.MaxRetries,
+		DialTimeout:      timeout,
+		ReadTimeout:      timeout,
+		WriteTimeout:     timeout,
+		PoolSize:         cfg.Redis.PoolSize,
+	}
 
-	return &Client{client: redisClient}
+	if cfg.Redis.TLSEnabled {
+		options.TLSConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			ServerName:         cfg.Redis.TLSServerName,
+			InsecureSkipVerify: cfg.Redis.TLSInsecureSkipVerify, //nolint:gosec // управляется конфигом, нужно для self-signed сертификатов
+		}
+	}
+
+	var redisClient redis.UniversalClient
+	switch cfg.Redis.Mode {
+	case ModeStandalone, "":
+		redisClient = redis.NewClient(options.Simple())
+	case ModeSentinel:
+		if cfg.Redis.MasterName == "" {
+			return nil, fmt.Errorf("REDIS_MASTER_NAME is required in sentinel mode")
+		}
+		redisClient = redis.NewFailoverClient(options.Failover())
+	case ModeCluster:
+		redisClient = redis.NewClusterClient(options.Cluster())
+	default:
+		return nil, fmt.Errorf("unknown Redis mode %q", cfg.Redis.Mode)
+	}
+
+	return &Client{client: redisClient}, nil
 }
 
 // Close closes the Redis client
@@ -26,8 +78,7 @@ func (c *Client) Close() error {
 	return c.client.Close()
 }
 
-// GetClient returns the underlying redis.Client
-func (c *Client) GetClient() *redis.Client {
+// GetClient returns the underlying redis.UniversalClient
+func (c *Client) GetClient() redis.UniversalClient {
 	return c.client
 }
-
@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		redis   config.RedisConfig
+		cluster bool
+		err     string
+	}{
+		{name: "standalone by default", redis: config.RedisConfig{Addr: "localhost:6379"}},
+		{name: "sentinel", redis: config.RedisConfig{Mode: ModeSentinel, Addrs: []string{"sentinel:26379"}, MasterName: "mymaster"}},
+		{name: "cluster", redis: config.RedisConfig{Mode: ModeCluster, Addrs: []string{"node-1:6379", "node-2:6379"}}, cluster: true},
+		{name: "sentinel without master", redis: config.RedisConfig{Mode: ModeSentinel, Addrs: []string{"sentinel:26379"}}, err: "REDIS_MASTER_NAME"},
+		{name: "unknown mode", redis: config.RedisConfig{Mode: "replicated", Addr: "localhost:6379"}, err: "unknown Redis mode"},
+		{name: "without addresses", redis: config.RedisConfig{}, err: "no Redis addresses"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(&config.Config{Redis: tt.redis})
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			defer client.Close()
+
+			_, isCluster := client.GetClient().(*redis.ClusterClient)
+			assert.Equal(t, tt.cluster, isCluster)
+		})
+	}
+}
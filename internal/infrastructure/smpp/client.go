@@ -0,0 +1,302 @@
+package smpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+)
+
+var (
+	ErrClientClosed = errors.New("SMPP client is closed")
+	errUnbound      = errors.New("SMPP server unbound the session")
+)
+
+// StatusError non-zero command_status of SMPP response
+type StatusError struct {
+	CommandID uint32
+	Status    uint32
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("SMPP command 0x%08x failed with status 0x%08x", e.CommandID, e.Status)
+}
+
+// ReceiptHandler is called for every delivery receipt, it must not block for long
+type ReceiptHandler func(receipt Receipt)
+
+// Client SMPP 3.4 session bound as transceiver. Broken session is re-established by the next request.
+type Client struct {
+	cfg       *config.Config
+	timeout   time.Duration
+	onReceipt ReceiptHandler
+	sequence  atomic.Uint32
+
+	mu      sync.Mutex
+	session *session
+	closed  bool
+}
+
+type session struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+
+	pendingMu sync.Mutex
+	pending   map[uint32]chan *PDU
+
+	failOnce sync.Once
+	done     chan struct{}
+	err      error
+}
+
+// NewClient connects to SMPP server and binds as transceiver
+func NewClient(cfg *config.Config, onReceipt ReceiptHandler) (*Client, error) {
+	client := &Client{
+		cfg:       cfg,
+		timeout:   time.Duration(cfg.Sms.SmppTimeoutSeconds) * time.Second,
+		onReceipt: onReceipt,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
+	defer cancel()
+	if _, err := client.currentSession(ctx); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Submit sends submit_sm and returns message ID assigned by SMPP server
+func (c *Client) Submit(ctx context.Context, submit *SubmitSm) (string, error) {
+	s, err := c.currentSession(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := c.request(ctx, s, CommandSubmitSm, submit.marshal())
+	if err != nil {
+		return "", err
+	}
+
+	messageID, err := (&bodyReader{data: response.Body}).cString()
+	if err != nil {
+		return "", fmt.Errorf("can`t read submit_sm_resp: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// Close unbinds the session and closes connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	s := c.session
+	c.session = nil
+	c.mu.Unlock()
+
+	if s == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	if _, err := c.request(ctx, s, CommandUnbind, nil); err != nil {
+		slog.Warn("Can`t unbind SMPP session", slog.String("error", err.Error()))
+	}
+	s.fail(ErrClientClosed)
+
+	return nil
+}
+
+func (c *Client) currentSession(ctx context.Context) (*session, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+	if c.session != nil {
+		select {
+		case <-c.session.done:
+			slog.Warn("SMPP session is broken, reconnecting", slog.String("error", c.session.err.Error()))
+		default:
+			return c.session, nil
+		}
+	}
+
+	s, err := c.bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.session = s
+
+	return s, nil
+}
+
+func (c *Client) bind(ctx context.Context) (*session, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.cfg.Sms.SmppAddr)
+	if err != nil {
+		return nil, fmt.Errorf("can`t connect to SMPP server %s: %w", c.cfg.Sms.SmppAddr, err)
+	}
+
+	s := &session{conn: conn, pending: make(map[uint32]chan *PDU), done: make(chan struct{})}
+	go c.readLoop(s)
+
+	_, err = c.request(ctx, s, CommandBindTransceiver, bindTransceiverBody(c.cfg.Sms.SmppSystemID, c.cfg.Sms.SmppPassword, c.cfg.Sms.SmppSystemType))
+	if err != nil {
+		s.fail(err)
+		return nil, fmt.Errorf("can`t bind to SMPP server: %w", err)
+	}
+
+	if c.cfg.Sms.SmppEnquireLinkSeconds > 0 {
+		go c.enquireLinkLoop(s, time.Duration(c.cfg.Sms.SmppEnquireLinkSeconds)*time.Second)
+	}
+	slog.Info("SMPP session is bound", slog.String("addr", c.cfg.Sms.SmppAddr))
+
+	return s, nil
+}
+
+// request writes PDU and waits for response with the same sequence number
+func (c *Client) request(ctx context.Context, s *session, commandID uint32, body []byte) (*PDU, error) {
+	sequence := c.nextSequence()
+	responses := make(chan *PDU, 1)
+	s.pendingMu.Lock()
+	s.pending[sequence] = responses
+	s.pendingMu.Unlock()
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, sequence)
+		s.pendingMu.Unlock()
+	}()
+
+	if err := s.write(&PDU{CommandID: commandID, Sequence: sequence, Body: body}, c.timeout); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case response := <-responses:
+		if response.Status != 0 {
+			return nil, &StatusError{CommandID: commandID, Status: response.Status}
+		}
+		return response, nil
+	case <-s.done:
+		return nil, fmt.Errorf("SMPP session closed: %w", s.err)
+	case <-timer.C:
+		return nil, fmt.Errorf("SMPP command 0x%08x timed out", commandID)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) readLoop(s *session) {
+	for {
+		pdu, err := readPDU(s.conn)
+		if err != nil {
+			s.fail(fmt.Errorf("can`t read PDU: %w", err))
+			return
+		}
+
+		switch {
+		case pdu.CommandID&CommandGenericNack != 0:
+			s.pendingMu.Lock()
+			responses, ok := s.pending[pdu.Sequence]
+			s.pendingMu.Unlock()
+			if ok {
+				responses <- pdu
+			}
+		case pdu.CommandID == CommandDeliverSm:
+			// Ответ отправляется сразу, иначе сервер повторит доставку квитанции
+			c.reply(s, pdu, CommandDeliverSmResp, []byte{0})
+			c.handleDeliverSm(pdu)
+		case pdu.CommandID == CommandEnquireLink:
+			c.reply(s, pdu, CommandEnquireLinkResp, nil)
+		case pdu.CommandID == CommandUnbind:
+			c.reply(s, pdu, CommandUnbindResp, nil)
+			s.fail(errUnbound)
+			return
+		default:
+			slog.Warn("Unexpected SMPP command", slog.String("command_id", fmt.Sprintf("0x%08x", pdu.CommandID)))
+			c.reply(s, pdu, CommandGenericNack, nil)
+		}
+	}
+}
+
+func (c *Client) handleDeliverSm(pdu *PDU) {
+	receipt, ok, err := parseReceipt(pdu.Body)
+	if err != nil {
+		slog.Warn("Can`t parse deliver_sm", slog.String("error", err.Error()))
+		return
+	}
+	if !ok {
+		slog.Info("Skip deliver_sm, which isn't a delivery receipt")
+		return
+	}
+
+	if c.onReceipt != nil {
+		go c.onReceipt(receipt)
+	}
+}
+
+func (c *Client) reply(s *session, request *PDU, commandID uint32, body []byte) {
+	err := s.write(&PDU{CommandID: commandID, Sequence: request.Sequence, Body: body}, c.timeout)
+	if err != nil {
+		slog.Warn("Can`t reply to SMPP server", slog.String("error", err.Error()))
+	}
+}
+
+func (c *Client) enquireLinkLoop(s *session, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+			_, err := c.request(ctx, s, CommandEnquireLink, nil)
+			cancel()
+			if err != nil {
+				s.fail(fmt.Errorf("enquire_link failed: %w", err))
+				return
+			}
+		}
+	}
+}
+
+// nextSequence returns sequence number in range 1..0x7FFFFFFF
+func (c *Client) nextSequence() uint32 {
+	return c.sequence.Add(1)%0x7FFFFFFF + 1
+}
+
+func (s *session) write(pdu *PDU, timeout time.Duration) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err := s.conn.Write(pdu.marshal())
+	if err != nil {
+		s.fail(fmt.Errorf("can`t write PDU: %w", err))
+		return fmt.Errorf("can`t write PDU: %w", err)
+	}
+
+	return nil
+}
+
+func (s *session) fail(err error) {
+	s.failOnce.Do(func() {
+		s.err = err
+		close(s.done)
+		s.conn.Close()
+	})
+}
@@ -0,0 +1,137 @@
+package smpp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/smpp/smpptest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, enquireLinkSeconds int, onReceipt ReceiptHandler) (*Client, *smpptest.Server) {
+	server, err := smpptest.NewServer()
+	require.NoError(t, err)
+	server.Password = "secret"
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	cfg.Sms.SmppAddr = server.Addr()
+	cfg.Sms.SmppSystemID = "notificator"
+	cfg.Sms.SmppPassword = "secret"
+	cfg.Sms.SmppSystemType = "test"
+	cfg.Sms.SmppTimeoutSeconds = 2
+	cfg.Sms.SmppEnquireLinkSeconds = enquireLinkSeconds
+
+	client, err := NewClient(cfg, onReceipt)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client, server
+}
+
+func TestClientBindsTransceiver(t *testing.T) {
+	_, server := newTestClient(t, 0, nil)
+
+	binds := server.Binds()
+	require.Len(t, binds, 1)
+	assert.Equal(t, smpptest.Bind{SystemID: "notificator", Password: "secret", SystemType: "test", Version: 0x34}, binds[0])
+}
+
+func TestClientFailsWithWrongPassword(t *testing.T) {
+	server, err := smpptest.NewServer()
+	require.NoError(t, err)
+	server.Password = "secret"
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cfg.Sms.SmppAddr = server.Addr()
+	cfg.Sms.SmppPassword = "wrong"
+	cfg.Sms.SmppTimeoutSeconds = 2
+
+	_, err = NewClient(cfg, nil)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, CommandBindTransceiver, statusErr.CommandID)
+}
+
+func TestClientSubmitsMessage(t *testing.T) {
+	client, server := newTestClient(t, 0, nil)
+
+	messageID, err := client.Submit(context.Background(), &SubmitSm{
+		SourceAddr:         "Notificator",
+		SourceTon:          TonAlphanumeric,
+		DestAddr:           "79001234567",
+		RegisteredDelivery: RegisteredDeliveryFinal,
+		ShortMessage:       []byte("hello"),
+	})
+	require.NoError(t, err)
+
+	submits := server.Submits()
+	require.Len(t, submits, 1)
+	assert.Equal(t, submits[0].MessageID, messageID)
+	assert.Equal(t, "Notificator", submits[0].SourceAddr)
+	assert.Equal(t, "79001234567", submits[0].DestAddr)
+	assert.Equal(t, RegisteredDeliveryFinal, submits[0].RegisteredDelivery)
+	assert.Equal(t, []byte("hello"), submits[0].ShortMessage)
+}
+
+func TestClientReturnsStatusOfRejectedSubmit(t *testing.T) {
+	client, server := newTestClient(t, 0, nil)
+	server.SubmitStatus = func(int) uint32 { return 0x0000000B }
+
+	_, err := client.Submit(context.Background(), &SubmitSm{DestAddr: "1", ShortMessage: []byte("hello")})
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, uint32(0x0000000B), statusErr.Status)
+}
+
+func TestClientHandlesDeliveryReceipt(t *testing.T) {
+	receipts := make(chan Receipt, 1)
+	_, server := newTestClient(t, 0, func(receipt Receipt) { receipts <- receipt })
+
+	status, err := server.SendReceipt("msg-7", "UNDELIV", "012")
+	require.NoError(t, err)
+	assert.Zero(t, status)
+
+	select {
+	case receipt := <-receipts:
+		assert.Equal(t, Receipt{MessageID: "msg-7", State: "UNDELIV", Error: "012"}, receipt)
+	case <-time.After(2 * time.Second):
+		t.Fatal("receipt isn't handled")
+	}
+}
+
+func TestClientAnswersEnquireLink(t *testing.T) {
+	_, server := newTestClient(t, 0, nil)
+
+	status, err := server.EnquireLink()
+	require.NoError(t, err)
+	assert.Zero(t, status)
+}
+
+func TestClientSendsEnquireLink(t *testing.T) {
+	_, server := newTestClient(t, 1, nil)
+
+	assert.True(t, server.WaitFor(3*time.Second, func() bool { return server.EnquireLinks() > 0 }))
+}
+
+func TestClientRebindsAfterUnbind(t *testing.T) {
+	client, server := newTestClient(t, 0, nil)
+
+	status, err := server.Unbind()
+	require.NoError(t, err)
+	assert.Zero(t, status)
+
+	// Сессия закрывается после ответа на unbind, ждем, пока клиент это заметит
+	require.Eventually(t, func() bool {
+		_, err := client.Submit(context.Background(), &SubmitSm{DestAddr: "1", ShortMessage: []byte("hello")})
+		return err == nil
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.Len(t, server.Binds(), 2)
+}
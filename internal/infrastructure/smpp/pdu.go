@@ -0,0 +1,302 @@
+package smpp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Command IDs of SMPP 3.4 used by the client, responses have the highest bit set
+const (
+	CommandGenericNack         uint32 = 0x80000000
+	CommandBindTransceiver     uint32 = 0x00000009
+	CommandBindTransceiverResp uint32 = 0x80000009
+	CommandSubmitSm            uint32 = 0x00000004
+	CommandSubmitSmResp        uint32 = 0x80000004
+	CommandDeliverSm           uint32 = 0x00000005
+	CommandDeliverSmResp       uint32 = 0x80000005
+	CommandUnbind              uint32 = 0x00000006
+	CommandUnbindResp          uint32 = 0x80000006
+	CommandEnquireLink         uint32 = 0x00000015
+	CommandEnquireLinkResp     uint32 = 0x80000015
+)
+
+const (
+	interfaceVersion = 0x34
+	headerLength     = 16
+	maxPduLength     = 64 << 10
+
+	// EsmClassUdh short message starts with user data header of concatenated SMS
+	EsmClassUdh byte = 0x40
+	// esmClassReceipt deliver_sm carries delivery receipt
+	esmClassReceipt byte = 0x04
+	// RegisteredDeliveryFinal requests receipt on final state of the message
+	RegisteredDeliveryFinal byte = 0x01
+
+	tagReceiptedMessageID uint16 = 0x001E
+	tagMessageState       uint16 = 0x0427
+)
+
+// Type of number and numbering plan indicator of addresses
+const (
+	TonInternational byte = 0x01
+	TonAlphanumeric  byte = 0x05
+	NpiIsdn          byte = 0x01
+	NpiUnknown       byte = 0x00
+)
+
+// messageStates maps message_state TLV to state names used in receipt text
+var messageStates = map[byte]string{
+	1: "ENROUTE", 2: "DELIVRD", 3: "EXPIRED", 4: "DELETED", 5: "UNDELIV", 6: "ACCEPTD", 7: "UNKNOWN", 8: "REJECTD",
+}
+
+type PDU struct {
+	CommandID uint32
+	Status    uint32
+	Sequence  uint32
+	Body      []byte
+}
+
+func (p *PDU) marshal() []byte {
+	packet := make([]byte, headerLength, headerLength+len(p.Body))
+	binary.BigEndian.PutUint32(packet[0:], uint32(headerLength+len(p.Body)))
+	binary.BigEndian.PutUint32(packet[4:], p.CommandID)
+	binary.BigEndian.PutUint32(packet[8:], p.Status)
+	binary.BigEndian.PutUint32(packet[12:], p.Sequence)
+	return append(packet, p.Body...)
+}
+
+func readPDU(reader io.Reader) (*PDU, error) {
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:])
+	if length < headerLength || length > maxPduLength {
+		return nil, fmt.Errorf("invalid PDU length %d", length)
+	}
+
+	pdu := &PDU{
+		CommandID: binary.BigEndian.Uint32(header[4:]),
+		Status:    binary.BigEndian.Uint32(header[8:]),
+		Sequence:  binary.BigEndian.Uint32(header[12:]),
+		Body:      make([]byte, length-headerLength),
+	}
+	if _, err := io.ReadFull(reader, pdu.Body); err != nil {
+		return nil, err
+	}
+
+	return pdu, nil
+}
+
+type bodyWriter struct {
+	bytes.Buffer
+}
+
+func (w *bodyWriter) cString(value string) {
+	w.WriteString(value)
+	w.WriteByte(0)
+}
+
+type bodyReader struct {
+	data []byte
+	pos  int
+}
+
+var errShortBody = errors.New("PDU body is too short")
+
+func (r *bodyReader) cString() (string, error) {
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		return "", errShortBody
+	}
+
+	value := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return value, nil
+}
+
+func (r *bodyReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errShortBody
+	}
+
+	value := r.data[r.pos]
+	r.pos++
+	return value, nil
+}
+
+func (r *bodyReader) octets(length int) ([]byte, error) {
+	if r.pos+length > len(r.data) {
+		return nil, errShortBody
+	}
+
+	value := r.data[r.pos : r.pos+length]
+	r.pos += length
+	return value, nil
+}
+
+// skip reads fields of the layout: c is C-Octet String, b is one octet
+func (r *bodyReader) skip(layout string) error {
+	for _, field := range layout {
+		var err error
+		if field == 'c' {
+			_, err = r.cString()
+		} else {
+			_, err = r.byte()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tlvs reads optional parameters following mandatory ones
+func (r *bodyReader) tlvs() (map[uint16][]byte, error) {
+	params := make(map[uint16][]byte)
+	for r.pos < len(r.data) {
+		header, err := r.octets(4)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := r.octets(int(binary.BigEndian.Uint16(header[2:])))
+		if err != nil {
+			return nil, err
+		}
+		params[binary.BigEndian.Uint16(header[0:])] = value
+	}
+
+	return params, nil
+}
+
+func bindTransceiverBody(systemID string, password string, systemType string) []byte {
+	var body bodyWriter
+	body.cString(systemID)
+	body.cString(password)
+	body.cString(systemType)
+	body.WriteByte(interfaceVersion)
+	body.WriteByte(0) // addr_ton
+	body.WriteByte(0) // addr_npi
+	body.cString("")  // address_range
+	return body.Bytes()
+}
+
+// SubmitSm mandatory parameters of submit_sm used by the client
+type SubmitSm struct {
+	SourceAddr         string
+	SourceTon          byte
+	SourceNpi          byte
+	DestAddr           string
+	EsmClass           byte
+	RegisteredDelivery byte
+	DataCoding         byte
+	ShortMessage       []byte
+}
+
+func (s *SubmitSm) marshal() []byte {
+	var body bodyWriter
+	body.cString("") // service_type
+	body.WriteByte(s.SourceTon)
+	body.WriteByte(s.SourceNpi)
+	body.cString(s.SourceAddr)
+	body.WriteByte(TonInternational)
+	body.WriteByte(NpiIsdn)
+	body.cString(s.DestAddr)
+	body.WriteByte(s.EsmClass)
+	body.WriteByte(0) // protocol_id
+	body.WriteByte(0) // priority_flag
+	body.cString("")  // schedule_delivery_time
+	body.cString("")  // validity_period
+	body.WriteByte(s.RegisteredDelivery)
+	body.WriteByte(0) // replace_if_present_flag
+	body.WriteByte(s.DataCoding)
+	body.WriteByte(0) // sm_default_msg_id
+	body.WriteByte(byte(len(s.ShortMessage)))
+	body.Write(s.ShortMessage)
+	return body.Bytes()
+}
+
+// Receipt delivery receipt received in deliver_sm
+type Receipt struct {
+	MessageID string
+	State     string
+	Error     string
+}
+
+// parseReceipt reads deliver_sm, it returns false when deliver_sm isn't a delivery receipt.
+// TLVs receipted_message_id and message_state win over fields of receipt text.
+func parseReceipt(body []byte) (Receipt, bool, error) {
+	reader := &bodyReader{data: body}
+	// service_type, source_addr_ton, source_addr_npi, source_addr, dest_addr_ton, dest_addr_npi, destination_addr
+	if err := reader.skip("cbbcbbc"); err != nil {
+		return Receipt{}, false, err
+	}
+	esmClass, err := reader.byte()
+	if err != nil {
+		return Receipt{}, false, err
+	}
+	// protocol_id, priority_flag, schedule_delivery_time, validity_period,
+	// registered_delivery, replace_if_present_flag, data_coding, sm_default_msg_id
+	if err := reader.skip("bbccbbbb"); err != nil {
+		return Receipt{}, false, err
+	}
+
+	length, err := reader.byte()
+	if err != nil {
+		return Receipt{}, false, err
+	}
+	shortMessage, err := reader.octets(int(length))
+	if err != nil {
+		return Receipt{}, false, err
+	}
+	params, err := reader.tlvs()
+	if err != nil {
+		return Receipt{}, false, err
+	}
+
+	if esmClass&esmClassReceipt == 0 {
+		return Receipt{}, false, nil
+	}
+
+	receipt := parseReceiptText(string(shortMessage))
+	if messageID, ok := params[tagReceiptedMessageID]; ok {
+		receipt.MessageID = strings.TrimRight(string(messageID), "\x00")
+	}
+	if state, ok := params[tagMessageState]; ok && len(state) == 1 {
+		if name, ok := messageStates[state[0]]; ok {
+			receipt.State = name
+		}
+	}
+
+	return receipt, receipt.MessageID != "", nil
+}
+
+// parseReceiptText parses receipt of SMPP 3.4 appendix B:
+// id:IIIIIIIIII sub:SSS dlvrd:DDD submit date:YYMMDDhhmm done date:YYMMDDhhmm stat:DDDDDDD err:E text:...
+func parseReceiptText(text string) Receipt {
+	var receipt Receipt
+	for _, field := range strings.Fields(text) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "id":
+			receipt.MessageID = value
+		case "stat":
+			receipt.State = strings.ToUpper(value)
+		case "err":
+			receipt.Error = value
+		}
+	}
+
+	return receipt
+}
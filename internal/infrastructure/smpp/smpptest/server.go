@@ -0,0 +1,414 @@
+// Package smpptest provides in-process SMPP 3.4 server for tests of SMPP clients.
+// It encodes PDUs on its own, so tests check the client against the protocol rather than against its own encoder.
+package smpptest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Command IDs of SMPP 3.4 served by the stub
+const (
+	commandGenericNack         uint32 = 0x80000000
+	commandBindTransceiver     uint32 = 0x00000009
+	commandBindTransceiverResp uint32 = 0x80000009
+	commandSubmitSm            uint32 = 0x00000004
+	commandSubmitSmResp        uint32 = 0x80000004
+	commandDeliverSm           uint32 = 0x00000005
+	commandDeliverSmResp       uint32 = 0x80000005
+	commandUnbind              uint32 = 0x00000006
+	commandUnbindResp          uint32 = 0x80000006
+	commandEnquireLink         uint32 = 0x00000015
+	commandEnquireLinkResp     uint32 = 0x80000015
+
+	statusInvalidPassword   uint32 = 0x0000000E
+	statusInvalidBindStatus uint32 = 0x00000004
+)
+
+// Bind bind_transceiver received by the server
+type Bind struct {
+	SystemID   string
+	Password   string
+	SystemType string
+	Version    byte
+}
+
+// Submit submit_sm received by the server
+type Submit struct {
+	MessageID          string
+	SourceAddr         string
+	DestAddr           string
+	EsmClass           byte
+	RegisteredDelivery byte
+	DataCoding         byte
+	ShortMessage       []byte
+}
+
+// Server accepts SMPP sessions on 127.0.0.1, every accepted submit_sm gets message ID msg-N
+type Server struct {
+	// Password is required in bind_transceiver, when it isn't empty
+	Password string
+	// SubmitStatus returns command_status of submit_sm_resp for N-th submit_sm counting from 1, nil accepts all
+	SubmitStatus func(n int) uint32
+
+	listener net.Listener
+
+	mu           sync.Mutex
+	conns        []*serverConn
+	binds        []Bind
+	submits      []Submit
+	submitCount  int
+	enquireLinks int
+	responses    map[uint32]chan uint32
+	sequence     uint32
+	changed      chan struct{}
+}
+
+type serverConn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	bound   bool
+}
+
+// NewServer starts the server, it's stopped by Close
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{listener: listener, responses: make(map[uint32]chan uint32), changed: make(chan struct{})}
+	go server.accept()
+	return server, nil
+}
+
+// Addr returns host:port of the server
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.conn.Close()
+	}
+}
+
+// Binds returns bind_transceiver requests received so far
+func (s *Server) Binds() []Bind {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Bind(nil), s.binds...)
+}
+
+// Submits returns accepted submit_sm requests
+func (s *Server) Submits() []Submit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Submit(nil), s.submits...)
+}
+
+// EnquireLinks returns number of enquire_link requests sent by clients
+func (s *Server) EnquireLinks() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enquireLinks
+}
+
+// WaitFor waits until condition on the state of the server holds
+func (s *Server) WaitFor(timeout time.Duration, condition func() bool) bool {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+		if condition() {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return condition()
+		}
+	}
+}
+
+// SendReceipt sends delivery receipt of the message as deliver_sm to the latest bound session
+// and returns command_status of deliver_sm_resp
+func (s *Server) SendReceipt(messageID string, state string, errorCode string) (uint32, error) {
+	var text bodyWriter
+	text.WriteString(fmt.Sprintf("id:%s sub:001 dlvrd:001 submit date:2401011200 done date:2401011201 stat:%s err:%s text:", messageID, state, errorCode))
+
+	var body bodyWriter
+	body.cString("") // service_type
+	body.WriteByte(1)
+	body.WriteByte(1)
+	body.cString("79001234567")
+	body.WriteByte(5)
+	body.WriteByte(0)
+	body.cString("Notificator")
+	body.WriteByte(0x04) // esm_class: delivery receipt
+	body.WriteByte(0)    // protocol_id
+	body.WriteByte(0)    // priority_flag
+	body.cString("")     // schedule_delivery_time
+	body.cString("")     // validity_period
+	body.WriteByte(0)    // registered_delivery
+	body.WriteByte(0)    // replace_if_present_flag
+	body.WriteByte(0)    // data_coding
+	body.WriteByte(0)    // sm_default_msg_id
+	body.WriteByte(byte(text.Len()))
+	body.Write(text.Bytes())
+
+	return s.request(commandDeliverSm, body.Bytes())
+}
+
+// EnquireLink sends enquire_link to the latest bound session and returns command_status of the response
+func (s *Server) EnquireLink() (uint32, error) {
+	return s.request(commandEnquireLink, nil)
+}
+
+// Unbind sends unbind to the latest bound session, client must reconnect on the next request
+func (s *Server) Unbind() (uint32, error) {
+	return s.request(commandUnbind, nil)
+}
+
+func (s *Server) request(commandID uint32, body []byte) (uint32, error) {
+	s.mu.Lock()
+	var conn *serverConn
+	for i := len(s.conns) - 1; i >= 0; i-- {
+		if s.conns[i].bound {
+			conn = s.conns[i]
+			break
+		}
+	}
+	s.sequence++
+	sequence := s.sequence
+	responses := make(chan uint32, 1)
+	s.responses[sequence] = responses
+	s.mu.Unlock()
+
+	if conn == nil {
+		return 0, fmt.Errorf("no bound SMPP session")
+	}
+	if err := conn.write(commandID, 0, sequence, body); err != nil {
+		return 0, err
+	}
+
+	select {
+	case status := <-responses:
+		return status, nil
+	case <-time.After(5 * time.Second):
+		return 0, fmt.Errorf("SMPP client didn't respond to command 0x%08x", commandID)
+	}
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		serverConn := &serverConn{conn: conn}
+		s.mu.Lock()
+		s.conns = append(s.conns, serverConn)
+		s.mu.Unlock()
+		go s.serve(serverConn)
+	}
+}
+
+func (s *Server) serve(conn *serverConn) {
+	defer conn.conn.Close()
+
+	for {
+		commandID, status, sequence, body, err := readPDU(conn.conn)
+		if err != nil {
+			return
+		}
+
+		if commandID&commandGenericNack != 0 {
+			s.mu.Lock()
+			responses, ok := s.responses[sequence]
+			delete(s.responses, sequence)
+			s.mu.Unlock()
+			if ok {
+				responses <- status
+			}
+			continue
+		}
+
+		switch commandID {
+		case commandBindTransceiver:
+			s.handleBind(conn, sequence, body)
+		case commandSubmitSm:
+			s.handleSubmit(conn, sequence, body)
+		case commandEnquireLink:
+			s.update(func() { s.enquireLinks++ })
+			conn.write(commandEnquireLinkResp, 0, sequence, nil)
+		case commandUnbind:
+			conn.write(commandUnbindResp, 0, sequence, nil)
+			return
+		default:
+			conn.write(commandGenericNack, 0x00000003, sequence, nil)
+		}
+	}
+}
+
+func (s *Server) handleBind(conn *serverConn, sequence uint32, body []byte) {
+	reader := &bodyReader{data: body}
+	bind := Bind{SystemID: reader.cString(), Password: reader.cString(), SystemType: reader.cString(), Version: reader.byte()}
+	s.update(func() { s.binds = append(s.binds, bind) })
+
+	status := uint32(0)
+	if s.Password != "" && bind.Password != s.Password {
+		status = statusInvalidPassword
+	}
+
+	var response bodyWriter
+	response.cString("smpptest")
+	conn.write(commandBindTransceiverResp, status, sequence, response.Bytes())
+
+	if status == 0 {
+		s.update(func() { conn.bound = true })
+	}
+}
+
+func (s *Server) handleSubmit(conn *serverConn, sequence uint32, body []byte) {
+	s.mu.Lock()
+	bound := conn.bound
+	s.submitCount++
+	n := s.submitCount
+	s.mu.Unlock()
+
+	status := uint32(0)
+	if !bound {
+		status = statusInvalidBindStatus
+	} else if s.SubmitStatus != nil {
+		status = s.SubmitStatus(n)
+	}
+	if status != 0 {
+		conn.write(commandSubmitSmResp, status, sequence, nil)
+		s.update(func() {})
+		return
+	}
+
+	reader := &bodyReader{data: body}
+	reader.cString() // service_type
+	reader.byte()
+	reader.byte()
+	submit := Submit{MessageID: fmt.Sprintf("msg-%d", n), SourceAddr: reader.cString()}
+	reader.byte()
+	reader.byte()
+	submit.DestAddr = reader.cString()
+	submit.EsmClass = reader.byte()
+	reader.byte()    // protocol_id
+	reader.byte()    // priority_flag
+	reader.cString() // schedule_delivery_time
+	reader.cString() // validity_period
+	submit.RegisteredDelivery = reader.byte()
+	reader.byte() // replace_if_present_flag
+	submit.DataCoding = reader.byte()
+	reader.byte() // sm_default_msg_id
+	submit.ShortMessage = reader.octets(int(reader.byte()))
+
+	s.update(func() { s.submits = append(s.submits, submit) })
+
+	var response bodyWriter
+	response.cString(submit.MessageID)
+	conn.write(commandSubmitSmResp, 0, sequence, response.Bytes())
+}
+
+// update changes state of the server and wakes up WaitFor
+func (s *Server) update(change func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change()
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (c *serverConn) write(commandID uint32, status uint32, sequence uint32, body []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	packet := make([]byte, 16, 16+len(body))
+	binary.BigEndian.PutUint32(packet[0:], uint32(16+len(body)))
+	binary.BigEndian.PutUint32(packet[4:], commandID)
+	binary.BigEndian.PutUint32(packet[8:], status)
+	binary.BigEndian.PutUint32(packet[12:], sequence)
+	_, err := c.conn.Write(append(packet, body...))
+	return err
+}
+
+// readPDU returns command_id, command_status, sequence_number and body of the next PDU
+func readPDU(reader io.Reader) (uint32, uint32, uint32, []byte, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, 0, 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:])
+	if length < 16 {
+		return 0, 0, 0, nil, fmt.Errorf("invalid PDU length %d", length)
+	}
+	body := make([]byte, length-16)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, 0, 0, nil, err
+	}
+
+	return binary.BigEndian.Uint32(header[4:]), binary.BigEndian.Uint32(header[8:]), binary.BigEndian.Uint32(header[12:]), body, nil
+}
+
+type bodyWriter struct {
+	bytes.Buffer
+}
+
+func (w *bodyWriter) cString(value string) {
+	w.WriteString(value)
+	w.WriteByte(0)
+}
+
+// bodyReader reads fields of valid PDU, missing fields are read as zero values
+type bodyReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bodyReader) cString() string {
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		r.pos = len(r.data)
+		return ""
+	}
+
+	value := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return value
+}
+
+func (r *bodyReader) byte() byte {
+	if r.pos >= len(r.data) {
+		return 0
+	}
+
+	value := r.data[r.pos]
+	r.pos++
+	return value
+}
+
+func (r *bodyReader) octets(length int) []byte {
+	end := min(r.pos+length, len(r.data))
+	value := append([]byte(nil), r.data[r.pos:end]...)
+	r.pos = end
+	return value
+}
@@ -2,6 +2,7 @@ package smtp
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/mwsbkru/evrone-go-final/config"
@@ -9,9 +10,15 @@ import (
 	mail "github.com/xhit/go-simple-mail/v2"
 )
 
-// Client wraps SMTP client functionality
+// Client wraps SMTP client functionality. It keeps a small pool of connections: each connection serves
+// one send or NOOP at a time, and connection which returned error is closed, so the next use dials a new one.
 type Client struct {
-	client *mail.SMTPClient
+	server *mail.SMTPServer
+	// slots limits amount of open connections, idle keeps connections between uses
+	slots  chan struct{}
+	idle   chan *mail.SMTPClient
+	mu     sync.Mutex
+	closed bool
 }
 
 // NewClient creates and configures an SMTP client based on the provided configuration
@@ -28,20 +35,97 @@ func NewClient(cfg *config.Config) (*Client, error) {
 	server.ConnectTimeout = time.Duration(cfg.Email.SmtpTimeoutSeconds) * time.Second
 	server.SendTimeout = time.Duration(cfg.Email.SmtpTimeoutSeconds) * time.Second
 
+	poolSize := max(cfg.Email.SmtpPoolSize, 1)
+	client := &Client{
+		server: server,
+		slots:  make(chan struct{}, poolSize),
+		idle:   make(chan *mail.SMTPClient, poolSize),
+	}
+
+	// Первое соединение открываем сразу, чтобы недоступный сервер останавливал запуск
 	smtpClient, err := server.Connect()
 	if err != nil {
 		return nil, fmt.Errorf("can't create SMTP client: %w", err)
 	}
+	client.idle <- smtpClient
+
+	return client, nil
+}
+
+// Send sends email through one of the pooled connections
+func (c *Client) Send(email *mail.Email) error {
+	smtpClient, err := c.acquire()
+	if err != nil {
+		return err
+	}
 
-	return &Client{client: smtpClient}, nil
+	err = email.Send(smtpClient)
+	c.release(smtpClient, err)
+	return err
 }
 
-// Close closes the SMTP client
+// Noop checks one of the pooled connections, dialing it when there is no idle one
+func (c *Client) Noop() error {
+	smtpClient, err := c.acquire()
+	if err != nil {
+		return err
+	}
+
+	err = smtpClient.Noop()
+	c.release(smtpClient, err)
+	return err
+}
+
+// Close closes idle connections, connections in use are closed when they are released
 func (c *Client) Close() error {
-	return c.client.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	var err error
+	for {
+		select {
+		case smtpClient := <-c.idle:
+			if closeErr := smtpClient.Close(); closeErr != nil {
+				err = closeErr
+			}
+		default:
+			return err
+		}
+	}
 }
 
-// GetClient returns the underlying mail.SMTPClient
-func (c *Client) GetClient() *mail.SMTPClient {
-	return c.client
+// acquire takes idle connection or dials a new one, when pool has room for it
+func (c *Client) acquire() (*mail.SMTPClient, error) {
+	c.slots <- struct{}{}
+
+	select {
+	case smtpClient := <-c.idle:
+		return smtpClient, nil
+	default:
+	}
+
+	smtpClient, err := c.server.Connect()
+	if err != nil {
+		<-c.slots
+		return nil, fmt.Errorf("can`t connect to SMTP server: %w", err)
+	}
+
+	return smtpClient, nil
+}
+
+// release returns connection to the pool. After error state of the connection is unknown, so it's closed.
+func (c *Client) release(smtpClient *mail.SMTPClient, err error) {
+	defer func() { <-c.slots }()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil || c.closed {
+		smtpClient.Close()
+		return
+	}
+
+	// Открытых соединений не больше размера пула, поэтому очередь свободных не переполняется
+	c.idle <- smtpClient
 }
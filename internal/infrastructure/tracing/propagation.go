@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/mwsbkru/evrone-go-final"
+
+// Tracer returns tracer used by all components of the application
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// ContextFromNotification returns ctx with remote span context carried by notification
+func ContextFromNotification(ctx context.Context, notification *entity.Notification) context.Context {
+	if len(notification.TraceContext) == 0 {
+		return ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(notification.TraceContext))
+}
+
+// InjectToNotification stores span context of ctx in notification, so it survives retries and Redis
+func InjectToNotification(ctx context.Context, notification *entity.Notification) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		notification.TraceContext = carrier
+	}
+}
+
+// ContextFromKafkaHeaders returns ctx with remote span context carried by Kafka record headers
+func ContextFromKafkaHeaders(ctx context.Context, headers []*sarama.RecordHeader) context.Context {
+	carrier := propagation.MapCarrier{}
+	for _, header := range headers {
+		if header != nil {
+			carrier[string(header.Key)] = string(header.Value)
+		}
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// KafkaHeadersFromNotification converts trace context of notification to Kafka record headers
+func KafkaHeadersFromNotification(notification *entity.Notification) []sarama.RecordHeader {
+	headers := make([]sarama.RecordHeader, 0, len(notification.TraceContext))
+	for key, value := range notification.TraceContext {
+		headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+	}
+
+	return headers
+}
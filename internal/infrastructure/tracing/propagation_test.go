@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func contextWithRemoteSpan(t *testing.T) (context.Context, trace.SpanContext) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled, Remote: true})
+
+	return trace.ContextWithRemoteSpanContext(context.Background(), spanContext), spanContext
+}
+
+func TestTraceContextSurvivesNotification(t *testing.T) {
+	ctx, spanContext := contextWithRemoteSpan(t)
+	notification := &entity.Notification{}
+
+	InjectToNotification(ctx, notification)
+	require.Contains(t, notification.TraceContext, "traceparent")
+
+	extracted := trace.SpanContextFromContext(ContextFromNotification(context.Background(), notification))
+	assert.Equal(t, spanContext.TraceID(), extracted.TraceID())
+	assert.Equal(t, spanContext.SpanID(), extracted.SpanID())
+}
+
+func TestTraceContextSurvivesKafkaHeaders(t *testing.T) {
+	ctx, spanContext := contextWithRemoteSpan(t)
+	notification := &entity.Notification{}
+	InjectToNotification(ctx, notification)
+
+	headers := KafkaHeadersFromNotification(notification)
+	received := make([]*sarama.RecordHeader, 0, len(headers))
+	for i := range headers {
+		received = append(received, &headers[i])
+	}
+
+	extracted := trace.SpanContextFromContext(ContextFromKafkaHeaders(context.Background(), received))
+	assert.Equal(t, spanContext.TraceID(), extracted.TraceID())
+	assert.Equal(t, spanContext.SpanID(), extracted.SpanID())
+}
+
+func TestNotificationWithoutTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	notification := &entity.Notification{}
+
+	InjectToNotification(context.Background(), notification)
+
+	assert.Nil(t, notification.TraceContext)
+	assert.Empty(t, KafkaHeadersFromNotification(notification))
+	assert.False(t, trace.SpanContextFromContext(ContextFromNotification(context.Background(), notification)).IsValid())
+}
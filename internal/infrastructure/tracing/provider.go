@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// Provider wraps OpenTelemetry tracer provider functionality
+type Provider struct {
+	provider *sdktrace.TracerProvider
+	file     io.Closer
+}
+
+// NewProvider creates tracer provider with configured exporter and registers it globally
+// together with W3C trace context propagator
+func NewProvider(ctx context.Context, cfg *config.Config, serviceName string) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	var err error
+
+	switch cfg.Tracing.Exporter {
+	case ExporterNone, "":
+		return &Provider{}, nil
+	case ExporterOTLP:
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Tracing.OTLPEndpoint)}
+		if cfg.Tracing.OTLPInsecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		file, err = os.OpenFile(cfg.Tracing.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("can't open traces file: %w", err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Tracing.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't create tracing exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("can't create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return &Provider{provider: provider, file: file}, nil
+}
+
+// Close flushes collected spans and stops the exporter
+func (p *Provider) Close() error {
+	if p.provider == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := p.provider.Shutdown(ctx)
+	if p.file != nil {
+		p.file.Close()
+	}
+
+	return err
+}
@@ -0,0 +1,108 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	saltLength = 16
+	// recordSize of the only record, push services accept up to 4096 octets of body
+	recordSize = 4096
+	// MaxPayloadLength is plaintext which fits the record with header, delimiter and GCM tag
+	MaxPayloadLength = recordSize - saltLength - 4 - 1 - 65 - 1 - 16
+	authSecretLength = 16
+	paddingDelimiter = 0x02
+)
+
+var ErrPayloadTooLarge = errors.New("web push payload is too large")
+
+// Encrypt encrypts payload for user agent with aes128gcm content coding of RFC 8188 and keys of RFC 8291.
+// userAgentPublicKey is p256dh key of the subscription, authSecret is its auth key.
+func Encrypt(payload []byte, userAgentPublicKey []byte, authSecret []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadLength {
+		return nil, ErrPayloadTooLarge
+	}
+	if len(authSecret) != authSecretLength {
+		return nil, fmt.Errorf("auth secret must be %d octets", authSecretLength)
+	}
+
+	// Для каждого сообщения создается новая пара ключей сервера приложений
+	applicationServerKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("can`t generate ECDH key: %w", err)
+	}
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("can`t generate salt: %w", err)
+	}
+
+	return encrypt(payload, userAgentPublicKey, authSecret, applicationServerKey, salt)
+}
+
+func encrypt(payload []byte, userAgentPublicKey []byte, authSecret []byte, applicationServerKey *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	userAgentKey, err := ecdh.P256().NewPublicKey(userAgentPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("can`t parse p256dh key: %w", err)
+	}
+
+	sharedSecret, err := applicationServerKey.ECDH(userAgentKey)
+	if err != nil {
+		return nil, fmt.Errorf("can`t derive ECDH secret: %w", err)
+	}
+	applicationServerPublicKey := applicationServerKey.PublicKey().Bytes()
+
+	contentKey, nonce, err := deriveKeys(sharedSecret, authSecret, userAgentPublicKey, applicationServerPublicKey, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, fmt.Errorf("can`t init AES: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("can`t init GCM: %w", err)
+	}
+
+	// Заголовок: salt, размер записи, длина и значение keyid (публичный ключ сервера приложений)
+	body := make([]byte, 0, saltLength+4+1+len(applicationServerPublicKey)+len(payload)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(applicationServerPublicKey)))
+	body = append(body, applicationServerPublicKey...)
+
+	plaintext := append(append(make([]byte, 0, len(payload)+1), payload...), paddingDelimiter)
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+func deriveKeys(sharedSecret []byte, authSecret []byte, userAgentPublicKey []byte, applicationServerPublicKey []byte, salt []byte) ([]byte, []byte, error) {
+	keyInfo := "WebPush: info\x00" + string(userAgentPublicKey) + string(applicationServerPublicKey)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can`t derive input keying material: %w", err)
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can`t derive pseudorandom key: %w", err)
+	}
+	contentKey, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can`t derive content encryption key: %w", err)
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can`t derive nonce: %w", err)
+	}
+
+	return contentKey, nonce, nil
+}
@@ -0,0 +1,93 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeBase64URL(t *testing.T, value string) []byte {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	require.NoError(t, err)
+	return decoded
+}
+
+// TestEncryptRFC8291Example encrypts the message of RFC 8291 Appendix A with its keys and salt
+func TestEncryptRFC8291Example(t *testing.T) {
+	applicationServerKey, err := ecdh.P256().NewPrivateKey(decodeBase64URL(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	require.NoError(t, err)
+	require.Equal(t, "BP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A8",
+		base64.RawURLEncoding.EncodeToString(applicationServerKey.PublicKey().Bytes()))
+
+	body, err := encrypt(
+		[]byte("When I grow up, I want to be a watermelon"),
+		decodeBase64URL(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"),
+		decodeBase64URL(t, "BTBZMqHH6r4Tts7J_aSIgg"),
+		applicationServerKey,
+		decodeBase64URL(t, "DGv6ra1nlYgDCS1FRnbzlw"),
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN",
+		base64.RawURLEncoding.EncodeToString(body))
+}
+
+// TestEncryptDecryptsByUserAgent decrypts the body the way browser does with its private key
+func TestEncryptDecryptsByUserAgent(t *testing.T) {
+	userAgentKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	authSecret := make([]byte, authSecretLength)
+	rand.Read(authSecret)
+	payload := []byte(`{"title":"Alert","body":"disk is full"}`)
+
+	body, err := Encrypt(payload, userAgentKey.PublicKey().Bytes(), authSecret)
+	require.NoError(t, err)
+
+	salt := body[:saltLength]
+	assert.Equal(t, uint32(recordSize), binary.BigEndian.Uint32(body[saltLength:saltLength+4]))
+	keyLength := int(body[saltLength+4])
+	applicationServerPublicKey := body[saltLength+5 : saltLength+5+keyLength]
+
+	applicationServerKey, err := ecdh.P256().NewPublicKey(applicationServerPublicKey)
+	require.NoError(t, err)
+	sharedSecret, err := userAgentKey.ECDH(applicationServerKey)
+	require.NoError(t, err)
+	contentKey, nonce, err := deriveKeys(sharedSecret, authSecret, userAgentKey.PublicKey().Bytes(), applicationServerPublicKey, salt)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(contentKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, body[saltLength+5+keyLength:], nil)
+	require.NoError(t, err)
+	assert.Equal(t, append(payload, paddingDelimiter), plaintext)
+}
+
+func TestEncryptRejectsInvalidInput(t *testing.T) {
+	userAgentKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	userAgentPublicKey := userAgentKey.PublicKey().Bytes()
+	authSecret := make([]byte, authSecretLength)
+
+	_, err = Encrypt(make([]byte, MaxPayloadLength+1), userAgentPublicKey, authSecret)
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+
+	_, err = Encrypt([]byte("payload"), userAgentPublicKey, authSecret[:8])
+	assert.Error(t, err)
+
+	_, err = Encrypt([]byte("payload"), userAgentPublicKey[:33], authSecret)
+	assert.Error(t, err)
+
+	// Тело с payload максимальной длины не превышает 4096 октетов, которые принимают push сервисы
+	body, err := Encrypt(make([]byte, MaxPayloadLength), userAgentPublicKey, authSecret)
+	require.NoError(t, err)
+	assert.Equal(t, recordSize, len(body))
+}
@@ -0,0 +1,79 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// vapidTokenTTL push services reject tokens expiring later than in 24 hours
+const vapidTokenTTL = 12 * time.Hour
+
+// Vapid signs requests to push services with application server key of RFC 8292
+type Vapid struct {
+	privateKey *ecdsa.PrivateKey
+	// publicKey is uncompressed P-256 point in base64url, browsers take it as applicationServerKey
+	publicKey string
+	subject   string
+}
+
+// NewVapid parses private key given as base64url of 32 octets scalar, subject is mailto: or https: contact
+func NewVapid(privateKey string, subject string) (*Vapid, error) {
+	rawPrivateKey, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("can`t decode VAPID private key: %w", err)
+	}
+
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), rawPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("can`t parse VAPID private key: %w", err)
+	}
+	publicKey, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("can`t encode VAPID public key: %w", err)
+	}
+
+	return &Vapid{privateKey: key, publicKey: base64.RawURLEncoding.EncodeToString(publicKey), subject: subject}, nil
+}
+
+// PublicKey returns application server key in base64url
+func (v *Vapid) PublicKey() string {
+	return v.publicKey
+}
+
+// Authorization returns value of Authorization header for request to the push service endpoint
+func (v *Vapid) Authorization(endpoint string, now time.Time) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("can`t parse endpoint: %w", err)
+	}
+
+	claims, err := json.Marshal(map[string]any{
+		"aud": endpointURL.Scheme + "://" + endpointURL.Host,
+		"exp": now.Add(vapidTokenTTL).Unix(),
+		"sub": v.subject,
+	})
+	if err != nil {
+		return "", fmt.Errorf("can`t marshal VAPID claims: %w", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`)) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, v.privateKey, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("can`t sign VAPID token: %w", err)
+	}
+
+	// ES256 подписывает JWT конкатенацией r и s по 32 октета, а не ASN.1
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return fmt.Sprintf("vapid t=%s.%s, k=%s", unsigned, base64.RawURLEncoding.EncodeToString(signature), v.publicKey), nil
+}
@@ -0,0 +1,68 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVapidAuthorizationIsVerifiedWithPublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rawPrivateKey, err := key.Bytes()
+	require.NoError(t, err)
+
+	vapid, err := NewVapid(base64.RawURLEncoding.EncodeToString(rawPrivateKey), "mailto:admin@example.com")
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	authorization, err := vapid.Authorization("https://push.example.net:8443/send/abc?x=1", now)
+	require.NoError(t, err)
+
+	require.True(t, strings.HasPrefix(authorization, "vapid t="))
+	token, publicKey, found := strings.Cut(strings.TrimPrefix(authorization, "vapid t="), ", k=")
+	require.True(t, found)
+	assert.Equal(t, vapid.PublicKey(), publicKey)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	var header, claims map[string]any
+	require.NoError(t, json.Unmarshal(decodeBase64URL(t, parts[0]), &header))
+	require.NoError(t, json.Unmarshal(decodeBase64URL(t, parts[1]), &claims))
+	assert.Equal(t, map[string]any{"typ": "JWT", "alg": "ES256"}, header)
+	assert.Equal(t, "https://push.example.net:8443", claims["aud"])
+	assert.Equal(t, float64(now.Add(vapidTokenTTL).Unix()), claims["exp"])
+	assert.Equal(t, "mailto:admin@example.com", claims["sub"])
+
+	verifyingKey, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), decodeBase64URL(t, publicKey))
+	require.NoError(t, err)
+	assert.True(t, verifyingKey.Equal(&key.PublicKey))
+
+	signature := decodeBase64URL(t, parts[2])
+	require.Len(t, signature, 64)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	assert.True(t, ecdsa.Verify(verifyingKey, digest[:], r, s))
+
+	digest[0] ^= 0xff
+	assert.False(t, ecdsa.Verify(verifyingKey, digest[:], r, s))
+}
+
+func TestNewVapidRejectsInvalidKey(t *testing.T) {
+	_, err := NewVapid("not base64!", "mailto:admin@example.com")
+	assert.Error(t, err)
+
+	_, err = NewVapid(base64.RawURLEncoding.EncodeToString(make([]byte, 32)), "mailto:admin@example.com")
+	assert.Error(t, err)
+}
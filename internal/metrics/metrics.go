@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "notifications"
+
+var (
+	NotificationsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "received_total",
+		Help:      "Notifications received by notifications channel.",
+	}, []string{"channel"})
+
+	NotificationsDelivered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "delivered_total",
+		Help:      "Notifications successfully processed by notifications channel.",
+	}, []string{"channel"})
+
+	NotificationsRetried = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retried_total",
+		Help:      "Retries of notifications processing.",
+	}, []string{"channel"})
+
+	NotificationsDead = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dead_total",
+		Help:      "Notifications passed to dead notifications processor.",
+	}, []string{"channel"})
+
+	NotificationsScheduled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scheduled_total",
+		Help:      "Notifications postponed by scheduler.",
+	}, []string{"channel"})
+
+	NotificationsSuppressed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "suppressed_total",
+		Help:      "Notifications skipped because of user preferences.",
+	}, []string{"channel"})
+
+	NotificationsDigested = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "digested_total",
+		Help:      "Notifications collected into digests.",
+	}, []string{"channel"})
+
+	NotificationsRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Notifications delayed or dropped by rate limits.",
+	}, []string{"channel", "scope"})
+
+	ProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "processing_duration_seconds",
+		Help:      "Duration of one NotificationsProcessor.Process call.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"channel", "result"})
+
+	KafkaConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kafka_consumer_lag",
+		Help:      "Difference between high water mark and last consumed offset of Kafka partition.",
+	}, []string{"topic", "partition"})
+
+	WSActiveConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ws_active_connections",
+		Help:      "WebSocket connections held by the instance.",
+	})
+
+	WSSlowConnectionsClosed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ws_slow_connections_closed_total",
+		Help:      "WebSocket connections closed, because their send buffer overflowed.",
+	})
+
+	RedisReaders = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "redis_reader_goroutines",
+		Help:      "Shared goroutines reading per-user Redis streams.",
+	})
+
+	SMTPSendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "smtp_send_duration_seconds",
+		Help:      "Duration of sending one email through SMTP.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+
+	WebhookRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_request_duration_seconds",
+		Help:      "Duration of one webhook HTTP request.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+)
+
+// Result returns label value for the result of an operation
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
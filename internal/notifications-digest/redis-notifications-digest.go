@@ -0,0 +1,141 @@
+package notifications_digest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// closeGracePeriod window is claimed a bit after its end, so notifications added at the very end get into it
+const closeGracePeriod = 5 * time.Second
+
+// collectedTTL removes lists of windows, which weren't sent for a long time
+const collectedTTL = 7 * 24 * time.Hour
+
+// claimScript returns closed windows and moves them forward by lease, so other instances skip them meanwhile
+var claimScript = redis.NewScript(`
+local windows = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+local leaseEnd = tonumber(ARGV[1]) + tonumber(ARGV[3])
+for _, window in ipairs(windows) do
+	redis.call('ZADD', KEYS[1], leaseEnd, window)
+end
+return windows
+`)
+
+// addScript collects notification and registers its window at once, so Ack can't remove window of not sent notification
+var addScript = redis.NewScript(`
+redis.call('RPUSH', KEYS[1], ARGV[1])
+redis.call('PEXPIREAT', KEYS[1], ARGV[2])
+redis.call('ZADD', KEYS[2], 'NX', ARGV[3], ARGV[4])
+return 1
+`)
+
+// ackScript removes collected notifications, window stays in the queue while notifications added after collecting remain
+var ackScript = redis.NewScript(`
+redis.call('LTRIM', KEYS[1], tonumber(ARGV[1]), -1)
+if redis.call('LLEN', KEYS[1]) == 0 then
+	redis.call('ZREM', KEYS[2], ARGV[2])
+else
+	redis.call('ZADD', KEYS[2], ARGV[3], ARGV[2])
+end
+return 1
+`)
+
+type RedisNotificationsDigest struct {
+	client redis.UniversalClient
+}
+
+func NewRedisNotificationsDigest(client redis.UniversalClient) *RedisNotificationsDigest {
+	return &RedisNotificationsDigest{client: client}
+}
+
+func (r *RedisNotificationsDigest) Add(ctx context.Context, window entity.DigestWindow, notification *entity.Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("can`t marshal digest notification: %w", err)
+	}
+
+	member, err := json.Marshal(window)
+	if err != nil {
+		return fmt.Errorf("can`t marshal digest window: %w", err)
+	}
+
+	key := tools.GetUserDigestKey(window.UserEmail, window.Category, window.End.Unix())
+	keys := []string{key, tools.REDIS_DIGEST_WINDOWS}
+	err = addScript.Run(ctx, r.client, keys, payload, window.End.Add(collectedTTL).UnixMilli(), window.End.UnixMilli(), member).Err()
+	if err != nil {
+		return fmt.Errorf("can`t add notification to digest of %s: %w", window.UserEmail, err)
+	}
+
+	return nil
+}
+
+func (r *RedisNotificationsDigest) Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.DigestWindow, error) {
+	closedBefore := time.Now().Add(-closeGracePeriod).UnixMilli()
+	members, err := claimScript.Run(ctx, r.client, []string{tools.REDIS_DIGEST_WINDOWS}, closedBefore, limit, lease.Milliseconds()).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("can`t claim digest windows: %w", err)
+	}
+
+	windows := make([]entity.DigestWindow, 0, len(members))
+	for _, member := range members {
+		var window entity.DigestWindow
+		if err := json.Unmarshal([]byte(member), &window); err != nil {
+			slog.Error("Can`t parse digest window, dropping it", slog.String("window", member), slog.String("error", err.Error()))
+			r.client.ZRem(ctx, tools.REDIS_DIGEST_WINDOWS, member)
+			continue
+		}
+		windows = append(windows, window)
+	}
+
+	return windows, nil
+}
+
+func (r *RedisNotificationsDigest) Collect(ctx context.Context, window entity.DigestWindow, limit int) (*entity.Digest, error) {
+	key := tools.GetUserDigestKey(window.UserEmail, window.Category, window.End.Unix())
+
+	var itemsCmd *redis.StringSliceCmd
+	var totalCmd *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		itemsCmd = pipe.LRange(ctx, key, 0, int64(limit)-1)
+		totalCmd = pipe.LLen(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can`t read digest of %s: %w", window.UserEmail, err)
+	}
+
+	digest := &entity.Digest{DigestWindow: window, Total: int(totalCmd.Val())}
+	for _, item := range itemsCmd.Val() {
+		var notification entity.Notification
+		if err := json.Unmarshal([]byte(item), &notification); err != nil {
+			slog.Warn("Skip malformed digest notification", slog.String("user_email", window.UserEmail), slog.String("error", err.Error()))
+			continue
+		}
+		digest.Notifications = append(digest.Notifications, notification)
+	}
+
+	return digest, nil
+}
+
+func (r *RedisNotificationsDigest) Ack(ctx context.Context, window entity.DigestWindow, collected int) error {
+	member, err := json.Marshal(window)
+	if err != nil {
+		return fmt.Errorf("can`t marshal digest window: %w", err)
+	}
+
+	keys := []string{tools.GetUserDigestKey(window.UserEmail, window.Category, window.End.Unix()), tools.REDIS_DIGEST_WINDOWS}
+	err = ackScript.Run(ctx, r.client, keys, collected, member, window.End.UnixMilli()).Err()
+	if err != nil {
+		return fmt.Errorf("can`t ack digest of %s: %w", window.UserEmail, err)
+	}
+
+	return nil
+}
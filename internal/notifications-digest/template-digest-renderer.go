@@ -0,0 +1,48 @@
+package notifications_digest
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+)
+
+//go:embed templates/digest.html
+var digestTemplateSource string
+
+var digestTemplate = template.Must(template.New("digest").Parse(digestTemplateSource))
+
+type digestItem struct {
+	Subject string
+	// Body comes from producers, so it's escaped by the template
+	Body string
+}
+
+type TemplateDigestRenderer struct{}
+
+func NewTemplateDigestRenderer() *TemplateDigestRenderer {
+	return &TemplateDigestRenderer{}
+}
+
+func (t *TemplateDigestRenderer) Render(digest *entity.Digest) (string, string, error) {
+	items := make([]digestItem, 0, len(digest.Notifications))
+	for _, notification := range digest.Notifications {
+		items = append(items, digestItem{Subject: notification.Subject, Body: notification.Body})
+	}
+
+	var body bytes.Buffer
+	err := digestTemplate.Execute(&body, map[string]any{
+		"Total":    digest.Total,
+		"Category": digest.Category,
+		"Items":    items,
+		"More":     digest.Total - len(items),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("can`t render digest: %w", err)
+	}
+
+	subject := fmt.Sprintf("%d new notifications: %s", digest.Total, digest.Category)
+	return subject, body.String(), nil
+}
@@ -0,0 +1,30 @@
+package notifications_digest
+
+import (
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateDigestRendererEscapesBody(t *testing.T) {
+	digest := &entity.Digest{
+		DigestWindow: entity.DigestWindow{UserEmail: "user@example.com", Category: "news"},
+		Total:        3,
+		Notifications: []entity.Notification{
+			{Subject: "<b>Subject</b>", Body: `<script>alert("x")</script><a href="javascript:alert(1)">link</a>`},
+		},
+	}
+
+	subject, body, err := NewTemplateDigestRenderer().Render(digest)
+	require.NoError(t, err)
+
+	assert.Equal(t, "3 new notifications: news", subject)
+	assert.NotContains(t, body, "<script>")
+	assert.NotContains(t, body, `<a href=`)
+	assert.NotContains(t, body, "<b>")
+	assert.Contains(t, body, "&lt;script&gt;")
+	assert.Contains(t, body, "And 2 more.")
+}
@@ -0,0 +1,149 @@
+package notifications_inbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const unreadCountBatchSize = 1000
+
+// markReadScript moves the read cursor forward only, so late or repeated requests can't move it back
+var markReadScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local currentMs, currentSeq = string.match(current, '^(%d+)-(%d+)$')
+	local newMs, newSeq = string.match(ARGV[1], '^(%d+)-(%d+)$')
+	if currentMs and (tonumber(currentMs) > tonumber(newMs) or (tonumber(currentMs) == tonumber(newMs) and tonumber(currentSeq) >= tonumber(newSeq))) then
+		return 0
+	end
+end
+redis.call('SET', KEYS[1], ARGV[1])
+return 1
+`)
+
+type RedisNotificationsInbox struct {
+	client redis.UniversalClient
+	cfg    *config.Config
+}
+
+func NewRedisNotificationsInbox(client redis.UniversalClient, cfg *config.Config) *RedisNotificationsInbox {
+	return &RedisNotificationsInbox{client: client, cfg: cfg}
+}
+
+func (r *RedisNotificationsInbox) List(ctx context.Context, userEmail string, cursor string, limit int64, ascending bool) ([]entity.InboxNotification, string, error) {
+	tools.TouchUserLastSeen(ctx, r.client, r.cfg, userEmail)
+
+	lastReadID, err := r.readLastReadID(ctx, userEmail)
+	if err != nil {
+		return nil, "", err
+	}
+
+	streamName := tools.GetUserStreamName(userEmail)
+
+	// Берем на одну запись больше, чтобы понять, есть ли следующая страница
+	var messages []redis.XMessage
+	if ascending {
+		start := "-"
+		if cursor != "" {
+			start = "(" + cursor
+		}
+		messages, err = r.client.XRangeN(ctx, streamName, start, "+", limit+1).Result()
+	} else {
+		end := "+"
+		if cursor != "" {
+			end = "(" + cursor
+		}
+		messages, err = r.client.XRevRangeN(ctx, streamName, end, "-", limit+1).Result()
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("can`t read inbox of user %s: %w", userEmail, err)
+	}
+
+	nextCursor := ""
+	if int64(len(messages)) > limit {
+		messages = messages[:limit]
+		nextCursor = messages[len(messages)-1].ID
+	}
+
+	items := make([]entity.InboxNotification, 0, len(messages))
+	for _, message := range messages {
+		notification, err := tools.ParseStreamNotification(message.Values)
+		if err != nil {
+			slog.Error("Can`t parse inbox notification", slog.String("user_email", userEmail), slog.String("redis_message_id", message.ID), slog.String("error", err.Error()))
+			continue
+		}
+
+		items = append(items, entity.InboxNotification{
+			ID:           message.ID,
+			Read:         lastReadID != "" && tools.CompareStreamIDs(message.ID, lastReadID) <= 0,
+			Notification: *notification,
+		})
+	}
+
+	return items, nextCursor, nil
+}
+
+func (r *RedisNotificationsInbox) UnreadCount(ctx context.Context, userEmail string) (int64, error) {
+	lastReadID, err := r.readLastReadID(ctx, userEmail)
+	if err != nil {
+		return 0, err
+	}
+
+	streamName := tools.GetUserStreamName(userEmail)
+	if lastReadID == "" {
+		count, err := r.client.XLen(ctx, streamName).Result()
+		if err != nil {
+			return 0, fmt.Errorf("can`t count inbox notifications of user %s: %w", userEmail, err)
+		}
+		return count, nil
+	}
+
+	var count int64
+	start := "(" + lastReadID
+	for {
+		messages, err := r.client.XRangeN(ctx, streamName, start, "+", unreadCountBatchSize).Result()
+		if err != nil {
+			return 0, fmt.Errorf("can`t count unread inbox notifications of user %s: %w", userEmail, err)
+		}
+
+		count += int64(len(messages))
+		if len(messages) < unreadCountBatchSize {
+			return count, nil
+		}
+		start = "(" + messages[len(messages)-1].ID
+	}
+}
+
+func (r *RedisNotificationsInbox) MarkRead(ctx context.Context, userEmail string, notificationID string) error {
+	err := markReadScript.Run(ctx, r.client, []string{tools.GetUserInboxLastReadNotificationID(userEmail)}, notificationID).Err()
+	if err != nil {
+		return fmt.Errorf("can`t mark inbox of user %s as read: %w", userEmail, err)
+	}
+
+	return nil
+}
+
+func (r *RedisNotificationsInbox) Delete(ctx context.Context, userEmail string, notificationID string) (bool, error) {
+	deleted, err := r.client.XDel(ctx, tools.GetUserStreamName(userEmail), notificationID).Result()
+	if err != nil {
+		return false, fmt.Errorf("can`t delete inbox notification of user %s: %w", userEmail, err)
+	}
+
+	return deleted > 0, nil
+}
+
+func (r *RedisNotificationsInbox) readLastReadID(ctx context.Context, userEmail string) (string, error) {
+	lastReadID, err := r.client.Get(ctx, tools.GetUserInboxLastReadNotificationID(userEmail)).Result()
+	if err != nil && err != redis.Nil {
+		return "", fmt.Errorf("can`t fetch last read inbox notification ID of user %s: %w", userEmail, err)
+	}
+
+	return lastReadID, nil
+}
@@ -0,0 +1,125 @@
+package notifications_inbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserEmail = "user@example.com"
+
+// newTestInbox returns inbox with notifications 1-0..count-0 of the test user
+func newTestInbox(t *testing.T, count int) *RedisNotificationsInbox {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	for i := 1; i <= count; i++ {
+		payload, err := json.Marshal(entity.Notification{UserEmail: testUserEmail, Subject: fmt.Sprint(i)})
+		require.NoError(t, err)
+		err = client.XAdd(context.Background(), &redis.XAddArgs{
+			Stream: tools.GetUserStreamName(testUserEmail),
+			ID:     fmt.Sprintf("%d-0", i),
+			Values: map[string]interface{}{tools.REDIS_STREAM_NOTIFICATION_FIELD_NAME: string(payload)},
+		}).Err()
+		require.NoError(t, err)
+	}
+
+	return NewRedisNotificationsInbox(client, &config.Config{})
+}
+
+func inboxIDs(items []entity.InboxNotification) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestListPaginatesInbox(t *testing.T) {
+	tests := []struct {
+		name      string
+		ascending bool
+		pages     [][]string
+	}{
+		{name: "newest first", pages: [][]string{{"5-0", "4-0"}, {"3-0", "2-0"}, {"1-0"}}},
+		{name: "oldest first", ascending: true, pages: [][]string{{"1-0", "2-0"}, {"3-0", "4-0"}, {"5-0"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inbox := newTestInbox(t, 5)
+
+			cursor := ""
+			for i, page := range tt.pages {
+				items, nextCursor, err := inbox.List(context.Background(), testUserEmail, cursor, 2, tt.ascending)
+				require.NoError(t, err)
+				assert.Equal(t, page, inboxIDs(items))
+
+				if i == len(tt.pages)-1 {
+					assert.Empty(t, nextCursor)
+				} else {
+					assert.Equal(t, page[len(page)-1], nextCursor)
+				}
+				cursor = nextCursor
+			}
+		})
+	}
+}
+
+func TestMarkReadMovesCursorForwardOnly(t *testing.T) {
+	ctx := context.Background()
+	inbox := newTestInbox(t, 5)
+
+	unread, err := inbox.UnreadCount(ctx, testUserEmail)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), unread)
+
+	require.NoError(t, inbox.MarkRead(ctx, testUserEmail, "3-0"))
+	// Запоздавший запрос не возвращает курсор назад
+	require.NoError(t, inbox.MarkRead(ctx, testUserEmail, "2-0"))
+
+	unread, err = inbox.UnreadCount(ctx, testUserEmail)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), unread)
+
+	items, _, err := inbox.List(ctx, testUserEmail, "", 10, true)
+	require.NoError(t, err)
+	read := make([]bool, 0, len(items))
+	for _, item := range items {
+		read = append(read, item.Read)
+	}
+	assert.Equal(t, []bool{true, true, true, false, false}, read)
+
+	// Идентификатор с тем же ms, но большим seq двигает курсор
+	require.NoError(t, inbox.MarkRead(ctx, testUserEmail, "5-1"))
+	unread, err = inbox.UnreadCount(ctx, testUserEmail)
+	require.NoError(t, err)
+	assert.Zero(t, unread)
+}
+
+func TestDeleteReportsMissingNotification(t *testing.T) {
+	ctx := context.Background()
+	inbox := newTestInbox(t, 2)
+
+	deleted, err := inbox.Delete(ctx, testUserEmail, "2-0")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = inbox.Delete(ctx, testUserEmail, "2-0")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	items, _, err := inbox.List(ctx, testUserEmail, "", 10, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1-0"}, inboxIDs(items))
+}
@@ -5,13 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/mwsbkru/evrone-go-final/config"
 	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	"github.com/mwsbkru/evrone-go-final/internal/metrics"
 	"github.com/mwsbkru/evrone-go-final/internal/service"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type KafkaNotificationsObserver struct {
@@ -21,6 +28,8 @@ type KafkaNotificationsObserver struct {
 	cfg        *config.Config
 	subscriber service.NotificationsSubscriber
 	terminator service.Terminator
+	// sessionActive is true between Setup and Cleanup of consumer group session
+	sessionActive atomic.Bool
 }
 
 func NewKafkaNotificationsObserver(topicName string, cfg *config.Config, consumer sarama.ConsumerGroup) *KafkaNotificationsObserver {
@@ -55,22 +64,48 @@ func (k *KafkaNotificationsObserver) ConsumeClaim(session sarama.ConsumerGroupSe
 	// Обработка сообщений
 	for message := range claim.Messages() {
 		slog.Info("Kafka observer - message received", slog.String("message.key", string(message.Key)), slog.String("message.value", string(message.Value)))
+		metrics.KafkaConsumerLag.WithLabelValues(message.Topic, strconv.Itoa(int(message.Partition))).Set(float64(claim.HighWaterMarkOffset() - message.Offset - 1))
 
-		go func() {
-			session.MarkMessage(message, "")
-			notification, err := messageToNotification(message)
-			if err != nil {
-				slog.Error("KafkaNotificationsProcessor: error unmarshall json", slog.String("error", err.Error()))
-				return
-			}
-
-			k.subscriber(notification)
-		}()
+		// Подписчик блокируется, пока канал перегружен - так чтение партиции приостанавливается
+		k.handleMessage(session, message)
 	}
 
 	return nil
 }
 
+func (k *KafkaNotificationsObserver) handleMessage(session sarama.ConsumerGroupSession, message *sarama.ConsumerMessage) {
+	defer session.MarkMessage(message, "")
+
+	ctx := tracing.ContextFromKafkaHeaders(session.Context(), message.Headers)
+	ctx, span := tracing.Tracer().Start(ctx, "kafka.consume "+message.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", message.Topic),
+			attribute.Int("messaging.kafka.partition", int(message.Partition)),
+			attribute.Int64("messaging.kafka.offset", message.Offset),
+		))
+	defer span.End()
+
+	notification, err := messageToNotification(message)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		slog.Error("KafkaNotificationsProcessor: error unmarshall json", slog.String("error", err.Error()))
+		return
+	}
+
+	// Старые продюсеры не выставляют timestamp сообщения
+	producedAt := message.Timestamp
+	if producedAt.IsZero() {
+		producedAt = time.Now()
+	}
+	notification.ResolveExpiry(producedAt)
+
+	tracing.InjectToNotification(ctx, notification)
+	k.subscriber(notification)
+}
+
 func messageToNotification(message *sarama.ConsumerMessage) (*entity.Notification, error) {
 	var notification entity.Notification
 
@@ -83,9 +118,15 @@ func messageToNotification(message *sarama.ConsumerMessage) (*entity.Notificatio
 }
 
 func (k *KafkaNotificationsObserver) Setup(sarama.ConsumerGroupSession) error {
+	k.sessionActive.Store(true)
 	return nil
 }
 
 func (k *KafkaNotificationsObserver) Cleanup(sarama.ConsumerGroupSession) error {
+	k.sessionActive.Store(false)
 	return nil
 }
+
+func (k *KafkaNotificationsObserver) SessionActive() bool {
+	return k.sessionActive.Load()
+}
@@ -0,0 +1,233 @@
+package notifications_observer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/kafka"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/IBM/sarama"
+)
+
+// priorityLane one topic lane, consumed by its own consumer group: normal lane keeps groupID,
+// high and low lanes use groupID with priority suffix, so join of one lane doesn't rebalance the others
+type priorityLane struct {
+	priority string
+	weight   int
+	observer *KafkaNotificationsObserver
+	consumer *kafka.Consumer
+	queue    chan *entity.Notification
+}
+
+// KafkaPriorityNotificationsObserver consumes high, normal and low priority lanes of the topic
+// and passes notifications to subscriber with weighted round robin: in one round up to weight
+// notifications are taken from every non-empty lane, high priority lane goes first.
+// Notification with priority field is moved to the queue of its lane once it's read, but it can't
+// overtake notifications of its topic, which aren't read from Kafka yet.
+type KafkaPriorityNotificationsObserver struct {
+	topicName  string
+	lanes      []*priorityLane
+	subscriber service.NotificationsSubscriber
+	terminator service.Terminator
+	// wakeup signals dispatcher, that one of the lanes got notification
+	wakeup chan struct{}
+}
+
+// NewKafkaPriorityNotificationsObserver consumes lanes of the topic in consumer group, empty groupID means KAFKA_CONSUMER_GROUP_ID
+func NewKafkaPriorityNotificationsObserver(topicName string, groupID string, cfg *config.Config, client sarama.Client) (*KafkaPriorityNotificationsObserver, error) {
+	if groupID == "" {
+		groupID = cfg.Kafka.ConsumerGroupID
+	}
+
+	observer := &KafkaPriorityNotificationsObserver{topicName: topicName, wakeup: make(chan struct{}, 1)}
+
+	weights := map[string]int{entity.PriorityNormal: 1}
+	if cfg.Priority.LanesEnabled {
+		weights = map[string]int{
+			entity.PriorityHigh:   max(cfg.Priority.HighWeight, 1),
+			entity.PriorityNormal: max(cfg.Priority.NormalWeight, 1),
+			entity.PriorityLow:    max(cfg.Priority.LowWeight, 1),
+		}
+	}
+
+	for _, priority := range []string{entity.PriorityHigh, entity.PriorityNormal, entity.PriorityLow} {
+		weight, ok := weights[priority]
+		if !ok {
+			continue
+		}
+
+		laneTopic := tools.GetPriorityTopicName(topicName, priority)
+		if cfg.Priority.LanesEnabled {
+			err := tools.EnsureTopicExists(laneTopic, client)
+			if err != nil {
+				observer.Close()
+				return nil, fmt.Errorf("can`t prepare topic %s: %w", laneTopic, err)
+			}
+		}
+
+		consumer, err := kafka.NewConsumer(getLaneGroupID(groupID, priority), client)
+		if err != nil {
+			observer.Close()
+			return nil, fmt.Errorf("can`t init Kafka consumer of topic %s: %w", laneTopic, err)
+		}
+
+		observer.lanes = append(observer.lanes, &priorityLane{
+			priority: priority,
+			weight:   weight,
+			observer: NewKafkaNotificationsObserver(laneTopic, cfg, consumer.GetConsumer()),
+			consumer: consumer,
+			queue:    make(chan *entity.Notification, max(cfg.Priority.LaneQueueSize, 1)),
+		})
+	}
+
+	return observer, nil
+}
+
+func (k *KafkaPriorityNotificationsObserver) Subscribe(subscriber service.NotificationsSubscriber, terminator service.Terminator) {
+	k.subscriber = subscriber
+	k.terminator = terminator
+}
+
+func (k *KafkaPriorityNotificationsObserver) StartListening(ctx context.Context) {
+	var lanesWg sync.WaitGroup
+	lanesWg.Add(len(k.lanes))
+	for _, lane := range k.lanes {
+		lane.observer.Subscribe(k.laneSubscriber(lane), lanesWg.Done)
+		go lane.observer.StartListening(ctx)
+	}
+
+	lanesDone := make(chan struct{})
+	go func() {
+		lanesWg.Wait()
+		close(lanesDone)
+	}()
+
+	// Уведомления, уже попавшие в очереди полос, передаются подписчику и после остановки чтения
+	k.dispatch(lanesDone)
+
+	slog.Info("Terminating Kafka priority observer", slog.String("topic", k.topicName))
+	k.terminator()
+}
+
+// getLaneGroupID normal lane keeps group of the topic, so its committed offsets stay valid.
+// Groups of high and low lanes have no offsets on first start, they read lanes from the oldest retained message.
+func getLaneGroupID(groupID string, priority string) string {
+	if priority == entity.PriorityNormal {
+		return groupID
+	}
+	return fmt.Sprintf("%s.%s", groupID, priority)
+}
+
+// laneSubscriber blocks while queue is full, so reading of the lane is paused
+func (k *KafkaPriorityNotificationsObserver) laneSubscriber(lane *priorityLane) service.NotificationsSubscriber {
+	return func(notification *entity.Notification) {
+		if notification.Priority == "" {
+			notification.Priority = lane.priority
+		}
+
+		// Приоритет из payload важнее полосы, в которую продюсер отправил уведомление
+		target := lane
+		for _, candidate := range k.lanes {
+			if candidate.priority == notification.Priority {
+				target = candidate
+				break
+			}
+		}
+
+		target.queue <- notification
+		select {
+		case k.wakeup <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (k *KafkaPriorityNotificationsObserver) dispatch(lanesDone chan struct{}) {
+	credits := make([]int, len(k.lanes))
+	refill := func() {
+		for i, lane := range k.lanes {
+			credits[i] = lane.weight
+		}
+	}
+	refill()
+
+	for {
+		notification, ok := k.next(credits)
+		if ok {
+			k.subscriber(notification)
+			continue
+		}
+
+		// Непустые полосы исчерпали свою долю - начинаем новый раунд
+		if k.pending() {
+			refill()
+			continue
+		}
+
+		select {
+		case <-k.wakeup:
+		case <-lanesDone:
+			if !k.pending() {
+				return
+			}
+		}
+	}
+}
+
+// next takes notification from the highest priority lane, which has credits left in this round
+func (k *KafkaPriorityNotificationsObserver) next(credits []int) (*entity.Notification, bool) {
+	for i, lane := range k.lanes {
+		if credits[i] <= 0 {
+			continue
+		}
+
+		select {
+		case notification := <-lane.queue:
+			credits[i]--
+			return notification, true
+		default:
+		}
+	}
+
+	return nil, false
+}
+
+func (k *KafkaPriorityNotificationsObserver) pending() bool {
+	for _, lane := range k.lanes {
+		if len(lane.queue) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SessionActive reports whether consumer group sessions of all lanes are active
+func (k *KafkaPriorityNotificationsObserver) SessionActive() bool {
+	for _, lane := range k.lanes {
+		if !lane.observer.SessionActive() {
+			return false
+		}
+	}
+
+	return len(k.lanes) > 0
+}
+
+// Close closes consumers of all lanes
+func (k *KafkaPriorityNotificationsObserver) Close() error {
+	var errs []error
+	for _, lane := range k.lanes {
+		if err := lane.consumer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
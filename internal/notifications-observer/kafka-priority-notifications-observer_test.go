@@ -0,0 +1,72 @@
+package notifications_observer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPriorityObserver(weights map[string]int) *KafkaPriorityNotificationsObserver {
+	observer := &KafkaPriorityNotificationsObserver{wakeup: make(chan struct{}, 1)}
+	for _, priority := range []string{entity.PriorityHigh, entity.PriorityNormal, entity.PriorityLow} {
+		observer.lanes = append(observer.lanes, &priorityLane{
+			priority: priority,
+			weight:   weights[priority],
+			queue:    make(chan *entity.Notification, 10),
+		})
+	}
+
+	return observer
+}
+
+func TestPriorityObserverDispatchesWithWeightedRoundRobin(t *testing.T) {
+	observer := newTestPriorityObserver(map[string]int{entity.PriorityHigh: 3, entity.PriorityNormal: 2, entity.PriorityLow: 1})
+	for _, lane := range observer.lanes {
+		for i := 1; i <= 4; i++ {
+			lane.queue <- &entity.Notification{Subject: fmt.Sprintf("%s-%d", lane.priority, i)}
+		}
+	}
+
+	var dispatched []string
+	observer.subscriber = func(notification *entity.Notification) {
+		dispatched = append(dispatched, notification.Subject)
+	}
+
+	lanesDone := make(chan struct{})
+	close(lanesDone)
+	observer.dispatch(lanesDone)
+
+	assert.Equal(t, []string{
+		"high-1", "high-2", "high-3", "normal-1", "normal-2", "low-1",
+		"high-4", "normal-3", "normal-4", "low-2",
+		"low-3", "low-4",
+	}, dispatched)
+}
+
+func TestPriorityObserverMovesNotificationToLaneOfItsPriority(t *testing.T) {
+	observer := newTestPriorityObserver(map[string]int{entity.PriorityHigh: 1, entity.PriorityNormal: 1, entity.PriorityLow: 1})
+	high, normal, low := observer.lanes[0], observer.lanes[1], observer.lanes[2]
+
+	observer.laneSubscriber(normal)(&entity.Notification{Subject: "urgent", Priority: entity.PriorityHigh})
+	observer.laneSubscriber(low)(&entity.Notification{Subject: "without priority"})
+	observer.laneSubscriber(normal)(&entity.Notification{Subject: "unknown", Priority: "critical"})
+
+	assert.Equal(t, "urgent", (<-high.queue).Subject)
+
+	withoutPriority := <-low.queue
+	assert.Equal(t, "without priority", withoutPriority.Subject)
+	assert.Equal(t, entity.PriorityLow, withoutPriority.Priority)
+
+	// Уведомление с неизвестным приоритетом остается в полосе, из которой прочитано
+	assert.Equal(t, "unknown", (<-normal.queue).Subject)
+	assert.Len(t, observer.wakeup, 1)
+}
+
+func TestGetLaneGroupID(t *testing.T) {
+	assert.Equal(t, "notifications", getLaneGroupID("notifications", entity.PriorityNormal))
+	assert.Equal(t, "notifications.high", getLaneGroupID("notifications", entity.PriorityHigh))
+	assert.Equal(t, "notifications.low", getLaneGroupID("notifications", entity.PriorityLow))
+}
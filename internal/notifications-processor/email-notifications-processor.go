@@ -4,19 +4,25 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/mwsbkru/evrone-go-final/config"
 	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/smtp"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	"github.com/mwsbkru/evrone-go-final/internal/metrics"
 
 	mail "github.com/xhit/go-simple-mail/v2"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type EmailNotificationsProcessor struct {
 	cfg        *config.Config
-	smtpClient *mail.SMTPClient
+	smtpClient *smtp.Client
 }
 
-func NewEmailNotificationsProcessor(cfg *config.Config, smtpClient *mail.SMTPClient) *EmailNotificationsProcessor {
+func NewEmailNotificationsProcessor(cfg *config.Config, smtpClient *smtp.Client) *EmailNotificationsProcessor {
 	return &EmailNotificationsProcessor{
 		cfg:        cfg,
 		smtpClient: smtpClient,
@@ -35,7 +41,15 @@ func (e *EmailNotificationsProcessor) Process(ctx context.Context, notification
 		return reportAndWrapErrorEmail(email.Error, notification.CurrentRetry)
 	}
 
-	err := email.Send(e.smtpClient)
+	_, span := tracing.Tracer().Start(ctx, "smtp.send", trace.WithSpanKind(trace.SpanKindClient))
+	startedAt := time.Now()
+	err := e.smtpClient.Send(email)
+	metrics.SMTPSendDuration.WithLabelValues(metrics.Result(err)).Observe(time.Since(startedAt).Seconds())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
 	if err != nil {
 		return reportAndWrapErrorEmail(err, notification.CurrentRetry)
 	}
@@ -0,0 +1,24 @@
+package notifications_processor
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+// chatMaxResponseBytes limits response of chat APIs, it carries only result of the request
+const chatMaxResponseBytes = 64 << 10
+
+// checkHTTPStatus returns nil for 2xx. Other 3xx and 4xx are permanent failures,
+// 408, 429 and 5xx are transient ones.
+func checkHTTPStatus(target string, statusCode int) error {
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return nil
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests || statusCode >= 500:
+		return fmt.Errorf("%s responded with status %d", target, statusCode)
+	default:
+		return fmt.Errorf("%w: %s responded with status %d", service.ErrPermanent, target, statusCode)
+	}
+}
@@ -0,0 +1,213 @@
+package notifications_processor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/IBM/sarama"
+)
+
+// KafkaRouterNotificationsProcessor fans notification request out to topics of its channels
+type KafkaRouterNotificationsProcessor struct {
+	cfg           *config.Config
+	producer      sarama.SyncProducer
+	preferences   *service.PreferencesService
+	statusTracker service.NotificationsStatusTracker
+	fallback      *service.FallbackCoordinator
+	// topics maps channel to its topic
+	topics map[string]string
+}
+
+// NewKafkaRouterNotificationsProcessor topics maps key of every channel notification can be routed to onto its topic
+func NewKafkaRouterNotificationsProcessor(cfg *config.Config, topics map[string]string, producer sarama.SyncProducer, preferences *service.PreferencesService, statusTracker service.NotificationsStatusTracker, fallback *service.FallbackCoordinator) *KafkaRouterNotificationsProcessor {
+	return &KafkaRouterNotificationsProcessor{
+		cfg:           cfg,
+		producer:      producer,
+		preferences:   preferences,
+		statusTracker: statusTracker,
+		fallback:      fallback,
+		topics:        topics,
+	}
+}
+
+// Process routes notification to every channel once, channels already routed by previous attempt are skipped
+func (k *KafkaRouterNotificationsProcessor) Process(ctx context.Context, notification *entity.Notification) error {
+	if notification.CorrelationID == "" {
+		notification.CorrelationID = notification.ID
+	}
+	if notification.CorrelationID == "" {
+		correlationID, err := newCorrelationID()
+		if err != nil {
+			return err
+		}
+		notification.CorrelationID = correlationID
+	}
+
+	if notification.Fallback != "" {
+		chain, ok := k.fallback.Chain(notification.Fallback)
+		if ok {
+			return k.startFallback(ctx, notification, chain)
+		}
+		slog.Warn("Unknown fallback chain, routing to channels", slog.String("correlation_id", notification.CorrelationID), slog.String("fallback", notification.Fallback))
+	}
+
+	channels, err := k.resolveChannels(ctx, notification)
+	if err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		slog.Info("Notification request has no channels to route to", slog.String("correlation_id", notification.CorrelationID))
+		return nil
+	}
+
+	routed, err := k.statusTracker.Get(ctx, notification.CorrelationID)
+	if err != nil {
+		return err
+	}
+
+	for _, channel := range channels {
+		if _, ok := routed[channel]; ok {
+			continue
+		}
+
+		err := k.Route(ctx, notification, channel)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startFallback routes notification to the first step of the chain, coordinator escalates it further
+func (k *KafkaRouterNotificationsProcessor) startFallback(ctx context.Context, notification *entity.Notification, chain entity.FallbackChain) error {
+	routed, err := k.statusTracker.Get(ctx, notification.CorrelationID)
+	if err != nil {
+		return err
+	}
+
+	firstChannel := chain.Steps[0].Channel
+	if _, ok := routed[firstChannel]; !ok {
+		err := k.Route(ctx, notification, firstChannel)
+		if err != nil {
+			return err
+		}
+	}
+
+	return k.fallback.Start(ctx, notification, chain)
+}
+
+func (k *KafkaRouterNotificationsProcessor) resolveChannels(ctx context.Context, notification *entity.Notification) ([]string, error) {
+	if len(notification.Channels) == 0 || slices.Contains(notification.Channels, entity.ChannelsAuto) {
+		// Каналы по умолчанию, которых нет в конфигурации, пропускаются
+		defaults := slices.DeleteFunc([]string{entity.ChannelEmail, entity.ChannelPush, entity.ChannelWS}, func(channel string) bool {
+			_, ok := k.topics[channel]
+			return !ok
+		})
+		return k.preferences.AllowedChannels(ctx, notification.UserEmail, notification.Category, defaults)
+	}
+
+	channels := make([]string, 0, len(notification.Channels))
+	for _, channel := range notification.Channels {
+		if _, ok := k.topics[channel]; !ok {
+			slog.Warn("Skip unknown channel of notification request", slog.String("correlation_id", notification.CorrelationID), slog.String("channel", channel))
+			continue
+		}
+		if !slices.Contains(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+
+	return channels, nil
+}
+
+// Route sends notification to the topic of the channel once. Channel is marked as routed before publishing,
+// so retry of the request doesn't publish it again, the mark is removed if publishing failed.
+func (k *KafkaRouterNotificationsProcessor) Route(ctx context.Context, notification *entity.Notification, channel string) error {
+	channelNotification := *notification
+	channelNotification.Channels = nil
+	channelNotification.Fallback = ""
+	channelNotification.CurrentRetry = 0
+	channelNotification.DeliveredTo = nil
+	channelNotification.Priority = routedPriority(notification)
+	// ID уникален в пределах канала, по нему отменяются отложенные уведомления
+	channelNotification.ID = fmt.Sprintf("%s-%s", notification.CorrelationID, channel)
+
+	payload, err := json.Marshal(&channelNotification)
+	if err != nil {
+		return fmt.Errorf("can`t marshal notification for %s: %w", channel, err)
+	}
+
+	routedStatus := entity.ChannelStatus{Status: entity.StatusRouted, UpdatedAt: time.Now()}
+	marked, err := k.statusTracker.TrackInitial(ctx, notification.CorrelationID, channel, routedStatus)
+	if err != nil {
+		return fmt.Errorf("can`t mark notification as routed to %s: %w", channel, err)
+	}
+	if !marked {
+		slog.Info("Notification already routed", slog.String("correlation_id", notification.CorrelationID), slog.String("channel", channel))
+		return nil
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic:   k.topic(channel, channelNotification.Priority),
+		Key:     sarama.StringEncoder(notification.UserEmail),
+		Value:   sarama.ByteEncoder(payload),
+		Headers: tracing.KafkaHeadersFromNotification(notification),
+	}
+	_, _, err = k.producer.SendMessage(msg)
+	if err != nil {
+		// Снимаем отметку, иначе повторная попытка пропустит канал
+		untrackErr := k.statusTracker.Untrack(context.WithoutCancel(ctx), notification.CorrelationID, channel, routedStatus)
+		if untrackErr != nil {
+			slog.Error("Can`t unmark not routed notification", slog.String("correlation_id", notification.CorrelationID), slog.String("channel", channel), slog.String("error", untrackErr.Error()))
+		}
+		return fmt.Errorf("can`t route notification to %s: %w", channel, err)
+	}
+
+	slog.Info("Notification routed", slog.String("correlation_id", notification.CorrelationID), slog.String("channel", channel))
+	return nil
+}
+
+// topic returns lane of the priority, without lanes all priorities share the topic of the channel
+func (k *KafkaRouterNotificationsProcessor) topic(channel string, priority string) string {
+	if !k.cfg.Priority.LanesEnabled {
+		return k.topics[channel]
+	}
+
+	return tools.GetPriorityTopicName(k.topics[channel], priority)
+}
+
+// routedPriority replaces unknown priority with normal, lanes of unknown priorities aren't consumed
+func routedPriority(notification *entity.Notification) string {
+	switch notification.Priority {
+	case entity.PriorityHigh, entity.PriorityNormal, entity.PriorityLow:
+		return notification.Priority
+	case "":
+		return ""
+	default:
+		slog.Warn("Unknown priority of notification, routing as normal", slog.String("correlation_id", notification.CorrelationID), slog.String("priority", notification.Priority))
+		return entity.PriorityNormal
+	}
+}
+
+func newCorrelationID() (string, error) {
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", fmt.Errorf("can`t generate correlation ID: %w", err)
+	}
+
+	return hex.EncodeToString(randomBytes), nil
+}
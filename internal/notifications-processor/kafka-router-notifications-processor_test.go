@@ -0,0 +1,139 @@
+package notifications_processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestRouter(t *testing.T, tracker service.NotificationsStatusTracker) (*KafkaRouterNotificationsProcessor, *mocks.SyncProducer) {
+	producer := mocks.NewSyncProducer(t, nil)
+	t.Cleanup(func() { producer.Close() })
+	cfg := &config.Config{}
+	cfg.Priority.LanesEnabled = true
+	return NewKafkaRouterNotificationsProcessor(cfg, map[string]string{entity.ChannelEmail: "notifications_email"}, producer, nil, tracker, nil), producer
+}
+
+func TestRouteMarksChannelBeforePublishing(t *testing.T) {
+	tracker := &service.MockNotificationsStatusTracker{}
+	tracker.On("TrackInitial", mock.Anything, "corr", entity.ChannelEmail, mock.Anything).Return(true, nil).Once()
+	router, producer := newTestRouter(t, tracker)
+	producer.ExpectSendMessageAndSucceed()
+
+	err := router.Route(context.Background(), &entity.Notification{CorrelationID: "corr"}, entity.ChannelEmail)
+
+	assert.NoError(t, err)
+	tracker.AssertExpectations(t)
+}
+
+func TestRouteSkipsAlreadyRoutedChannel(t *testing.T) {
+	tracker := &service.MockNotificationsStatusTracker{}
+	tracker.On("TrackInitial", mock.Anything, "corr", entity.ChannelEmail, mock.Anything).Return(false, nil).Once()
+	router, _ := newTestRouter(t, tracker)
+
+	// Продюсер без ожиданий упадет на любой отправке
+	err := router.Route(context.Background(), &entity.Notification{CorrelationID: "corr"}, entity.ChannelEmail)
+
+	assert.NoError(t, err)
+	tracker.AssertExpectations(t)
+}
+
+func TestRouteDoesNotPublishWhenMarkFails(t *testing.T) {
+	tracker := &service.MockNotificationsStatusTracker{}
+	tracker.On("TrackInitial", mock.Anything, "corr", entity.ChannelEmail, mock.Anything).Return(false, errors.New("redis is down")).Once()
+	router, _ := newTestRouter(t, tracker)
+
+	err := router.Route(context.Background(), &entity.Notification{CorrelationID: "corr"}, entity.ChannelEmail)
+
+	assert.Error(t, err)
+	tracker.AssertExpectations(t)
+}
+
+func TestRouteUnmarksChannelWhenPublishFails(t *testing.T) {
+	tracker := &service.MockNotificationsStatusTracker{}
+	tracker.On("TrackInitial", mock.Anything, "corr", entity.ChannelEmail, mock.Anything).Return(true, nil).Once()
+	tracker.On("Untrack", mock.Anything, "corr", entity.ChannelEmail, mock.MatchedBy(func(status entity.ChannelStatus) bool {
+		return status.Status == entity.StatusRouted
+	})).Return(nil).Once()
+	router, producer := newTestRouter(t, tracker)
+	producer.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+
+	err := router.Route(context.Background(), &entity.Notification{CorrelationID: "corr"}, entity.ChannelEmail)
+
+	assert.Error(t, err)
+	tracker.AssertExpectations(t)
+}
+
+func TestProcessRoutesToTopicsOfDeclaredChannels(t *testing.T) {
+	tracker := &service.MockNotificationsStatusTracker{}
+	tracker.On("Get", mock.Anything, "corr").Return(map[string]entity.ChannelStatus{}, nil)
+	tracker.On("TrackInitial", mock.Anything, "corr", "pager", mock.Anything).Return(true, nil).Once()
+	producer := mocks.NewSyncProducer(t, nil)
+	defer producer.Close()
+	router := NewKafkaRouterNotificationsProcessor(&config.Config{}, map[string]string{"pager": "notifications_pager"}, producer, nil, tracker, nil)
+
+	var topic string
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		topic = msg.Topic
+		return nil
+	})
+
+	// Канал email не объявлен в конфигурации и пропускается
+	err := router.Process(context.Background(), &entity.Notification{CorrelationID: "corr", Channels: []string{"pager", entity.ChannelEmail}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "notifications_pager", topic)
+	tracker.AssertExpectations(t)
+}
+
+func TestRouteChoosesTopicOfPriority(t *testing.T) {
+	tests := []struct {
+		name         string
+		lanesEnabled bool
+		priority     string
+		topic        string
+		routed       string
+	}{
+		{name: "high priority lane", lanesEnabled: true, priority: entity.PriorityHigh, topic: "notifications_email.high", routed: entity.PriorityHigh},
+		{name: "normal priority lane", lanesEnabled: true, priority: entity.PriorityNormal, topic: "notifications_email", routed: entity.PriorityNormal},
+		{name: "without priority", lanesEnabled: true, topic: "notifications_email"},
+		{name: "lanes disabled", priority: entity.PriorityLow, topic: "notifications_email", routed: entity.PriorityLow},
+		{name: "unknown priority", lanesEnabled: true, priority: "urgent", topic: "notifications_email", routed: entity.PriorityNormal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := &service.MockNotificationsStatusTracker{}
+			tracker.On("TrackInitial", mock.Anything, "corr", entity.ChannelEmail, mock.Anything).Return(true, nil)
+			producer := mocks.NewSyncProducer(t, nil)
+			defer producer.Close()
+			cfg := &config.Config{}
+			cfg.Priority.LanesEnabled = tt.lanesEnabled
+			router := NewKafkaRouterNotificationsProcessor(cfg, map[string]string{entity.ChannelEmail: "notifications_email"}, producer, nil, tracker, nil)
+
+			var topic string
+			var routed entity.Notification
+			producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+				topic = msg.Topic
+				payload, _ := msg.Value.Encode()
+				return json.Unmarshal(payload, &routed)
+			})
+
+			err := router.Route(context.Background(), &entity.Notification{CorrelationID: "corr", Priority: tt.priority}, entity.ChannelEmail)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.topic, topic)
+			assert.Equal(t, tt.routed, routed.Priority)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package notifications_processor
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+)
+
+// newPublicTransport returns transport for URLs registered by users. Address is checked when it's dialed,
+// after DNS resolution, so host resolving to internal address is rejected too, redirects of DNS included.
+func newPublicTransport(connectTimeout time.Duration, allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: connectTimeout}
+	if !allowPrivate {
+		dialer.Control = checkPublicAddress
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+	if !allowPrivate {
+		// Прокси сам соединяется с адресом назначения, проверка адреса его бы не коснулась
+		transport.Proxy = nil
+	}
+
+	return transport
+}
+
+func checkPublicAddress(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: can`t parse dialed address %s: %w", service.ErrPermanent, address, err)
+	}
+	if !tools.IsPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s isn't public address", service.ErrPermanent, addrPort.Addr())
+	}
+
+	return nil
+}
@@ -6,19 +6,27 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/mwsbkru/evrone-go-final/config"
 	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
 	"github.com/mwsbkru/evrone-go-final/internal/tools"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type RedisWSNotificationsProcessor struct {
-	client *redis.Client
+	client redis.UniversalClient
+	cfg    *config.Config
 }
 
-func NewRedisWSNotificationsProcessor(client *redis.Client) *RedisWSNotificationsProcessor {
-	return &RedisWSNotificationsProcessor{client: client}
+func NewRedisWSNotificationsProcessor(client redis.UniversalClient, cfg *config.Config) *RedisWSNotificationsProcessor {
+	return &RedisWSNotificationsProcessor{client: client, cfg: cfg}
 }
 
 func (r *RedisWSNotificationsProcessor) Process(ctx context.Context, notification *entity.Notification) error {
@@ -36,16 +44,43 @@ func (r *RedisWSNotificationsProcessor) Process(ctx context.Context, notificatio
 	// Формируем имя потока на основе email пользователя
 	streamName := tools.GetUserStreamName(notification.UserEmail)
 
-	// Добавляем сообщение в поток Redis
-	_, err = r.client.XAdd(ctx, &redis.XAddArgs{
-		Stream: streamName,
-		ID:     "*", // Используем автоинкремент ID
-		Values: map[string]interface{}{
-			"notification": string(notificationJSON),
-		},
-	}).Result()
+	ctx, span := tracing.Tracer().Start(ctx, "redis.xadd", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
+	// Контекст трассировки кладем отдельным полем записи, чтобы продолжить трейс при отправке в WS
+	values := map[string]interface{}{
+		tools.REDIS_STREAM_NOTIFICATION_FIELD_NAME: string(notificationJSON),
+	}
+	traceCarrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, traceCarrier)
+	if len(traceCarrier) > 0 {
+		traceContextJSON, err := json.Marshal(traceCarrier)
+		if err == nil {
+			values[tools.REDIS_STREAM_TRACE_CONTEXT_FIELD_NAME] = string(traceContextJSON)
+		}
+	}
+
+	// Добавляем сообщение в поток Redis, заодно приблизительно обрезая поток по длине и возрасту
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: streamName,
+			ID:     "*", // Используем автоинкремент ID
+			MaxLen: r.cfg.Redis.StreamMaxLen,
+			Approx: true,
+			Values: values,
+		})
+
+		if r.cfg.Redis.StreamMaxAgeHours > 0 {
+			minTime := time.Now().Add(-time.Duration(r.cfg.Redis.StreamMaxAgeHours) * time.Hour)
+			pipe.XTrimMinIDApprox(ctx, streamName, fmt.Sprintf("%d-0", minTime.UnixMilli()), 0)
+		}
+
+		return nil
+	})
 
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return reportAndWrapErrorWs(err, notification.CurrentRetry)
 	}
 
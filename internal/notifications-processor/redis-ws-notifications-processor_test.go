@@ -0,0 +1,52 @@
+package notifications_processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestRedisClient(t *testing.T) *redis.Client {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestRedisWSProcessorKeepsTraceContextInStreamEntry(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled, Remote: true}))
+
+	client := newTestRedisClient(t)
+	processor := NewRedisWSNotificationsProcessor(client, &config.Config{})
+
+	err = processor.Process(ctx, &entity.Notification{UserEmail: "user@example.com", Subject: "Hello"})
+	require.NoError(t, err)
+
+	messages, err := client.XRange(context.Background(), tools.GetUserStreamName("user@example.com"), "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0].Values, tools.REDIS_STREAM_TRACE_CONTEXT_FIELD_NAME)
+
+	notification, err := tools.ParseStreamNotification(messages[0].Values)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", notification.Subject)
+	assert.Equal(t, traceID, trace.SpanContextFromContext(tracing.ContextFromNotification(context.Background(), notification)).TraceID())
+}
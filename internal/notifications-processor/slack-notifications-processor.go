@@ -0,0 +1,125 @@
+package notifications_processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+// Limits of Block Kit: header text and section text
+const (
+	slackMaxHeaderRunes  = 150
+	slackMaxSectionRunes = 3000
+)
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type string    `json:"type"`
+	Text slackText `json:"text"`
+}
+
+type slackMessage struct {
+	// Text is shown in notifications of Slack clients, which don't render blocks
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// SlackNotificationsProcessor posts notification to Slack-compatible incoming webhook,
+// Subject becomes header block and Body becomes mrkdwn section
+type SlackNotificationsProcessor struct {
+	cfg    *config.Config
+	client *http.Client
+}
+
+func NewSlackNotificationsProcessor(cfg *config.Config) *SlackNotificationsProcessor {
+	return &SlackNotificationsProcessor{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.Slack.TimeoutSeconds) * time.Second},
+	}
+}
+
+func (s *SlackNotificationsProcessor) Process(ctx context.Context, notification *entity.Notification) error {
+	body, err := json.Marshal(formatSlackMessage(notification))
+	if err != nil {
+		return fmt.Errorf("%w: can`t marshal Slack message: %w", service.ErrPermanent, err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Slack.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: can`t prepare Slack request", service.ErrPermanent)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		// URL входящего вебхука является секретом
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("SlackNotificationsProcessor error send notification: %w", err)
+	}
+	defer response.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(response.Body, chatMaxResponseBytes))
+	if err := checkHTTPStatus("Slack webhook", response.StatusCode); err != nil {
+		return fmt.Errorf("SlackNotificationsProcessor error send notification: %w: %s", err, responseBody)
+	}
+
+	return nil
+}
+
+func formatSlackMessage(notification *entity.Notification) slackMessage {
+	body := truncateSlack(escapeSlack(notification.Body), slackMaxSectionRunes)
+	message := slackMessage{Text: escapeSlack(notification.Subject)}
+	if message.Text == "" {
+		message.Text = body
+	}
+
+	if notification.Subject != "" {
+		message.Blocks = append(message.Blocks, slackBlock{
+			Type: "header",
+			Text: slackText{Type: "plain_text", Text: truncateRunes(notification.Subject, slackMaxHeaderRunes)},
+		})
+	}
+	if body != "" {
+		message.Blocks = append(message.Blocks, slackBlock{Type: "section", Text: slackText{Type: "mrkdwn", Text: body}})
+	}
+
+	return message
+}
+
+// escapeSlack escapes control characters of Slack markup, so text isn't taken for mentions or links
+func escapeSlack(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// truncateSlack cuts escaped text without leaving a part of escaped character at the end
+func truncateSlack(text string, limit int) string {
+	truncated := truncateRunes(text, limit)
+	if truncated == text {
+		return text
+	}
+
+	withoutEllipsis := strings.TrimSuffix(truncated, "…")
+	if amp := strings.LastIndexByte(withoutEllipsis, '&'); amp > strings.LastIndexByte(withoutEllipsis, ';') {
+		return withoutEllipsis[:amp] + "…"
+	}
+
+	return truncated
+}
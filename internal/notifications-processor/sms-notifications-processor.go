@@ -0,0 +1,63 @@
+package notifications_processor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+)
+
+// SmsNotificationsProcessor sends Body of notification as SMS, Subject is sent when Body is empty
+type SmsNotificationsProcessor struct {
+	cfg      *config.Config
+	sender   service.SmsSender
+	messages service.SmsMessagesStore
+}
+
+func NewSmsNotificationsProcessor(cfg *config.Config, sender service.SmsSender, messages service.SmsMessagesStore) *SmsNotificationsProcessor {
+	return &SmsNotificationsProcessor{cfg: cfg, sender: sender, messages: messages}
+}
+
+func (s *SmsNotificationsProcessor) Process(ctx context.Context, notification *entity.Notification) error {
+	phone, err := tools.NormalizePhone(notification.Phone)
+	if err != nil {
+		return fmt.Errorf("%w: %w", service.ErrPermanent, err)
+	}
+
+	text := notification.Body
+	if text == "" {
+		text = notification.Subject
+	}
+	if text == "" {
+		return fmt.Errorf("%w: SMS text is empty", service.ErrPermanent)
+	}
+
+	segments := len(tools.SplitSms(text).Parts)
+	if s.cfg.Sms.MaxSegments > 0 && segments > s.cfg.Sms.MaxSegments {
+		return fmt.Errorf("%w: SMS takes %d segments, limit is %d", service.ErrPermanent, segments, s.cfg.Sms.MaxSegments)
+	}
+
+	// Отправитель возвращает ID уже отправленных частей и при ошибке, их квитанции тоже учитываются
+	messageIDs, sendErr := s.sender.Send(ctx, phone, text)
+	parts := len(messageIDs)
+	if sendErr != nil {
+		parts = segments
+	}
+
+	if notification.CorrelationID != "" && len(messageIDs) > 0 {
+		// SMS уже отправлено: ошибка сохранения не должна приводить к повторной отправке
+		if err := s.messages.Save(ctx, messageIDs, parts, notification.CorrelationID); err != nil {
+			slog.Error("Can`t save SMS messages, receipts won't be matched", slog.String("error", err.Error()), slog.String("correlation_id", notification.CorrelationID))
+		}
+	}
+
+	if sendErr != nil {
+		return fmt.Errorf("SmsNotificationsProcessor error send notification: %w", sendErr)
+	}
+
+	return nil
+}
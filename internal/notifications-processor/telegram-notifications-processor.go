@@ -0,0 +1,138 @@
+package notifications_processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+// telegramMaxBodyRunes leaves room for subject and markup in message limited by 4096 characters
+const telegramMaxBodyRunes = 3500
+
+type telegramSendMessageRequest struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Parameters  struct {
+		// RetryAfter seconds to wait, when flood control exceeded
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// TelegramNotificationsProcessor sends notification to the chat of the user with Bot API sendMessage,
+// Subject is bold and Body is plain text. Error code of the response is classified as HTTP status,
+// retry after flood control waits as long as Bot API asked.
+type TelegramNotificationsProcessor struct {
+	cfg    *config.Config
+	client *http.Client
+}
+
+func NewTelegramNotificationsProcessor(cfg *config.Config) *TelegramNotificationsProcessor {
+	return &TelegramNotificationsProcessor{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.Telegram.TimeoutSeconds) * time.Second},
+	}
+}
+
+func (t *TelegramNotificationsProcessor) Process(ctx context.Context, notification *entity.Notification) error {
+	chatID := t.cfg.Telegram.ChatIDs[notification.UserEmail]
+	if chatID == "" {
+		return fmt.Errorf("%w: user %s has no Telegram chat", service.ErrPermanent, notification.UserEmail)
+	}
+
+	body, err := json.Marshal(telegramSendMessageRequest{
+		ChatID:                chatID,
+		Text:                  formatTelegramMessage(notification),
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: can`t marshal Telegram message: %w", service.ErrPermanent, err)
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimRight(t.cfg.Telegram.APIURL, "/"), t.cfg.Telegram.BotToken)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: can`t prepare Telegram request", service.ErrPermanent)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := t.client.Do(request)
+	if err != nil {
+		// URL запроса содержит токен бота, в ошибку он не попадает
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("TelegramNotificationsProcessor error send notification: %w", err)
+	}
+	defer response.Body.Close()
+
+	var telegramResult telegramResponse
+	decodeErr := json.NewDecoder(io.LimitReader(response.Body, chatMaxResponseBytes)).Decode(&telegramResult)
+	if decodeErr == nil && telegramResult.OK {
+		return nil
+	}
+	if decodeErr != nil {
+		if err := checkHTTPStatus("Telegram Bot API", response.StatusCode); err != nil {
+			return fmt.Errorf("TelegramNotificationsProcessor error send notification: %w", err)
+		}
+		return fmt.Errorf("TelegramNotificationsProcessor error send notification: can`t read Bot API response: %w", decodeErr)
+	}
+
+	errorCode := telegramResult.ErrorCode
+	if errorCode == 0 {
+		errorCode = response.StatusCode
+	}
+	err = checkHTTPStatus("Telegram Bot API", errorCode)
+	if err == nil {
+		err = fmt.Errorf("Telegram Bot API responded with error code %d", errorCode)
+	}
+	err = fmt.Errorf("TelegramNotificationsProcessor error send notification: %w: %s", err, telegramResult.Description)
+
+	if telegramResult.Parameters.RetryAfter > 0 && !errors.Is(err, service.ErrPermanent) {
+		return &service.RetryAfterError{After: time.Duration(telegramResult.Parameters.RetryAfter) * time.Second, Err: err}
+	}
+	return err
+}
+
+func formatTelegramMessage(notification *entity.Notification) string {
+	var message strings.Builder
+	if notification.Subject != "" {
+		message.WriteString("<b>")
+		message.WriteString(html.EscapeString(notification.Subject))
+		message.WriteString("</b>\n")
+	}
+	message.WriteString(html.EscapeString(truncateRunes(notification.Body, telegramMaxBodyRunes)))
+
+	return message.String()
+}
+
+// truncateRunes cuts text to limit of characters, ellipsis marks cut text
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,99 @@
+package notifications_processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTelegramProcessor(t *testing.T, handler http.HandlerFunc) *TelegramNotificationsProcessor {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	cfg.Telegram.APIURL = server.URL
+	cfg.Telegram.BotToken = "123:token"
+	cfg.Telegram.ChatIDs = map[string]string{"user@example.com": "-1001"}
+	cfg.Telegram.TimeoutSeconds = 5
+	return NewTelegramNotificationsProcessor(cfg)
+}
+
+func TestTelegramProcessorSendsMessage(t *testing.T) {
+	var sent telegramSendMessageRequest
+	var path string
+	processor := newTestTelegramProcessor(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Write([]byte(`{"ok": true, "result": {"message_id": 1}}`))
+	})
+
+	err := processor.Process(context.Background(), &entity.Notification{UserEmail: "user@example.com", Subject: "<Alert>", Body: "disk & cpu"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "/bot123:token/sendMessage", path)
+	assert.Equal(t, telegramSendMessageRequest{ChatID: "-1001", Text: "<b>&lt;Alert&gt;</b>\ndisk &amp; cpu", ParseMode: "HTML", DisableWebPagePreview: true}, sent)
+}
+
+func TestTelegramProcessorFailsWithoutChatOfUser(t *testing.T) {
+	called := false
+	processor := newTestTelegramProcessor(t, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	err := processor.Process(context.Background(), &entity.Notification{UserEmail: "other@example.com", Body: "body"})
+
+	assert.ErrorIs(t, err, service.ErrPermanent)
+	assert.False(t, called)
+}
+
+func TestTelegramProcessorClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		response   string
+		permanent  bool
+		retryAfter time.Duration
+	}{
+		{name: "ok false with success status", status: http.StatusOK, response: `{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"}`, permanent: true},
+		{name: "bot blocked by user", status: http.StatusForbidden, response: `{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}`, permanent: true},
+		{name: "flood control", status: http.StatusTooManyRequests, response: `{"ok": false, "error_code": 429, "description": "Too Many Requests: retry after 7", "parameters": {"retry_after": 7}}`, retryAfter: 7 * time.Second},
+		{name: "server error", status: http.StatusBadGateway, response: `{"ok": false, "error_code": 502, "description": "Bad Gateway"}`},
+		{name: "server error without JSON", status: http.StatusBadGateway, response: `<html>Bad Gateway</html>`},
+		{name: "success status without JSON", status: http.StatusOK, response: `<html>proxy</html>`},
+		{name: "client error without JSON", status: http.StatusUnauthorized, response: `Unauthorized`, permanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := newTestTelegramProcessor(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			})
+
+			err := processor.Process(context.Background(), &entity.Notification{UserEmail: "user@example.com", Body: "body"})
+
+			require.Error(t, err)
+			assert.Equal(t, tt.permanent, errors.Is(err, service.ErrPermanent))
+			assert.NotContains(t, err.Error(), "123:token")
+
+			var retryAfter *service.RetryAfterError
+			if tt.retryAfter > 0 {
+				require.ErrorAs(t, err, &retryAfter)
+				assert.Equal(t, tt.retryAfter, retryAfter.After)
+			} else {
+				assert.False(t, errors.As(err, &retryAfter))
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package notifications_processor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/webpush"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const webPushMaxResponseBytes = 64 << 10
+
+// errPushSubscriptionGone push service doesn't know the subscription anymore
+var errPushSubscriptionGone = errors.New("push subscription is gone")
+
+type webPushPayload struct {
+	Title         string `json:"title"`
+	Body          string `json:"body"`
+	ID            string `json:"id,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Category      string `json:"category,omitempty"`
+}
+
+// WebPushNotificationsProcessor sends notification to every browser subscription of the user through its push service.
+// Subscriptions answered with 404 or 410 are removed. Endpoints are registered by users, so they are dialed only on public addresses.
+type WebPushNotificationsProcessor struct {
+	cfg           *config.Config
+	vapid         *webpush.Vapid
+	subscriptions *service.PushSubscriptionsService
+	client        *http.Client
+}
+
+func NewWebPushNotificationsProcessor(cfg *config.Config, vapid *webpush.Vapid, subscriptions *service.PushSubscriptionsService) *WebPushNotificationsProcessor {
+	return &WebPushNotificationsProcessor{
+		cfg:           cfg,
+		vapid:         vapid,
+		subscriptions: subscriptions,
+		client: &http.Client{
+			Transport: newPublicTransport(time.Duration(cfg.WebPush.TimeoutSeconds)*time.Second, cfg.WebPush.AllowPrivateNetworks),
+			Timeout:   time.Duration(cfg.WebPush.TimeoutSeconds) * time.Second,
+			// Push сервисы не перенаправляют запросы, VAPID токен выписан для зарегистрированного endpoint
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Process sends notification to subscriptions. When some of them fail transiently the notification is retried,
+// subscriptions which already accepted it are skipped by retries.
+func (w *WebPushNotificationsProcessor) Process(ctx context.Context, notification *entity.Notification) error {
+	subscriptions, err := w.subscriptions.List(ctx, notification.UserEmail)
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return fmt.Errorf("%w: user %s has no push subscriptions", service.ErrPermanent, notification.UserEmail)
+	}
+
+	payload, err := webPushPayloadOf(notification)
+	if err != nil {
+		return fmt.Errorf("%w: %w", service.ErrPermanent, err)
+	}
+
+	var transientErrs, permanentErrs []error
+	accepted := 0
+	for _, subscription := range subscriptions {
+		if slices.Contains(notification.DeliveredTo, subscription.ID) {
+			accepted++
+			continue
+		}
+
+		err := w.send(ctx, notification, subscription, payload)
+		switch {
+		case err == nil:
+			accepted++
+			notification.DeliveredTo = append(notification.DeliveredTo, subscription.ID)
+		case errors.Is(err, errPushSubscriptionGone):
+			slog.Info("Removing stale push subscription", slog.String("user_email", subscription.UserEmail), slog.String("subscription_id", subscription.ID))
+			if err := w.subscriptions.Delete(ctx, subscription.UserEmail, subscription.ID); err != nil && !errors.Is(err, service.ErrPushSubscriptionNotFound) {
+				slog.Error("Can`t remove stale push subscription", slog.String("error", err.Error()))
+			}
+			permanentErrs = append(permanentErrs, fmt.Errorf("%w: %w", service.ErrPermanent, err))
+		case errors.Is(err, service.ErrPermanent):
+			slog.Warn("Push service rejected notification", slog.String("subscription_id", subscription.ID), slog.String("error", err.Error()))
+			permanentErrs = append(permanentErrs, err)
+		default:
+			transientErrs = append(transientErrs, err)
+		}
+	}
+
+	if len(transientErrs) > 0 {
+		return fmt.Errorf("WebPushNotificationsProcessor error send notification: %w", errors.Join(transientErrs...))
+	}
+	if accepted == 0 {
+		return fmt.Errorf("WebPushNotificationsProcessor error send notification: %w", errors.Join(permanentErrs...))
+	}
+
+	return nil
+}
+
+func (w *WebPushNotificationsProcessor) send(ctx context.Context, notification *entity.Notification, subscription entity.PushSubscription, payload []byte) error {
+	p256dh, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(subscription.Keys.P256dh, "="))
+	if err != nil {
+		return fmt.Errorf("%w: can`t decode p256dh key: %w", service.ErrPermanent, err)
+	}
+	auth, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(subscription.Keys.Auth, "="))
+	if err != nil {
+		return fmt.Errorf("%w: can`t decode auth key: %w", service.ErrPermanent, err)
+	}
+
+	body, err := webpush.Encrypt(payload, p256dh, auth)
+	if err != nil {
+		return fmt.Errorf("%w: can`t encrypt push message: %w", service.ErrPermanent, err)
+	}
+	authorization, err := w.vapid.Authorization(subscription.Endpoint, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %w", service.ErrPermanent, err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: can`t prepare push request: %w", service.ErrPermanent, err)
+	}
+	request.Header.Set("Authorization", authorization)
+	request.Header.Set("Content-Encoding", "aes128gcm")
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("TTL", strconv.Itoa(w.ttl(notification)))
+	request.Header.Set("Urgency", webPushUrgency(notification.Priority))
+	if topic := webPushTopic(notification); topic != "" {
+		request.Header.Set("Topic", topic)
+	}
+
+	_, span := tracing.Tracer().Start(ctx, "webpush.send", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("webpush.subscription_id", subscription.ID)))
+	defer span.End()
+
+	err = w.post(request)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("push subscription %s: %w", subscription.ID, err)
+	}
+
+	return nil
+}
+
+func (w *WebPushNotificationsProcessor) post(request *http.Request) error {
+	response, err := w.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("can`t post to push service: %w", err)
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, webPushMaxResponseBytes))
+
+	if response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone {
+		return errPushSubscriptionGone
+	}
+
+	return checkHTTPStatus("push service", response.StatusCode)
+}
+
+// ttl push service keeps message for offline browser until notification expires
+func (w *WebPushNotificationsProcessor) ttl(notification *entity.Notification) int {
+	if notification.ExpiresAt != nil {
+		return max(int(time.Until(*notification.ExpiresAt).Seconds()), 0)
+	}
+
+	return w.cfg.WebPush.TTLSeconds
+}
+
+// webPushPayloadOf marshals notification, Body is cut when payload doesn't fit one record
+func webPushPayloadOf(notification *entity.Notification) ([]byte, error) {
+	payload := webPushPayload{
+		Title:         notification.Subject,
+		Body:          notification.Body,
+		ID:            notification.ID,
+		CorrelationID: notification.CorrelationID,
+		Category:      notification.Category,
+	}
+
+	for {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("can`t marshal push payload: %w", err)
+		}
+		if len(encoded) <= webpush.MaxPayloadLength {
+			return encoded, nil
+		}
+
+		bodyRunes := []rune(payload.Body)
+		if len(bodyRunes) == 0 {
+			return nil, webpush.ErrPayloadTooLarge
+		}
+		// Символ тела занимает в JSON не меньше октета, поэтому цикл сходится
+		payload.Body = truncateRunes(payload.Body, max(len(bodyRunes)-(len(encoded)-webpush.MaxPayloadLength), 1))
+		if len(bodyRunes) == 1 {
+			payload.Body = ""
+		}
+	}
+}
+
+func webPushUrgency(priority string) string {
+	switch priority {
+	case entity.PriorityHigh:
+		return "high"
+	case entity.PriorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// webPushTopic returns Topic header: up to 32 base64url characters, the same for retries of notification
+func webPushTopic(notification *entity.Notification) string {
+	id := notification.ID
+	if id == "" {
+		id = notification.CorrelationID
+	}
+	if id == "" {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(hash[:24])
+}
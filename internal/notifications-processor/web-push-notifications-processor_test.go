@@ -0,0 +1,110 @@
+package notifications_processor
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/webpush"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWebPushProcessor(t *testing.T, allowPrivate bool, endpoints ...string) (*WebPushNotificationsProcessor, *service.MockPushSubscriptionsStore) {
+	cfg := &config.Config{}
+	cfg.WebPush.TimeoutSeconds = 5
+	cfg.WebPush.TTLSeconds = 60
+	cfg.WebPush.AllowPrivateNetworks = allowPrivate
+
+	vapidKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rawVapidKey, err := vapidKey.Bytes()
+	require.NoError(t, err)
+	vapid, err := webpush.NewVapid(base64.RawURLEncoding.EncodeToString(rawVapidKey), "mailto:admin@example.com")
+	require.NoError(t, err)
+
+	subscriptions := make([]entity.PushSubscription, 0, len(endpoints))
+	for i, endpoint := range endpoints {
+		userAgentKey, err := ecdh.P256().GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		auth := make([]byte, 16)
+		rand.Read(auth)
+		subscriptions = append(subscriptions, entity.PushSubscription{
+			ID:        string(rune('a' + i)),
+			UserEmail: "user@example.com",
+			Endpoint:  endpoint,
+			Keys: entity.PushSubscriptionKeys{
+				P256dh: base64.RawURLEncoding.EncodeToString(userAgentKey.PublicKey().Bytes()),
+				Auth:   base64.RawURLEncoding.EncodeToString(auth),
+			},
+		})
+	}
+	store := &service.MockPushSubscriptionsStore{}
+	store.On("List", mock.Anything, "user@example.com").Return(subscriptions, nil)
+
+	return NewWebPushNotificationsProcessor(cfg, vapid, service.NewPushSubscriptionsService(cfg, store, vapid.PublicKey())), store
+}
+
+func TestWebPushProcessorRetriesOnlyFailedSubscriptions(t *testing.T) {
+	accepting := &webhookReceiver{statuses: []int{http.StatusCreated}}
+	flaky := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusCreated}}
+	acceptingServer := httptest.NewServer(accepting)
+	defer acceptingServer.Close()
+	flakyServer := httptest.NewServer(flaky)
+	defer flakyServer.Close()
+
+	processor, _ := newTestWebPushProcessor(t, true, acceptingServer.URL, flakyServer.URL)
+	notification := &entity.Notification{ID: "id", UserEmail: "user@example.com", Subject: "subject", Body: "body"}
+
+	err := processor.Process(context.Background(), notification)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, service.ErrPermanent)
+	assert.Equal(t, []string{"a"}, notification.DeliveredTo)
+
+	err = processor.Process(context.Background(), notification)
+	require.NoError(t, err)
+
+	assert.Len(t, accepting.calls(), 1)
+	assert.Len(t, flaky.calls(), 2)
+	assert.Equal(t, []string{"a", "b"}, notification.DeliveredTo)
+}
+
+func TestWebPushProcessorRemovesGoneSubscriptions(t *testing.T) {
+	accepting := &webhookReceiver{statuses: []int{http.StatusCreated}}
+	gone := &webhookReceiver{statuses: []int{http.StatusGone}}
+	acceptingServer := httptest.NewServer(accepting)
+	defer acceptingServer.Close()
+	goneServer := httptest.NewServer(gone)
+	defer goneServer.Close()
+
+	processor, store := newTestWebPushProcessor(t, true, acceptingServer.URL, goneServer.URL)
+	store.On("Delete", mock.Anything, "user@example.com", "b").Return(true, nil)
+
+	err := processor.Process(context.Background(), &entity.Notification{ID: "id", UserEmail: "user@example.com", Body: "body"})
+
+	require.NoError(t, err)
+	store.AssertCalled(t, "Delete", mock.Anything, "user@example.com", "b")
+}
+
+func TestWebPushProcessorRejectsPrivateAddresses(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusCreated}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	processor, _ := newTestWebPushProcessor(t, false, server.URL)
+	err := processor.Process(context.Background(), &entity.Notification{ID: "id", UserEmail: "user@example.com"})
+
+	assert.ErrorIs(t, err, service.ErrPermanent)
+	assert.Empty(t, receiver.calls())
+}
@@ -0,0 +1,203 @@
+package notifications_processor
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	"github.com/mwsbkru/evrone-go-final/internal/metrics"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Headers of webhook request. Signature is hex HMAC-SHA256 of "<timestamp>.<body>" with the subscription secret,
+// receivers should reject requests with stale timestamp and deduplicate them by delivery ID.
+const (
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	webhookSignaturePrefix = "sha256="
+	// webhookMaxResponseBytes response body is read only to reuse the connection
+	webhookMaxResponseBytes = 64 << 10
+)
+
+type webhookPayload struct {
+	DeliveryID     string    `json:"delivery_id"`
+	NotificationID string    `json:"notification_id,omitempty"`
+	CorrelationID  string    `json:"correlation_id,omitempty"`
+	UserEmail      string    `json:"user_email"`
+	Category       string    `json:"category,omitempty"`
+	Priority       string    `json:"priority,omitempty"`
+	Subject        string    `json:"subject"`
+	Body           string    `json:"body"`
+	SentAt         time.Time `json:"sent_at"`
+}
+
+// WebhookNotificationsProcessor posts notification to every webhook of the user subscribed to its category.
+// Responses 4xx except 408 and 429 are permanent failures, other failures are retried.
+type WebhookNotificationsProcessor struct {
+	webhooks *service.WebhooksService
+	client   *http.Client
+}
+
+func NewWebhookNotificationsProcessor(cfg *config.Config, webhooks *service.WebhooksService) *WebhookNotificationsProcessor {
+	transport := newPublicTransport(time.Duration(cfg.Webhook.ConnectTimeoutSeconds)*time.Second, cfg.Webhook.AllowPrivateNetworks)
+
+	return &WebhookNotificationsProcessor{
+		webhooks: webhooks,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   time.Duration(cfg.Webhook.TimeoutSeconds) * time.Second,
+			// Редиректы не выполняются: подпись относится к зарегистрированному URL
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Process posts notification to webhooks. When some of them fail transiently the notification is retried,
+// webhooks which already accepted it are skipped by retries.
+func (w *WebhookNotificationsProcessor) Process(ctx context.Context, notification *entity.Notification) error {
+	subscriptions, err := w.webhooks.Matching(ctx, notification.UserEmail, notification.Category)
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return fmt.Errorf("%w: user %s has no webhooks for category %q", service.ErrPermanent, notification.UserEmail, notification.Category)
+	}
+
+	if notification.ID == "" && notification.CorrelationID == "" && notification.DeliveryID == "" {
+		deliveryID, err := newCorrelationID()
+		if err != nil {
+			return err
+		}
+		notification.DeliveryID = deliveryID
+	}
+
+	var transientErrs, permanentErrs []error
+	accepted := 0
+	for _, subscription := range subscriptions {
+		if slices.Contains(notification.DeliveredTo, subscription.ID) {
+			accepted++
+			continue
+		}
+
+		err := w.post(ctx, notification, subscription)
+		switch {
+		case err == nil:
+			accepted++
+			notification.DeliveredTo = append(notification.DeliveredTo, subscription.ID)
+		case errors.Is(err, service.ErrPermanent):
+			slog.Warn("Webhook rejected notification", slog.String("webhook_id", subscription.ID), slog.String("error", err.Error()))
+			permanentErrs = append(permanentErrs, err)
+		default:
+			transientErrs = append(transientErrs, err)
+		}
+	}
+
+	// Ретрай имеет смысл, если хотя бы один вебхук может принять уведомление позже
+	if len(transientErrs) > 0 {
+		return fmt.Errorf("WebhookNotificationsProcessor error send notification: %w", errors.Join(transientErrs...))
+	}
+	if accepted == 0 {
+		return fmt.Errorf("WebhookNotificationsProcessor error send notification: %w", errors.Join(permanentErrs...))
+	}
+
+	return nil
+}
+
+func (w *WebhookNotificationsProcessor) post(ctx context.Context, notification *entity.Notification, subscription entity.WebhookSubscription) error {
+	deliveryID := fmt.Sprintf("%s-%s", notificationDeliveryID(notification), subscription.ID)
+
+	now := time.Now()
+	body, err := json.Marshal(webhookPayload{
+		DeliveryID:     deliveryID,
+		NotificationID: notification.ID,
+		CorrelationID:  notification.CorrelationID,
+		UserEmail:      notification.UserEmail,
+		Category:       notification.Category,
+		Priority:       notification.Priority,
+		Subject:        notification.Subject,
+		Body:           notification.Body,
+		SentAt:         now.UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: can`t marshal webhook payload: %w", service.ErrPermanent, err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: can`t prepare webhook request: %w", service.ErrPermanent, err)
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookDeliveryHeader, deliveryID)
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookSignatureHeader, webhookSignaturePrefix+SignWebhook(subscription.Secret, timestamp, body))
+
+	_, span := tracing.Tracer().Start(ctx, "webhook.post", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("webhook.id", subscription.ID)))
+	defer span.End()
+
+	startedAt := time.Now()
+	err = w.send(request)
+	metrics.WebhookRequestDuration.WithLabelValues(metrics.Result(err)).Observe(time.Since(startedAt).Seconds())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("webhook %s: %w", subscription.ID, err)
+	}
+
+	return nil
+}
+
+func (w *WebhookNotificationsProcessor) send(request *http.Request) error {
+	response, err := w.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("can`t post webhook: %w", err)
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, webhookMaxResponseBytes))
+
+	return checkHTTPStatus("webhook", response.StatusCode)
+}
+
+// notificationDeliveryID is the same for all attempts of notification
+func notificationDeliveryID(notification *entity.Notification) string {
+	switch {
+	case notification.ID != "":
+		return notification.ID
+	case notification.CorrelationID != "":
+		return notification.CorrelationID
+	default:
+		return notification.DeliveryID
+	}
+}
+
+// SignWebhook returns hex HMAC-SHA256 signature of webhook request body sent at timestamp
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,109 @@
+package notifications_processor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver answers with the given statuses one by one, the last one is repeated
+type webhookReceiver struct {
+	mu          sync.Mutex
+	statuses    []int
+	deliveryIDs []string
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deliveryIDs = append(r.deliveryIDs, request.Header.Get(WebhookDeliveryHeader))
+	status := r.statuses[min(len(r.deliveryIDs), len(r.statuses))-1]
+	w.WriteHeader(status)
+}
+
+func (r *webhookReceiver) calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.deliveryIDs...)
+}
+
+func newTestWebhookProcessor(t *testing.T, allowPrivate bool, urls ...string) *WebhookNotificationsProcessor {
+	cfg := &config.Config{}
+	cfg.Webhook.TimeoutSeconds = 5
+	cfg.Webhook.ConnectTimeoutSeconds = 5
+	cfg.Webhook.AllowPrivateNetworks = allowPrivate
+
+	subscriptions := make([]entity.WebhookSubscription, 0, len(urls))
+	for i, url := range urls {
+		subscriptions = append(subscriptions, entity.WebhookSubscription{ID: string(rune('a' + i)), UserEmail: "user@example.com", URL: url, Secret: "0123456789abcdef"})
+	}
+	store := &service.MockWebhooksStore{}
+	store.On("List", mock.Anything, "user@example.com").Return(subscriptions, nil)
+
+	return NewWebhookNotificationsProcessor(cfg, service.NewWebhooksService(cfg, store))
+}
+
+func TestWebhookProcessorRetriesOnlyFailedWebhooks(t *testing.T) {
+	accepting := &webhookReceiver{statuses: []int{http.StatusOK}}
+	flaky := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+	acceptingServer := httptest.NewServer(accepting)
+	defer acceptingServer.Close()
+	flakyServer := httptest.NewServer(flaky)
+	defer flakyServer.Close()
+
+	processor := newTestWebhookProcessor(t, true, acceptingServer.URL, flakyServer.URL)
+	notification := &entity.Notification{UserEmail: "user@example.com", Subject: "subject", Body: "body"}
+
+	err := processor.Process(context.Background(), notification)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, service.ErrPermanent)
+
+	err = processor.Process(context.Background(), notification)
+	require.NoError(t, err)
+
+	assert.Len(t, accepting.calls(), 1)
+	flakyCalls := flaky.calls()
+	require.Len(t, flakyCalls, 2)
+	// Повтор идет с тем же ID доставки, хотя у уведомления нет ни ID, ни CorrelationID
+	assert.Equal(t, flakyCalls[0], flakyCalls[1])
+	assert.NotEqual(t, "-b", flakyCalls[0])
+	assert.Equal(t, []string{"a", "b"}, notification.DeliveredTo)
+}
+
+func TestWebhookProcessorDeliveryIDsDifferBetweenNotifications(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	processor := newTestWebhookProcessor(t, true, server.URL)
+	require.NoError(t, processor.Process(context.Background(), &entity.Notification{UserEmail: "user@example.com"}))
+	require.NoError(t, processor.Process(context.Background(), &entity.Notification{UserEmail: "user@example.com"}))
+
+	calls := receiver.calls()
+	require.Len(t, calls, 2)
+	assert.NotEqual(t, calls[0], calls[1])
+}
+
+func TestWebhookProcessorRejectsPrivateAddresses(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	processor := newTestWebhookProcessor(t, false, server.URL)
+	err := processor.Process(context.Background(), &entity.Notification{ID: "id", UserEmail: "user@example.com"})
+
+	assert.ErrorIs(t, err, service.ErrPermanent)
+	assert.Empty(t, receiver.calls())
+}
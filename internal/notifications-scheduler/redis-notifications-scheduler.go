@@ -0,0 +1,207 @@
+package notifications_scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// claimScript returns expired leases to the schedule, then moves due notifications to leases.
+// Returns flat list of ID and payload pairs.
+var claimScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', now)
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('ZADD', KEYS[1], now, id)
+end
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now, 'LIMIT', 0, tonumber(ARGV[2]))
+local result = {}
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	local payload = redis.call('HGET', KEYS[3], id)
+	if payload then
+		redis.call('ZADD', KEYS[2], now + tonumber(ARGV[3]), id)
+		table.insert(result, id)
+		table.insert(result, payload)
+	end
+end
+return result
+`)
+
+// cancelScript removes notification only while it waits in the schedule, leased one is already being released
+var cancelScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 1 then
+	redis.call('HDEL', KEYS[2], ARGV[1])
+	return 1
+end
+return 0
+`)
+
+// scheduledNotification payload of scheduled notification, trace context isn't a part of notification JSON
+type scheduledNotification struct {
+	Notification *entity.Notification `json:"notification"`
+	TraceContext map[string]string    `json:"trace_context,omitempty"`
+}
+
+type RedisNotificationsScheduler struct {
+	client redis.UniversalClient
+}
+
+func NewRedisNotificationsScheduler(client redis.UniversalClient) *RedisNotificationsScheduler {
+	return &RedisNotificationsScheduler{client: client}
+}
+
+func (r *RedisNotificationsScheduler) Schedule(ctx context.Context, channel string, notification *entity.Notification) error {
+	if notification.SendAt == nil {
+		return errors.New("can`t schedule notification without send_at")
+	}
+
+	if notification.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		notification.ID = id
+	}
+
+	payload, err := json.Marshal(scheduledNotification{Notification: notification, TraceContext: notification.TraceContext})
+	if err != nil {
+		return fmt.Errorf("can`t marshal scheduled notification: %w", err)
+	}
+
+	// Повторное получение того же сообщения из Kafka перезаписывает запись, а не дублирует её
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, tools.GetScheduledNotificationsPayloadsKey(channel), notification.ID, payload)
+		pipe.ZAdd(ctx, tools.GetScheduledNotificationsKey(channel), redis.Z{Score: float64(notification.SendAt.UnixMilli()), Member: notification.ID})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can`t schedule notification %s: %w", notification.ID, err)
+	}
+
+	// Одинаковый ID могут запланировать несколько каналов, поэтому индекс хранит множество каналов
+	err = r.client.SAdd(ctx, tools.GetScheduledNotificationChannelsKey(notification.ID), channel).Err()
+	if err != nil {
+		return fmt.Errorf("can`t index scheduled notification %s: %w", notification.ID, err)
+	}
+
+	return nil
+}
+
+func (r *RedisNotificationsScheduler) Claim(ctx context.Context, channel string, limit int, lease time.Duration) ([]*entity.Notification, error) {
+	keys := []string{
+		tools.GetScheduledNotificationsKey(channel),
+		tools.GetScheduledNotificationsLeasesKey(channel),
+		tools.GetScheduledNotificationsPayloadsKey(channel),
+	}
+	result, err := claimScript.Run(ctx, r.client, keys, time.Now().UnixMilli(), limit, lease.Milliseconds()).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("can`t claim scheduled notifications of %s: %w", channel, err)
+	}
+
+	notifications := make([]*entity.Notification, 0, len(result)/2)
+	for i := 0; i+1 < len(result); i += 2 {
+		var payload scheduledNotification
+		err := json.Unmarshal([]byte(result[i+1]), &payload)
+		if err != nil || payload.Notification == nil {
+			// Битую запись не возвращаем в расписание, иначе она будет забираться бесконечно
+			slog.Error("Can`t parse scheduled notification, dropping it", slog.String("id", result[i]), slog.String("channel", channel))
+			if ackErr := r.Ack(ctx, channel, result[i]); ackErr != nil {
+				slog.Error("Can`t drop scheduled notification", slog.String("id", result[i]), slog.String("error", ackErr.Error()))
+			}
+			continue
+		}
+
+		payload.Notification.ID = result[i]
+		payload.Notification.TraceContext = payload.TraceContext
+		notifications = append(notifications, payload.Notification)
+	}
+
+	return notifications, nil
+}
+
+func (r *RedisNotificationsScheduler) Ack(ctx context.Context, channel string, id string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, tools.GetScheduledNotificationsLeasesKey(channel), id)
+		pipe.HDel(ctx, tools.GetScheduledNotificationsPayloadsKey(channel), id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can`t ack scheduled notification %s: %w", id, err)
+	}
+
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SRem(ctx, tools.GetScheduledNotificationChannelsKey(id), channel)
+		pipe.HDel(ctx, tools.REDIS_SCHEDULED_NOTIFICATIONS_INDEX, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can`t unindex scheduled notification %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// Cancel removes notification with the ID from schedules of all channels, which scheduled it
+func (r *RedisNotificationsScheduler) Cancel(ctx context.Context, id string) (bool, error) {
+	channels, err := r.client.SMembers(ctx, tools.GetScheduledNotificationChannelsKey(id)).Result()
+	if err != nil {
+		return false, fmt.Errorf("can`t find scheduled notification %s: %w", id, err)
+	}
+
+	// Уведомления, запланированные до появления множества каналов, есть только в старом индексе
+	legacyChannel, err := r.client.HGet(ctx, tools.REDIS_SCHEDULED_NOTIFICATIONS_INDEX, id).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("can`t find scheduled notification %s: %w", id, err)
+	}
+	if legacyChannel != "" && !slices.Contains(channels, legacyChannel) {
+		channels = append(channels, legacyChannel)
+	}
+
+	cancelledAny := false
+	for _, channel := range channels {
+		keys := []string{tools.GetScheduledNotificationsKey(channel), tools.GetScheduledNotificationsPayloadsKey(channel)}
+		cancelled, err := cancelScript.Run(ctx, r.client, keys, id).Int()
+		if err != nil {
+			return cancelledAny, fmt.Errorf("can`t cancel scheduled notification %s of %s: %w", id, channel, err)
+		}
+		if cancelled == 0 {
+			continue
+		}
+		cancelledAny = true
+
+		if err := r.client.SRem(ctx, tools.GetScheduledNotificationChannelsKey(id), channel).Err(); err != nil {
+			slog.Warn("Can`t unindex cancelled notification", slog.String("id", id), slog.String("error", err.Error()))
+		}
+	}
+
+	if legacyChannel != "" {
+		if err := r.client.HDel(ctx, tools.REDIS_SCHEDULED_NOTIFICATIONS_INDEX, id).Err(); err != nil {
+			slog.Warn("Can`t unindex cancelled notification", slog.String("id", id), slog.String("error", err.Error()))
+		}
+	}
+
+	return cancelledAny, nil
+}
+
+func newID() (string, error) {
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", fmt.Errorf("can`t generate notification ID: %w", err)
+	}
+
+	return hex.EncodeToString(randomBytes), nil
+}
@@ -0,0 +1,157 @@
+package notifications_scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestScheduler(t *testing.T) (*RedisNotificationsScheduler, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisNotificationsScheduler(client), client
+}
+
+func notificationAt(id string, sendAt time.Time) *entity.Notification {
+	return &entity.Notification{ID: id, UserEmail: "user@example.com", SendAt: &sendAt}
+}
+
+func claimedIDs(notifications []*entity.Notification) []string {
+	ids := make([]string, 0, len(notifications))
+	for _, notification := range notifications {
+		ids = append(ids, notification.ID)
+	}
+	return ids
+}
+
+func TestClaimLeasesDueNotifications(t *testing.T) {
+	ctx := context.Background()
+	scheduler, _ := newTestScheduler(t)
+	due := notificationAt("due", time.Now().Add(-time.Second))
+	due.TraceContext = map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	require.NoError(t, scheduler.Schedule(ctx, "email", due))
+	require.NoError(t, scheduler.Schedule(ctx, "email", notificationAt("later", time.Now().Add(time.Hour))))
+
+	claimed, err := scheduler.Claim(ctx, "email", 10, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, []string{"due"}, claimedIDs(claimed))
+	assert.Equal(t, due.TraceContext, claimed[0].TraceContext)
+	assert.Equal(t, "user@example.com", claimed[0].UserEmail)
+
+	// Арендованное уведомление не выдается повторно
+	claimed, err = scheduler.Claim(ctx, "email", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	claimed, err = scheduler.Claim(ctx, "sms", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "schedules of channels are separate")
+}
+
+func TestClaimReturnsNotificationWithExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	scheduler, _ := newTestScheduler(t)
+	require.NoError(t, scheduler.Schedule(ctx, "email", notificationAt("due", time.Now().Add(-time.Second))))
+
+	claimed, err := scheduler.Claim(ctx, "email", 10, time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	// Экземпляр, арендовавший уведомление, не подтвердил его
+	time.Sleep(5 * time.Millisecond)
+	claimed, err = scheduler.Claim(ctx, "email", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"due"}, claimedIDs(claimed))
+}
+
+func TestAckRemovesNotification(t *testing.T) {
+	ctx := context.Background()
+	scheduler, client := newTestScheduler(t)
+	require.NoError(t, scheduler.Schedule(ctx, "email", notificationAt("due", time.Now().Add(-time.Second))))
+
+	claimed, err := scheduler.Claim(ctx, "email", 10, time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.NoError(t, scheduler.Ack(ctx, "email", "due"))
+
+	time.Sleep(5 * time.Millisecond)
+	claimed, err = scheduler.Claim(ctx, "email", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+	assert.Zero(t, client.Exists(ctx, tools.GetScheduledNotificationsPayloadsKey("email"), tools.GetScheduledNotificationChannelsKey("due")).Val())
+}
+
+func TestCancelRemovesNotificationFromAllChannels(t *testing.T) {
+	ctx := context.Background()
+	scheduler, _ := newTestScheduler(t)
+	sendAt := time.Now().Add(-time.Second)
+	require.NoError(t, scheduler.Schedule(ctx, "email", notificationAt("42", sendAt)))
+	require.NoError(t, scheduler.Schedule(ctx, "sms", notificationAt("42", sendAt)))
+
+	cancelled, err := scheduler.Cancel(ctx, "42")
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+
+	for _, channel := range []string{"email", "sms"} {
+		claimed, err := scheduler.Claim(ctx, channel, 10, time.Minute)
+		require.NoError(t, err)
+		assert.Empty(t, claimed, channel)
+	}
+
+	cancelled, err = scheduler.Cancel(ctx, "42")
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+}
+
+func TestCancelSkipsLeasedNotification(t *testing.T) {
+	ctx := context.Background()
+	scheduler, _ := newTestScheduler(t)
+	require.NoError(t, scheduler.Schedule(ctx, "email", notificationAt("42", time.Now().Add(-time.Second))))
+
+	_, err := scheduler.Claim(ctx, "email", 10, time.Minute)
+	require.NoError(t, err)
+
+	cancelled, err := scheduler.Cancel(ctx, "42")
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+}
+
+func TestCancelFindsNotificationInLegacyIndex(t *testing.T) {
+	ctx := context.Background()
+	scheduler, client := newTestScheduler(t)
+	require.NoError(t, scheduler.Schedule(ctx, "email", notificationAt("42", time.Now().Add(time.Hour))))
+	// Уведомление запланировано до появления множества каналов
+	client.Del(ctx, tools.GetScheduledNotificationChannelsKey("42"))
+	client.HSet(ctx, tools.REDIS_SCHEDULED_NOTIFICATIONS_INDEX, "42", "email")
+
+	cancelled, err := scheduler.Cancel(ctx, "42")
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.False(t, client.HExists(ctx, tools.REDIS_SCHEDULED_NOTIFICATIONS_INDEX, "42").Val())
+}
+
+func TestScheduleRequiresSendAt(t *testing.T) {
+	scheduler, _ := newTestScheduler(t)
+
+	err := scheduler.Schedule(context.Background(), "email", &entity.Notification{})
+
+	assert.Error(t, err)
+}
+
+func TestScheduleGeneratesID(t *testing.T) {
+	scheduler, _ := newTestScheduler(t)
+	notification := notificationAt("", time.Now().Add(time.Hour))
+
+	require.NoError(t, scheduler.Schedule(context.Background(), "email", notification))
+
+	assert.Len(t, notification.ID, 32)
+}
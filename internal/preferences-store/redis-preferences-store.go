@@ -0,0 +1,142 @@
+package preferences_store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	ruleEnabled  = "1"
+	ruleDisabled = "0"
+)
+
+// RedisPreferencesStore keeps rules of the user in one hash, field is "<category>:<channel>"
+type RedisPreferencesStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisPreferencesStore(client redis.UniversalClient) *RedisPreferencesStore {
+	return &RedisPreferencesStore{client: client}
+}
+
+func (r *RedisPreferencesStore) Get(ctx context.Context, userEmail string) (*entity.Preferences, error) {
+	var rulesCmd *redis.MapStringStringCmd
+	var quietHoursCmd *redis.StringCmd
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		rulesCmd = pipe.HGetAll(ctx, tools.GetUserPreferencesKey(userEmail))
+		quietHoursCmd = pipe.Get(ctx, tools.GetUserQuietHoursKey(userEmail))
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("can`t read preferences of %s: %w", userEmail, err)
+	}
+
+	preferences := &entity.Preferences{UserEmail: userEmail, Rules: parseRules(userEmail, rulesCmd.Val())}
+	if rawQuietHours, err := quietHoursCmd.Bytes(); err == nil {
+		var quietHours entity.QuietHours
+		if err := json.Unmarshal(rawQuietHours, &quietHours); err != nil {
+			slog.Warn("Skip malformed quiet hours", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+		} else {
+			preferences.QuietHours = &quietHours
+		}
+	}
+
+	return preferences, nil
+}
+
+func parseRules(userEmail string, fields map[string]string) []entity.PreferenceRule {
+	rules := make([]entity.PreferenceRule, 0, len(fields))
+	for field, value := range fields {
+		category, channel, ok := strings.Cut(field, ":")
+		if !ok {
+			slog.Warn("Skip malformed preference rule", slog.String("user_email", userEmail), slog.String("field", field))
+			continue
+		}
+		rules = append(rules, entity.PreferenceRule{Category: category, Channel: channel, Enabled: value == ruleEnabled})
+	}
+
+	return rules
+}
+
+func (r *RedisPreferencesStore) Set(ctx context.Context, userEmail string, rule entity.PreferenceRule) error {
+	err := r.client.HSet(ctx, tools.GetUserPreferencesKey(userEmail), ruleField(rule.Category, rule.Channel), ruleValue(rule.Enabled)).Err()
+	if err != nil {
+		return fmt.Errorf("can`t save preference of %s: %w", userEmail, err)
+	}
+
+	return nil
+}
+
+func (r *RedisPreferencesStore) Replace(ctx context.Context, userEmail string, rules []entity.PreferenceRule) error {
+	key := tools.GetUserPreferencesKey(userEmail)
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		if len(rules) == 0 {
+			return nil
+		}
+
+		values := make([]any, 0, len(rules)*2)
+		for _, rule := range rules {
+			values = append(values, ruleField(rule.Category, rule.Channel), ruleValue(rule.Enabled))
+		}
+		pipe.HSet(ctx, key, values...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can`t replace preferences of %s: %w", userEmail, err)
+	}
+
+	return nil
+}
+
+func (r *RedisPreferencesStore) Delete(ctx context.Context, userEmail string, category string, channel string) (bool, error) {
+	deleted, err := r.client.HDel(ctx, tools.GetUserPreferencesKey(userEmail), ruleField(category, channel)).Result()
+	if err != nil {
+		return false, fmt.Errorf("can`t delete preference of %s: %w", userEmail, err)
+	}
+
+	return deleted > 0, nil
+}
+
+func (r *RedisPreferencesStore) SetQuietHours(ctx context.Context, userEmail string, quietHours entity.QuietHours) error {
+	payload, err := json.Marshal(quietHours)
+	if err != nil {
+		return fmt.Errorf("can`t marshal quiet hours: %w", err)
+	}
+
+	err = r.client.Set(ctx, tools.GetUserQuietHoursKey(userEmail), payload, 0).Err()
+	if err != nil {
+		return fmt.Errorf("can`t save quiet hours of %s: %w", userEmail, err)
+	}
+
+	return nil
+}
+
+func (r *RedisPreferencesStore) DeleteQuietHours(ctx context.Context, userEmail string) (bool, error) {
+	deleted, err := r.client.Del(ctx, tools.GetUserQuietHoursKey(userEmail)).Result()
+	if err != nil {
+		return false, fmt.Errorf("can`t delete quiet hours of %s: %w", userEmail, err)
+	}
+
+	return deleted > 0, nil
+}
+
+func ruleField(category string, channel string) string {
+	return fmt.Sprintf("%s:%s", category, channel)
+}
+
+func ruleValue(enabled bool) string {
+	if enabled {
+		return ruleEnabled
+	}
+	return ruleDisabled
+}
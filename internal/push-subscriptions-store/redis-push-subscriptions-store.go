@@ -0,0 +1,69 @@
+package push_subscriptions_store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisPushSubscriptionsStore keeps Web Push subscriptions of the user in one hash, field is subscription ID
+type RedisPushSubscriptionsStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisPushSubscriptionsStore(client redis.UniversalClient) *RedisPushSubscriptionsStore {
+	return &RedisPushSubscriptionsStore{client: client}
+}
+
+func (r *RedisPushSubscriptionsStore) List(ctx context.Context, userEmail string) ([]entity.PushSubscription, error) {
+	fields, err := r.client.HGetAll(ctx, tools.GetUserPushSubscriptionsKey(userEmail)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("can`t read push subscriptions of %s: %w", userEmail, err)
+	}
+
+	subscriptions := make([]entity.PushSubscription, 0, len(fields))
+	for id, payload := range fields {
+		var subscription entity.PushSubscription
+		if err := json.Unmarshal([]byte(payload), &subscription); err != nil {
+			slog.Warn("Skip malformed push subscription", slog.String("user_email", userEmail), slog.String("id", id), slog.String("error", err.Error()))
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+
+	return subscriptions, nil
+}
+
+func (r *RedisPushSubscriptionsStore) Save(ctx context.Context, subscription entity.PushSubscription) error {
+	payload, err := json.Marshal(subscription)
+	if err != nil {
+		return fmt.Errorf("can`t marshal push subscription: %w", err)
+	}
+
+	err = r.client.HSet(ctx, tools.GetUserPushSubscriptionsKey(subscription.UserEmail), subscription.ID, payload).Err()
+	if err != nil {
+		return fmt.Errorf("can`t save push subscription of %s: %w", subscription.UserEmail, err)
+	}
+
+	return nil
+}
+
+func (r *RedisPushSubscriptionsStore) Delete(ctx context.Context, userEmail string, id string) (bool, error) {
+	deleted, err := r.client.HDel(ctx, tools.GetUserPushSubscriptionsKey(userEmail), id).Result()
+	if err != nil {
+		return false, fmt.Errorf("can`t delete push subscription of %s: %w", userEmail, err)
+	}
+
+	return deleted > 0, nil
+}
@@ -0,0 +1,70 @@
+package rate_limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// allowScript fixed window counters: hits are taken only when no limit is exceeded.
+// Returns {0, 0} when allowed or {index of exceeded limit starting at 1, milliseconds until its window ends}.
+var allowScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	local hits = tonumber(redis.call('GET', key) or '0')
+	if hits >= tonumber(ARGV[i * 2 - 1]) then
+		local ttl = redis.call('PTTL', key)
+		if ttl < 0 then
+			redis.call('PEXPIRE', key, ARGV[i * 2])
+			ttl = tonumber(ARGV[i * 2])
+		end
+		return {i, ttl}
+	end
+end
+for i, key in ipairs(KEYS) do
+	if redis.call('INCR', key) == 1 then
+		redis.call('PEXPIRE', key, ARGV[i * 2])
+	end
+end
+return {0, 0}
+`)
+
+// RedisRateLimiter fixed window rate limiter shared by all instances
+type RedisRateLimiter struct {
+	client redis.UniversalClient
+}
+
+func NewRedisRateLimiter(client redis.UniversalClient) *RedisRateLimiter {
+	return &RedisRateLimiter{client: client}
+}
+
+// Allow checks limits in one script, in Redis Cluster their keys must share hash tag
+func (r *RedisRateLimiter) Allow(ctx context.Context, limits []entity.RateLimit) (bool, int, time.Duration, error) {
+	if len(limits) == 0 {
+		return true, 0, 0, nil
+	}
+
+	keys := make([]string, 0, len(limits))
+	args := make([]interface{}, 0, len(limits)*2)
+	for _, limit := range limits {
+		keys = append(keys, fmt.Sprintf("rate-limit--%s", limit.Key))
+		args = append(args, limit.Limit, limit.Period.Milliseconds())
+	}
+
+	result, err := allowScript.Run(ctx, r.client, keys, args...).Int64Slice()
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("can`t check rate limits: %w", err)
+	}
+	if len(result) != 2 {
+		return false, 0, 0, fmt.Errorf("unexpected result of rate limits check: %v", result)
+	}
+
+	if result[0] > 0 {
+		return false, int(result[0]) - 1, time.Duration(result[1]) * time.Millisecond, nil
+	}
+
+	return true, 0, 0, nil
+}
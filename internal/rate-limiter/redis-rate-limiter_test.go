@@ -0,0 +1,74 @@
+package rate_limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func limitsOf(userEmail string) []entity.RateLimit {
+	return []entity.RateLimit{
+		{Key: "{email}:recipient:" + userEmail, Limit: 2, Period: time.Minute},
+		{Key: "{email}:channel", Limit: 3, Period: time.Hour},
+	}
+}
+
+func TestAllowTakesHitsOnlyWhenNoLimitIsExceeded(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	limiter := NewRedisRateLimiter(client)
+
+	for range 2 {
+		allowed, _, _, err := limiter.Allow(ctx, limitsOf("first@example.com"))
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, exceeded, wait, err := limiter.Allow(ctx, limitsOf("first@example.com"))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 0, exceeded)
+	assert.InDelta(t, time.Minute, wait, float64(time.Second))
+
+	// Отклоненное уведомление не расходует лимит канала
+	allowed, _, _, err = limiter.Allow(ctx, limitsOf("second@example.com"))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, exceeded, wait, err = limiter.Allow(ctx, limitsOf("third@example.com"))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 1, exceeded)
+	assert.InDelta(t, time.Hour, wait, float64(time.Second))
+	assert.False(t, server.Exists("rate-limit--{email}:recipient:third@example.com"), "hit of recipient limit isn't taken")
+}
+
+func TestAllowResetsWindow(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	limiter := NewRedisRateLimiter(client)
+	limits := []entity.RateLimit{{Key: "{sms}:channel", Limit: 1, Period: time.Minute}}
+
+	allowed, _, _, err := limiter.Allow(ctx, limits)
+	require.NoError(t, err)
+	require.True(t, allowed)
+	allowed, _, _, err = limiter.Allow(ctx, limits)
+	require.NoError(t, err)
+	require.False(t, allowed)
+
+	server.FastForward(time.Minute)
+
+	allowed, _, _, err = limiter.Allow(ctx, limits)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
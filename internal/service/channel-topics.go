@@ -0,0 +1,46 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+)
+
+// ChannelTopics maps key of every channel notification can be routed to onto its topic.
+// Channels are taken from config file, without it they are channels of env topics. Channel ws is served by
+// ws-notifications, so it's always known unless config file declares it.
+func ChannelTopics(cfg *config.Config) (map[string]string, error) {
+	topics := map[string]string{entity.ChannelWS: cfg.Kafka.TopicWSNotifications}
+
+	if len(cfg.Channels) == 0 {
+		topics[entity.ChannelEmail] = cfg.Kafka.TopicEmailNotifications
+		topics[entity.ChannelPush] = cfg.Kafka.TopicPushNotifications
+		topics[entity.ChannelWebhook] = cfg.Kafka.TopicWebhookNotifications
+		topics[entity.ChannelSms] = cfg.Kafka.TopicSmsNotifications
+		topics[entity.ChannelTelegram] = cfg.Kafka.TopicTelegramNotifications
+		topics[entity.ChannelSlack] = cfg.Kafka.TopicSlackNotifications
+		return topics, nil
+	}
+
+	declared := make(map[string]struct{}, len(cfg.Channels))
+	for _, definition := range cfg.Channels {
+		if definition.Key == "" || definition.Topic == "" {
+			return nil, fmt.Errorf("key and topic of channel %q must be set", definition.Name)
+		}
+		if _, ok := declared[definition.Key]; ok {
+			return nil, fmt.Errorf("channel key %q is declared twice", definition.Key)
+		}
+		declared[definition.Key] = struct{}{}
+		topics[definition.Key] = definition.Topic
+	}
+
+	return topics, nil
+}
+
+// ChannelKeys returns sorted keys of channel topics
+func ChannelKeys(topics map[string]string) []string {
+	return slices.Sorted(maps.Keys(topics))
+}
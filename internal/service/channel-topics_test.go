@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelTopicsFromConfigFile(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Kafka.TopicWSNotifications = "notifications_ws"
+	cfg.Kafka.TopicSlackNotifications = "notifications_slack"
+	cfg.Channels = []config.ChannelDefinition{
+		{Name: "Email processor", Key: "email", Topic: "notifications_email"},
+		{Name: "Ops alerts", Key: "slack", Topic: "notifications_ops"},
+		{Name: "Pager", Key: "pager", Topic: "notifications_pager"},
+	}
+
+	topics, err := ChannelTopics(cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		entity.ChannelWS:    "notifications_ws",
+		entity.ChannelEmail: "notifications_email",
+		entity.ChannelSlack: "notifications_ops",
+		"pager":             "notifications_pager",
+	}, topics)
+	assert.Equal(t, []string{"email", "pager", "slack", "ws"}, ChannelKeys(topics))
+}
+
+func TestChannelTopicsFromEnv(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Kafka.TopicEmailNotifications = "notifications_email"
+	cfg.Kafka.TopicSlackNotifications = "notifications_slack"
+
+	topics, err := ChannelTopics(cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"email", "push", "slack", "sms", "telegram", "webhook", "ws"}, ChannelKeys(topics))
+	assert.Equal(t, "notifications_slack", topics[entity.ChannelSlack])
+}
+
+func TestChannelTopicsRejectsDuplicateKeys(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Channels = []config.ChannelDefinition{
+		{Name: "Email processor", Key: "email", Topic: "notifications_email"},
+		{Name: "Marketing email", Key: "email", Topic: "notifications_marketing"},
+	}
+
+	_, err := ChannelTopics(cfg)
+
+	assert.Error(t, err)
+}
+
+func TestParseFallbackChainsUsesKnownChannels(t *testing.T) {
+	chains, err := ParseFallbackChains(map[string]string{"critical": "ws/30s|pager"}, []string{"pager", "ws"})
+	require.NoError(t, err)
+	assert.Equal(t, []entity.FallbackStep{{Channel: "ws", Timeout: 30 * time.Second}, {Channel: "pager"}}, chains["critical"].Steps)
+
+	_, err = ParseFallbackChains(map[string]string{"critical": "ws|sms"}, []string{"pager", "ws"})
+	assert.Error(t, err)
+}
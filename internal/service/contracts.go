@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/mwsbkru/evrone-go-final/internal/entity"
 )
@@ -21,3 +22,130 @@ type NotificationsProcessor interface {
 type DeadNotificationsProcessor interface {
 	Process(notification *entity.Notification, err error) error
 }
+
+type NotificationsInbox interface {
+	List(ctx context.Context, userEmail string, cursor string, limit int64, ascending bool) ([]entity.InboxNotification, string, error)
+	UnreadCount(ctx context.Context, userEmail string) (int64, error)
+	MarkRead(ctx context.Context, userEmail string, notificationID string) error
+	Delete(ctx context.Context, userEmail string, notificationID string) (bool, error)
+}
+
+// WsConnectionKicker is called when connection of the user with token other than ownerToken must be terminated
+type WsConnectionKicker func(userEmail string, ownerToken string)
+
+type WsConnectionsRegistry interface {
+	Subscribe(kicker WsConnectionKicker)
+	Run(ctx context.Context)
+	Acquire(ctx context.Context, userEmail string) (string, error)
+	Release(ctx context.Context, userEmail string, token string)
+	// Online tells whether any instance holds WS connection of the user
+	Online(ctx context.Context, userEmail string) (bool, error)
+}
+
+// NotificationsScheduler keeps notifications of channels until they are due
+type NotificationsScheduler interface {
+	Schedule(ctx context.Context, channel string, notification *entity.Notification) error
+	// Claim leases due notifications of the channel, they return to the schedule unless acked before lease ends
+	Claim(ctx context.Context, channel string, limit int, lease time.Duration) ([]*entity.Notification, error)
+	Ack(ctx context.Context, channel string, id string) error
+	// Cancel removes the ID from schedules of all channels,
+	// it returns false when notification isn't scheduled (unknown, already released or cancelled)
+	Cancel(ctx context.Context, id string) (bool, error)
+}
+
+// PreferencesStore keeps notification preferences of users
+type PreferencesStore interface {
+	Get(ctx context.Context, userEmail string) (*entity.Preferences, error)
+	Set(ctx context.Context, userEmail string, rule entity.PreferenceRule) error
+	// Replace removes all rules of the user and saves the given ones
+	Replace(ctx context.Context, userEmail string, rules []entity.PreferenceRule) error
+	Delete(ctx context.Context, userEmail string, category string, channel string) (bool, error)
+	SetQuietHours(ctx context.Context, userEmail string, quietHours entity.QuietHours) error
+	DeleteQuietHours(ctx context.Context, userEmail string) (bool, error)
+}
+
+// NotificationsDigest collects notifications into windows
+type NotificationsDigest interface {
+	Add(ctx context.Context, window entity.DigestWindow, notification *entity.Notification) error
+	// Claim leases closed windows, they return to the queue unless acked before lease ends
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.DigestWindow, error)
+	Collect(ctx context.Context, window entity.DigestWindow, limit int) (*entity.Digest, error)
+	// Ack removes first collected notifications of the window, the window is done once none are left
+	Ack(ctx context.Context, window entity.DigestWindow, collected int) error
+}
+
+// DigestRenderer renders digest into email subject and HTML body
+type DigestRenderer interface {
+	Render(digest *entity.Digest) (string, string, error)
+}
+
+// NotificationsStatusTracker keeps statuses of notification in every channel it was sent to
+type NotificationsStatusTracker interface {
+	Track(ctx context.Context, correlationID string, channel string, status entity.ChannelStatus) error
+	// TrackInitial sets status only if the channel doesn't have one, returns false otherwise
+	TrackInitial(ctx context.Context, correlationID string, channel string, status entity.ChannelStatus) (bool, error)
+	// Untrack removes status of the channel, if it's still the given one
+	Untrack(ctx context.Context, correlationID string, channel string, status entity.ChannelStatus) error
+	Get(ctx context.Context, correlationID string) (map[string]entity.ChannelStatus, error)
+}
+
+// WsPresence tells whether user has live WS connection on any instance
+type WsPresence interface {
+	Online(ctx context.Context, userEmail string) (bool, error)
+}
+
+// NotificationsRouter sends notification to the topic of one channel
+type NotificationsRouter interface {
+	Route(ctx context.Context, notification *entity.Notification, channel string) error
+}
+
+// FallbackChainsStore keeps notifications moving through fallback chains until their next check
+type FallbackChainsStore interface {
+	// Create saves state of new chain, returns false if the chain of the notification is already saved
+	Create(ctx context.Context, state entity.FallbackState, checkAt time.Time) (bool, error)
+	Save(ctx context.Context, state entity.FallbackState, checkAt time.Time) error
+	// Claim leases states due for check, they return to the queue unless saved or deleted before lease ends
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.FallbackState, error)
+	Delete(ctx context.Context, correlationID string) error
+}
+
+// WebhooksStore keeps webhook subscriptions of users
+type WebhooksStore interface {
+	List(ctx context.Context, userEmail string) ([]entity.WebhookSubscription, error)
+	Save(ctx context.Context, subscription entity.WebhookSubscription) error
+	Delete(ctx context.Context, userEmail string, id string) (bool, error)
+}
+
+// PushSubscriptionsStore keeps Web Push subscriptions of users
+type PushSubscriptionsStore interface {
+	List(ctx context.Context, userEmail string) ([]entity.PushSubscription, error)
+	Save(ctx context.Context, subscription entity.PushSubscription) error
+	Delete(ctx context.Context, userEmail string, id string) (bool, error)
+}
+
+// SmsSender sends SMS to the phone in international format, it returns IDs of sent messages
+type SmsSender interface {
+	// Send returns message IDs of submitted parts, on error too. Error after the first part is permanent,
+	// retry would send submitted parts again.
+	Send(ctx context.Context, phone string, text string) ([]string, error)
+}
+
+// SmsMessagesStore binds message IDs of sent SMS to notifications, so delivery receipts can update their status
+type SmsMessagesStore interface {
+	// Save binds submitted parts to the notification, parts is number of parts of the whole message
+	Save(ctx context.Context, messageIDs []string, parts int, correlationID string) error
+	Get(ctx context.Context, messageID string) (string, bool, error)
+	// SaveState records final state of the part and returns states of all parts of the notification
+	SaveState(ctx context.Context, correlationID string, messageID string, state string) (entity.SmsParts, error)
+}
+
+type HealthChecker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type RateLimiter interface {
+	// Allow atomically takes one hit of every limit, but only when none of them is exceeded.
+	// Otherwise nothing is counted, and index of the exceeded limit with time left until it resets is returned.
+	Allow(ctx context.Context, limits []entity.RateLimit) (bool, int, time.Duration, error)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotificationExpired notification wasn't delivered before its expires_at
+	ErrNotificationExpired = errors.New("notification expired")
+	// ErrNotificationSuppressed user opted out of the notification
+	ErrNotificationSuppressed = errors.New("notification suppressed by user preferences")
+	// ErrPermanent processor wraps errors, which won't go away on retry, notification isn't retried then
+	ErrPermanent = errors.New("permanent failure")
+)
+
+// RetryAfterError transient failure, provider asked not to retry before After
+type RetryAfterError struct {
+	After time.Duration
+	Err   error
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// Reasons of passing notification to dead notifications processor
+const (
+	DeadReasonExpired     = "expired"
+	DeadReasonSuppressed  = "suppressed"
+	DeadReasonRateLimited = "rate_limited"
+	DeadReasonShutdown    = "shutdown"
+	DeadReasonPermanent   = "permanent"
+	DeadReasonFailed      = "failed"
+)
+
+// DeadNotificationReason classifies error notification was passed to dead notifications processor with
+func DeadNotificationReason(err error) string {
+	switch {
+	case errors.Is(err, ErrNotificationExpired):
+		return DeadReasonExpired
+	case errors.Is(err, ErrNotificationSuppressed):
+		return DeadReasonSuppressed
+	case errors.Is(err, ErrRateLimited):
+		return DeadReasonRateLimited
+	case errors.Is(err, ErrRetryAbortedByShutdown):
+		return DeadReasonShutdown
+	case errors.Is(err, ErrPermanent):
+		return DeadReasonPermanent
+	default:
+		return DeadReasonFailed
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadNotificationReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		reason string
+	}{
+		{name: "expired", err: fmt.Errorf("%w at 2026-01-01T12:00:00Z", ErrNotificationExpired), reason: DeadReasonExpired},
+		{name: "suppressed", err: fmt.Errorf("%w: category %q", ErrNotificationSuppressed, "marketing"), reason: DeadReasonSuppressed},
+		{name: "rate limited", err: fmt.Errorf("%w: channel limit", ErrRateLimited), reason: DeadReasonRateLimited},
+		{name: "shutdown wins over cause", err: fmt.Errorf("%w: %w", ErrRetryAbortedByShutdown, errors.New("SMTP relay is down")), reason: DeadReasonShutdown},
+		{name: "permanent", err: fmt.Errorf("invalid phone: %w", ErrPermanent), reason: DeadReasonPermanent},
+		{name: "provider asked to wait", err: &RetryAfterError{Err: errors.New("too many requests")}, reason: DeadReasonFailed},
+		{name: "other failure", err: errors.New("SMTP relay is down"), reason: DeadReasonFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.reason, DeadNotificationReason(tt.err))
+		})
+	}
+}
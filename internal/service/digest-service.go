@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+)
+
+const digestClaimBatchSize = 100
+
+// DigestService sends digests of closed windows through the email channel
+type DigestService struct {
+	cfg      *config.Config
+	digest   NotificationsDigest
+	renderer DigestRenderer
+	channel  *NotificationsChannel
+}
+
+func NewDigestService(cfg *config.Config, digest NotificationsDigest, renderer DigestRenderer, channel *NotificationsChannel) *DigestService {
+	return &DigestService{cfg: cfg, digest: digest, renderer: renderer, channel: channel}
+}
+
+func (d *DigestService) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(max(d.cfg.Digest.PollIntervalSeconds, 1)) * time.Second)
+	defer ticker.Stop()
+
+	slog.Info("Start notifications digest")
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Terminated notifications digest")
+			return
+		case <-ticker.C:
+			d.sendClosed(ctx)
+		}
+	}
+}
+
+func (d *DigestService) sendClosed(ctx context.Context) {
+	lease := time.Duration(max(d.cfg.Digest.LeaseSeconds, 1)) * time.Second
+
+	for ctx.Err() == nil {
+		windows, err := d.digest.Claim(ctx, digestClaimBatchSize, lease)
+		if err != nil {
+			slog.Error("Can`t claim digest windows", slog.String("error", err.Error()))
+			return
+		}
+
+		for _, window := range windows {
+			err := d.send(ctx, window)
+			if err != nil {
+				slog.Error("Can`t send digest", slog.String("user_email", window.UserEmail), slog.String("category", window.Category), slog.String("error", err.Error()))
+			}
+		}
+
+		if len(windows) < digestClaimBatchSize {
+			return
+		}
+	}
+}
+
+// send acks digest before passing it to the email channel, window stays leased if the channel is stopping.
+// Once acked, digest belongs to the channel, it's delivered or dead lettered there.
+func (d *DigestService) send(ctx context.Context, window entity.DigestWindow) error {
+	digest, err := d.digest.Collect(ctx, window, max(d.cfg.Digest.MaxItems, 1))
+	if err != nil {
+		return err
+	}
+
+	if len(digest.Notifications) == 0 {
+		return d.digest.Ack(context.WithoutCancel(ctx), window, digest.Total)
+	}
+
+	subject, body, err := d.renderer.Render(digest)
+	if err != nil {
+		return err
+	}
+
+	notification := &entity.Notification{
+		ID:        fmt.Sprintf("digest-%s-%s-%d", window.UserEmail, window.Category, window.End.Unix()),
+		UserEmail: window.UserEmail,
+		Subject:   subject,
+		Body:      body,
+		Category:  window.Category,
+		Digest:    true,
+	}
+	if !d.channel.startProcessing() {
+		return nil
+	}
+
+	err = d.digest.Ack(context.WithoutCancel(ctx), window, digest.Total)
+	if err != nil {
+		// Окно остаётся в аренде, дайджест будет отправлен после её окончания
+		d.channel.inFlight.Done()
+		return err
+	}
+
+	slog.Info("Digest released", slog.String("process channel", d.channel.Name), slog.String("id", notification.ID))
+	d.channel.enqueue(notification)
+	return nil
+}
+
+// digestWindowEnd returns end of the current window, windows are aligned to UTC hours and days
+func digestWindowEnd(window string, now time.Time) (time.Time, bool) {
+	now = now.UTC()
+	switch window {
+	case entity.DigestWindowHourly:
+		return now.Truncate(time.Hour).Add(time.Hour), true
+	case entity.DigestWindowDaily:
+		return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC), true
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+)
+
+const fallbackClaimBatchSize = 100
+
+// Outcomes of fallback step
+const (
+	fallbackPending = iota
+	fallbackDelivered
+	fallbackFailed
+)
+
+// FallbackCoordinator watches delivery outcome of the current step of fallback chain
+// and escalates notification to the next channel, when the step failed or timed out
+type FallbackCoordinator struct {
+	cfg           *config.Config
+	chains        map[string]entity.FallbackChain
+	store         FallbackChainsStore
+	statusTracker NotificationsStatusTracker
+	presence      WsPresence
+}
+
+func NewFallbackCoordinator(cfg *config.Config, chains map[string]entity.FallbackChain, store FallbackChainsStore, statusTracker NotificationsStatusTracker, presence WsPresence) *FallbackCoordinator {
+	return &FallbackCoordinator{cfg: cfg, chains: chains, store: store, statusTracker: statusTracker, presence: presence}
+}
+
+// Chain returns fallback chain by name
+func (f *FallbackCoordinator) Chain(name string) (entity.FallbackChain, bool) {
+	chain, ok := f.chains[name]
+	return chain, ok
+}
+
+// Start watches notification, which was already routed to the first step of the chain.
+// Chain already in progress is kept, so redelivered request doesn't restart it.
+func (f *FallbackCoordinator) Start(ctx context.Context, notification *entity.Notification, chain entity.FallbackChain) error {
+	state := entity.FallbackState{Notification: notification, Chain: chain, StepStarted: time.Now()}
+	created, err := f.store.Create(ctx, state, f.nextCheck(state))
+	if err != nil {
+		return fmt.Errorf("can`t start fallback chain: %w", err)
+	}
+	if !created {
+		slog.Info("Fallback chain already started", slog.String("correlation_id", notification.CorrelationID), slog.String("chain", chain.Name))
+	}
+
+	return nil
+}
+
+func (f *FallbackCoordinator) Run(ctx context.Context, router NotificationsRouter) {
+	ticker := time.NewTicker(f.pollInterval())
+	defer ticker.Stop()
+
+	slog.Info("Start fallback coordinator")
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Terminated fallback coordinator")
+			return
+		case <-ticker.C:
+			f.checkDue(ctx, router)
+		}
+	}
+}
+
+func (f *FallbackCoordinator) checkDue(ctx context.Context, router NotificationsRouter) {
+	lease := time.Duration(max(f.cfg.Fallback.LeaseSeconds, 1)) * time.Second
+
+	for ctx.Err() == nil {
+		states, err := f.store.Claim(ctx, fallbackClaimBatchSize, lease)
+		if err != nil {
+			slog.Error("Can`t claim fallback chains", slog.String("error", err.Error()))
+			return
+		}
+
+		for _, state := range states {
+			err := f.advance(ctx, router, state)
+			if err != nil {
+				slog.Error("Can`t advance fallback chain", slog.String("correlation_id", state.Notification.CorrelationID), slog.String("error", err.Error()))
+			}
+		}
+
+		if len(states) < fallbackClaimBatchSize {
+			return
+		}
+	}
+}
+
+func (f *FallbackCoordinator) advance(ctx context.Context, router NotificationsRouter, state entity.FallbackState) error {
+	correlationID := state.Notification.CorrelationID
+	step := state.Chain.Steps[state.Step]
+
+	outcome, err := f.outcome(ctx, state.Notification, step.Channel)
+	if err != nil {
+		return err
+	}
+
+	switch outcome {
+	case fallbackDelivered:
+		slog.Info("Fallback chain finished", slog.String("correlation_id", correlationID), slog.String("channel", step.Channel))
+		return f.store.Delete(ctx, correlationID)
+	case fallbackPending:
+		if !f.stepExpired(state, step) {
+			return f.store.Save(ctx, state, f.nextCheck(state))
+		}
+		if step.Timeout == 0 {
+			// Статус канала так и не стал окончательным, дальше его не отследить
+			slog.Warn("Fallback chain abandoned, channel outcome is unknown", slog.String("correlation_id", correlationID), slog.String("channel", step.Channel))
+			return f.store.Delete(ctx, correlationID)
+		}
+	}
+
+	if state.Step+1 >= len(state.Chain.Steps) {
+		slog.Warn("Fallback chain exhausted", slog.String("correlation_id", correlationID), slog.String("chain", state.Chain.Name))
+		return f.store.Delete(ctx, correlationID)
+	}
+
+	state.Step++
+	state.StepStarted = time.Now()
+	nextChannel := state.Chain.Steps[state.Step].Channel
+	slog.Info("Escalate notification to next channel of fallback chain", slog.String("correlation_id", correlationID), slog.String("from", step.Channel), slog.String("to", nextChannel))
+
+	err = router.Route(ctx, state.Notification, nextChannel)
+	if err != nil {
+		// Состояние остаётся в аренде и будет проверено повторно после её окончания
+		return err
+	}
+
+	return f.store.Save(ctx, state, f.nextCheck(state))
+}
+
+// outcome of the step: WS counts as delivered once user is online, the other channels report their statuses
+func (f *FallbackCoordinator) outcome(ctx context.Context, notification *entity.Notification, channel string) (int, error) {
+	statuses, err := f.statusTracker.Get(ctx, notification.CorrelationID)
+	if err != nil {
+		return fallbackPending, err
+	}
+
+	// Отписка от канала тоже переводит цепочку на следующий канал
+	status := statuses[channel].Status
+	if status == entity.StatusFailed || status == entity.StatusSuppressed {
+		return fallbackFailed, nil
+	}
+
+	if channel == entity.ChannelWS {
+		online, err := f.presence.Online(ctx, notification.UserEmail)
+		if err != nil {
+			return fallbackPending, err
+		}
+		if online && status == entity.StatusDelivered {
+			return fallbackDelivered, nil
+		}
+		return fallbackPending, nil
+	}
+
+	if status == entity.StatusDelivered || status == entity.StatusDigested {
+		return fallbackDelivered, nil
+	}
+
+	return fallbackPending, nil
+}
+
+// stepExpired tells whether the step exceeded its timeout, step without timeout waits while its status is kept
+func (f *FallbackCoordinator) stepExpired(state entity.FallbackState, step entity.FallbackStep) bool {
+	timeout := step.Timeout
+	if timeout == 0 {
+		timeout = time.Duration(max(f.cfg.NotificationStatusTTLHours, 1)) * time.Hour
+	}
+
+	return !time.Now().Before(state.StepStarted.Add(timeout))
+}
+
+func (f *FallbackCoordinator) nextCheck(state entity.FallbackState) time.Time {
+	nextCheck := time.Now().Add(f.pollInterval())
+
+	step := state.Chain.Steps[state.Step]
+	if step.Timeout > 0 {
+		deadline := state.StepStarted.Add(step.Timeout)
+		if deadline.Before(nextCheck) {
+			return deadline
+		}
+	}
+
+	return nextCheck
+}
+
+func (f *FallbackCoordinator) pollInterval() time.Duration {
+	return time.Duration(max(f.cfg.Fallback.PollIntervalSeconds, 1)) * time.Second
+}
+
+// ParseFallbackChains parses chains like ws/30s|push|email, steps are separated by | and timeout follows /.
+// Steps must use keys of known channels.
+func ParseFallbackChains(definitions map[string]string, channels []string) (map[string]entity.FallbackChain, error) {
+	chains := make(map[string]entity.FallbackChain, len(definitions))
+	for name, definition := range definitions {
+		chain := entity.FallbackChain{Name: name}
+		for _, rawStep := range strings.Split(definition, "|") {
+			channel, rawTimeout, hasTimeout := strings.Cut(strings.TrimSpace(rawStep), "/")
+			step := entity.FallbackStep{Channel: channel}
+			if !slices.Contains(channels, channel) {
+				return nil, fmt.Errorf("unknown channel %q in fallback chain %s", channel, name)
+			}
+
+			if hasTimeout {
+				timeout, err := time.ParseDuration(rawTimeout)
+				if err != nil || timeout <= 0 {
+					return nil, fmt.Errorf("invalid timeout %q in fallback chain %s", rawTimeout, name)
+				}
+				step.Timeout = timeout
+			}
+			chain.Steps = append(chain.Steps, step)
+		}
+		chains[name] = chain
+	}
+
+	return chains, nil
+}
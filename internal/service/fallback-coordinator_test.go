@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFallbackCoordinatorStartKeepsChainInProgress(t *testing.T) {
+	store := &MockFallbackChainsStore{}
+	store.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(false, nil).Once()
+	coordinator := NewFallbackCoordinator(&config.Config{}, nil, store, nil, nil)
+
+	chain := entity.FallbackChain{Name: "ws-then-email", Steps: []entity.FallbackStep{{Channel: entity.ChannelWS, Timeout: 30 * time.Second}, {Channel: entity.ChannelEmail}}}
+	err := coordinator.Start(context.Background(), &entity.Notification{CorrelationID: "corr"}, chain)
+
+	assert.NoError(t, err)
+	store.AssertExpectations(t)
+	store.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFallbackCoordinatorReadsPresenceOfWsChannel(t *testing.T) {
+	tracker := &MockNotificationsStatusTracker{}
+	tracker.On("Get", mock.Anything, "corr").Return(map[string]entity.ChannelStatus{
+		entity.ChannelWS: {Status: entity.StatusDelivered},
+	}, nil)
+
+	tests := []struct {
+		name     string
+		online   bool
+		expected int
+	}{
+		{name: "user is online", online: true, expected: fallbackDelivered},
+		{name: "user is offline", online: false, expected: fallbackPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := &MockWsConnectionsRegistry{}
+			registry.On("Online", mock.Anything, "user@example.com").Return(tt.online, nil).Once()
+			coordinator := NewFallbackCoordinator(&config.Config{}, nil, nil, tracker, registry)
+
+			outcome, err := coordinator.outcome(context.Background(), &entity.Notification{CorrelationID: "corr", UserEmail: "user@example.com"}, entity.ChannelWS)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, outcome)
+			registry.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+type HealthService struct {
+	checkers []HealthChecker
+}
+
+func NewHealthService(checkers []HealthChecker) *HealthService {
+	return &HealthService{checkers: checkers}
+}
+
+// Ready runs all dependency checks concurrently, service is ready when every check passes
+func (h *HealthService) Ready(ctx context.Context) entity.HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	report := entity.HealthReport{Status: entity.HealthStatusOK, Checks: make(map[string]string, len(h.checkers))}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, checker := range h.checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := entity.HealthStatusOK
+			if err := checker.Check(ctx); err != nil {
+				result = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[checker.Name()] = result
+			if result != entity.HealthStatusOK {
+				report.Status = entity.HealthStatusFail
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
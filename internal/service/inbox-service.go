@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+)
+
+const (
+	DefaultInboxPageSize = 20
+	MaxInboxPageSize     = 100
+)
+
+var (
+	ErrInvalidNotificationID = errors.New("invalid notification ID")
+	ErrNotificationNotFound  = errors.New("notification not found")
+)
+
+type InboxService struct {
+	inbox NotificationsInbox
+}
+
+func NewInboxService(inbox NotificationsInbox) *InboxService {
+	return &InboxService{inbox: inbox}
+}
+
+// List returns one page of user's inbox. Empty cursor means the first page, limit is clamped to MaxInboxPageSize.
+func (i *InboxService) List(ctx context.Context, userEmail string, cursor string, limit int64, ascending bool) (*entity.InboxPage, error) {
+	if cursor != "" {
+		if _, _, err := tools.ParseStreamID(cursor); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidNotificationID, err)
+		}
+	}
+
+	if limit <= 0 {
+		limit = DefaultInboxPageSize
+	}
+	if limit > MaxInboxPageSize {
+		limit = MaxInboxPageSize
+	}
+
+	items, nextCursor, err := i.inbox.List(ctx, userEmail, cursor, limit, ascending)
+	if err != nil {
+		return nil, fmt.Errorf("can`t list inbox: %w", err)
+	}
+
+	unreadCount, err := i.inbox.UnreadCount(ctx, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("can`t count unread notifications: %w", err)
+	}
+
+	return &entity.InboxPage{Items: items, NextCursor: nextCursor, UnreadCount: unreadCount}, nil
+}
+
+func (i *InboxService) UnreadCount(ctx context.Context, userEmail string) (int64, error) {
+	return i.inbox.UnreadCount(ctx, userEmail)
+}
+
+// MarkRead marks all notifications up to notificationID (inclusive) as read
+func (i *InboxService) MarkRead(ctx context.Context, userEmail string, notificationID string) error {
+	if _, _, err := tools.ParseStreamID(notificationID); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidNotificationID, err)
+	}
+
+	return i.inbox.MarkRead(ctx, userEmail, notificationID)
+}
+
+func (i *InboxService) Delete(ctx context.Context, userEmail string, notificationID string) error {
+	if _, _, err := tools.ParseStreamID(notificationID); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidNotificationID, err)
+	}
+
+	deleted, err := i.inbox.Delete(ctx, userEmail, notificationID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+)
+
+// keyedQueue serializes notifications with the same key, notifications with different keys are processed in parallel.
+// Key stays busy until its head notification is finished, so a retrying notification holds back later ones.
+type keyedQueue struct {
+	mu      sync.Mutex
+	pending map[string][]*entity.Notification
+	// ready keys have pending notifications and aren't processed by any worker
+	ready chan string
+	// slots limits amount of queued notifications
+	slots chan struct{}
+}
+
+func newKeyedQueue(size int) *keyedQueue {
+	size = max(size, 1)
+	return &keyedQueue{
+		pending: make(map[string][]*entity.Notification),
+		ready:   make(chan string, size),
+		slots:   make(chan struct{}, size),
+	}
+}
+
+// push blocks while the queue is full
+func (k *keyedQueue) push(ctx context.Context, key string, notification *entity.Notification) bool {
+	select {
+	case k.slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+
+	k.mu.Lock()
+	notifications, busy := k.pending[key]
+	k.pending[key] = append(notifications, notification)
+	k.mu.Unlock()
+
+	// Пока ключ занят, новое уведомление заберет воркер, обрабатывающий ключ
+	if !busy {
+		k.ready <- key
+	}
+	return true
+}
+
+// head returns the oldest notification of the key
+func (k *keyedQueue) head(key string) *entity.Notification {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.pending[key][0]
+}
+
+// done removes head of the key, key with pending notifications becomes ready again
+func (k *keyedQueue) done(key string) {
+	k.mu.Lock()
+	notifications := k.pending[key][1:]
+	if len(notifications) == 0 {
+		delete(k.pending, key)
+	} else {
+		k.pending[key] = notifications
+	}
+	k.mu.Unlock()
+
+	<-k.slots
+	if len(notifications) > 0 {
+		k.ready <- key
+	}
+}
+
+// retry makes the key ready again keeping its head
+func (k *keyedQueue) retry(key string) {
+	k.ready <- key
+}
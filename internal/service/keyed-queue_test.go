@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readyKey(t *testing.T, queue *keyedQueue) string {
+	select {
+	case key := <-queue.ready:
+		return key
+	case <-time.After(time.Second):
+		t.Fatal("no ready key")
+		return ""
+	}
+}
+
+func assertNoReadyKey(t *testing.T, queue *keyedQueue) {
+	select {
+	case key := <-queue.ready:
+		t.Fatalf("unexpected ready key %s", key)
+	default:
+	}
+}
+
+func TestKeyedQueueSerializesKey(t *testing.T) {
+	ctx := context.Background()
+	queue := newKeyedQueue(10)
+
+	require.True(t, queue.push(ctx, "a", &entity.Notification{ID: "a1"}))
+	require.True(t, queue.push(ctx, "a", &entity.Notification{ID: "a2"}))
+	require.True(t, queue.push(ctx, "b", &entity.Notification{ID: "b1"}))
+
+	// Ключ с несколькими уведомлениями готов только один раз
+	assert.Equal(t, "a", readyKey(t, queue))
+	assert.Equal(t, "b", readyKey(t, queue))
+	assertNoReadyKey(t, queue)
+
+	assert.Equal(t, "a1", queue.head("a").ID)
+	queue.retry("a")
+	assert.Equal(t, "a", readyKey(t, queue))
+	assert.Equal(t, "a1", queue.head("a").ID, "retry keeps the head")
+
+	queue.done("a")
+	assert.Equal(t, "a", readyKey(t, queue))
+	assert.Equal(t, "a2", queue.head("a").ID)
+
+	queue.done("a")
+	queue.done("b")
+	assertNoReadyKey(t, queue)
+	assert.Empty(t, queue.pending)
+}
+
+func TestKeyedQueueBlocksWhenFull(t *testing.T) {
+	queue := newKeyedQueue(1)
+	require.True(t, queue.push(context.Background(), "a", &entity.Notification{ID: "a1"}))
+
+	pushed := make(chan bool)
+	go func() {
+		pushed <- queue.push(context.Background(), "b", &entity.Notification{ID: "b1"})
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("notification pushed into full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Equal(t, "a", readyKey(t, queue))
+	queue.done("a")
+	assert.True(t, <-pushed)
+	assert.Equal(t, "b", readyKey(t, queue))
+}
+
+func TestKeyedQueueRefusesPushAfterCancel(t *testing.T) {
+	queue := newKeyedQueue(1)
+	require.True(t, queue.push(context.Background(), "a", &entity.Notification{ID: "a1"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.False(t, queue.push(ctx, "b", &entity.Notification{ID: "b1"}))
+}
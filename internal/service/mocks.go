@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/mwsbkru/evrone-go-final/internal/entity"
 	"github.com/stretchr/testify/mock"
@@ -44,6 +45,176 @@ func (m *MockWsNotificationsReceiver) Subscribe(receivedNotificationProcessor Re
 	m.Called(receivedNotificationProcessor, wsConnectionTerminator)
 }
 
+func (m *MockWsNotificationsReceiver) Run(ctx context.Context) {
+	m.Called(ctx)
+}
+
 func (m *MockWsNotificationsReceiver) ReceiveNotifications(ctx context.Context, userEmail string) {
 	m.Called(ctx, userEmail)
 }
+
+// MockNotificationsInbox is a mock implementation of NotificationsInbox
+type MockNotificationsInbox struct {
+	mock.Mock
+}
+
+func (m *MockNotificationsInbox) List(ctx context.Context, userEmail string, cursor string, limit int64, ascending bool) ([]entity.InboxNotification, string, error) {
+	args := m.Called(ctx, userEmail, cursor, limit, ascending)
+	return args.Get(0).([]entity.InboxNotification), args.String(1), args.Error(2)
+}
+
+func (m *MockNotificationsInbox) UnreadCount(ctx context.Context, userEmail string) (int64, error) {
+	args := m.Called(ctx, userEmail)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockNotificationsInbox) MarkRead(ctx context.Context, userEmail string, notificationID string) error {
+	args := m.Called(ctx, userEmail, notificationID)
+	return args.Error(0)
+}
+
+func (m *MockNotificationsInbox) Delete(ctx context.Context, userEmail string, notificationID string) (bool, error) {
+	args := m.Called(ctx, userEmail, notificationID)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockWsConnectionsRegistry is a mock implementation of WsConnectionsRegistry
+type MockWsConnectionsRegistry struct {
+	mock.Mock
+}
+
+func (m *MockWsConnectionsRegistry) Subscribe(kicker WsConnectionKicker) {
+	m.Called(kicker)
+}
+
+func (m *MockWsConnectionsRegistry) Run(ctx context.Context) {
+	m.Called(ctx)
+}
+
+func (m *MockWsConnectionsRegistry) Acquire(ctx context.Context, userEmail string) (string, error) {
+	args := m.Called(ctx, userEmail)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockWsConnectionsRegistry) Release(ctx context.Context, userEmail string, token string) {
+	m.Called(ctx, userEmail, token)
+}
+
+func (m *MockWsConnectionsRegistry) Online(ctx context.Context, userEmail string) (bool, error) {
+	args := m.Called(ctx, userEmail)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockNotificationsStatusTracker is a mock implementation of NotificationsStatusTracker
+type MockNotificationsStatusTracker struct {
+	mock.Mock
+}
+
+func (m *MockNotificationsStatusTracker) Track(ctx context.Context, correlationID string, channel string, status entity.ChannelStatus) error {
+	args := m.Called(ctx, correlationID, channel, status)
+	return args.Error(0)
+}
+
+func (m *MockNotificationsStatusTracker) TrackInitial(ctx context.Context, correlationID string, channel string, status entity.ChannelStatus) (bool, error) {
+	args := m.Called(ctx, correlationID, channel, status)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNotificationsStatusTracker) Untrack(ctx context.Context, correlationID string, channel string, status entity.ChannelStatus) error {
+	args := m.Called(ctx, correlationID, channel, status)
+	return args.Error(0)
+}
+
+func (m *MockNotificationsStatusTracker) Get(ctx context.Context, correlationID string) (map[string]entity.ChannelStatus, error) {
+	args := m.Called(ctx, correlationID)
+	statuses, _ := args.Get(0).(map[string]entity.ChannelStatus)
+	return statuses, args.Error(1)
+}
+
+// MockFallbackChainsStore is a mock implementation of FallbackChainsStore
+type MockFallbackChainsStore struct {
+	mock.Mock
+}
+
+func (m *MockFallbackChainsStore) Create(ctx context.Context, state entity.FallbackState, checkAt time.Time) (bool, error) {
+	args := m.Called(ctx, state, checkAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockFallbackChainsStore) Save(ctx context.Context, state entity.FallbackState, checkAt time.Time) error {
+	args := m.Called(ctx, state, checkAt)
+	return args.Error(0)
+}
+
+func (m *MockFallbackChainsStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.FallbackState, error) {
+	args := m.Called(ctx, limit, lease)
+	states, _ := args.Get(0).([]entity.FallbackState)
+	return states, args.Error(1)
+}
+
+func (m *MockFallbackChainsStore) Delete(ctx context.Context, correlationID string) error {
+	args := m.Called(ctx, correlationID)
+	return args.Error(0)
+}
+
+// MockWebhooksStore is a mock implementation of WebhooksStore
+type MockWebhooksStore struct {
+	mock.Mock
+}
+
+func (m *MockWebhooksStore) List(ctx context.Context, userEmail string) ([]entity.WebhookSubscription, error) {
+	args := m.Called(ctx, userEmail)
+	subscriptions, _ := args.Get(0).([]entity.WebhookSubscription)
+	return subscriptions, args.Error(1)
+}
+
+func (m *MockWebhooksStore) Save(ctx context.Context, subscription entity.WebhookSubscription) error {
+	args := m.Called(ctx, subscription)
+	return args.Error(0)
+}
+
+func (m *MockWebhooksStore) Delete(ctx context.Context, userEmail string, id string) (bool, error) {
+	args := m.Called(ctx, userEmail, id)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockSmsMessagesStore is a mock implementation of SmsMessagesStore
+type MockSmsMessagesStore struct {
+	mock.Mock
+}
+
+func (m *MockSmsMessagesStore) Save(ctx context.Context, messageIDs []string, parts int, correlationID string) error {
+	args := m.Called(ctx, messageIDs, parts, correlationID)
+	return args.Error(0)
+}
+
+func (m *MockSmsMessagesStore) Get(ctx context.Context, messageID string) (string, bool, error) {
+	args := m.Called(ctx, messageID)
+	return args.String(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockSmsMessagesStore) SaveState(ctx context.Context, correlationID string, messageID string, state string) (entity.SmsParts, error) {
+	args := m.Called(ctx, correlationID, messageID, state)
+	return args.Get(0).(entity.SmsParts), args.Error(1)
+}
+
+// MockPushSubscriptionsStore is a mock implementation of PushSubscriptionsStore
+type MockPushSubscriptionsStore struct {
+	mock.Mock
+}
+
+func (m *MockPushSubscriptionsStore) List(ctx context.Context, userEmail string) ([]entity.PushSubscription, error) {
+	args := m.Called(ctx, userEmail)
+	subscriptions, _ := args.Get(0).([]entity.PushSubscription)
+	return subscriptions, args.Error(1)
+}
+
+func (m *MockPushSubscriptionsStore) Save(ctx context.Context, subscription entity.PushSubscription) error {
+	args := m.Called(ctx, subscription)
+	return args.Error(0)
+}
+
+func (m *MockPushSubscriptionsStore) Delete(ctx context.Context, userEmail string, id string) (bool, error) {
+	args := m.Called(ctx, userEmail, id)
+	return args.Bool(0), args.Error(1)
+}
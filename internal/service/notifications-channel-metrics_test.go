@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type succeedingProcessor struct{}
+
+func (succeedingProcessor) Process(context.Context, *entity.Notification) error {
+	return nil
+}
+
+// counters reads counters of the test channel, metrics are global, so tests compare differences
+func counters() map[string]float64 {
+	read := func(counter *prometheus.CounterVec) float64 {
+		return testutil.ToFloat64(counter.WithLabelValues("test"))
+	}
+
+	return map[string]float64{
+		"received":  read(metrics.NotificationsReceived),
+		"delivered": read(metrics.NotificationsDelivered),
+		"retried":   read(metrics.NotificationsRetried),
+		"dead":      read(metrics.NotificationsDead),
+	}
+}
+
+func difference(before map[string]float64, after map[string]float64) map[string]float64 {
+	result := make(map[string]float64, len(after))
+	for name, value := range after {
+		result[name] = value - before[name]
+	}
+	return result
+}
+
+func TestChannelCountsDeliveredNotifications(t *testing.T) {
+	before := counters()
+	deadProcessor := &collectingDeadProcessor{}
+	_, observer := newTestChannel(t, &config.Config{}, succeedingProcessor{}, deadProcessor)
+
+	observer.subscriber(&entity.Notification{UserEmail: "user@example.com"})
+
+	require.Eventually(t, func() bool { return counters()["delivered"]-before["delivered"] == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]float64{"received": 1, "delivered": 1, "retried": 0, "dead": 0}, difference(before, counters()))
+}
+
+func TestChannelCountsRetriedAndDeadNotifications(t *testing.T) {
+	before := counters()
+	deadProcessor := &collectingDeadProcessor{}
+	_, observer := newTestChannel(t, &config.Config{NotificationsRetryCount: 2}, &failingProcessor{}, deadProcessor)
+
+	observer.subscriber(&entity.Notification{UserEmail: "user@example.com"})
+
+	require.Eventually(t, func() bool { return deadProcessor.count() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]float64{"received": 1, "delivered": 0, "retried": 2, "dead": 1}, difference(before, counters()))
+}
@@ -2,14 +2,25 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/mwsbkru/evrone-go-final/config"
 	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	"github.com/mwsbkru/evrone-go-final/internal/metrics"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrRetryAbortedByShutdown pending retry was abandoned, because the channel is stopping
+var ErrRetryAbortedByShutdown = errors.New("retry aborted by shutdown")
+
 type NotificationsChannel struct {
 	Name                       string
 	wg                         *sync.WaitGroup
@@ -17,52 +28,557 @@ type NotificationsChannel struct {
 	notificationsProcessor     NotificationsProcessor
 	deadNotificationsProcessor DeadNotificationsProcessor
 	cfg                        *config.Config
+	workers                    int
+	retryCount                 int
+	retryInterval              time.Duration
+	// queue is drained by workers, subscriber blocks while it's full and so pauses the observer
+	queue chan *entity.Notification
+	// orderedQueue replaces queue in ordered mode, notifications of one recipient are processed one by one
+	ordered      bool
+	orderedQueue *keyedQueue
+	// delayed limits notifications waiting for retry or rate limit, full semaphore blocks workers and so the observer
+	delayed     chan struct{}
+	rateLimiter RateLimiter
+	rateLimits  RateLimits
+	scheduler   NotificationsScheduler
+	preferences *PreferencesService
+	// preferencesKey is channel name in user preferences
+	preferencesKey string
+	quietHours     bool
+	digest         NotificationsDigest
+	statusTracker  NotificationsStatusTracker
+	// statusKey is channel name in notification statuses
+	statusKey string
+	// digestWindows maps category to its digest window
+	digestWindows map[string]string
+	// urgentCategories bypass quiet hours
+	urgentCategories map[string]struct{}
+	// inFlight counts notifications which are queued, processed or wait for retry
+	inFlight sync.WaitGroup
+	mu       sync.Mutex
+	draining bool
+	// processingCtx outlives ctx of Run, so in-flight notifications can be finished during shutdown
+	processingCtx    context.Context
+	cancelProcessing context.CancelFunc
+}
+
+type NotificationsChannelOption func(channel *NotificationsChannel)
+
+// WithConcurrency sets amount of workers and size of the queue in front of them, zero keeps global default
+func WithConcurrency(workers int, queueSize int) NotificationsChannelOption {
+	return func(channel *NotificationsChannel) {
+		if workers > 0 {
+			channel.workers = workers
+		}
+		if queueSize > 0 {
+			channel.queue = make(chan *entity.Notification, queueSize)
+		}
+	}
+}
+
+// WithRetryPolicy overrides global amount of retries and interval between them, nil keeps global default
+func WithRetryPolicy(count *int, intervalSeconds *int) NotificationsChannelOption {
+	return func(channel *NotificationsChannel) {
+		if count != nil {
+			channel.retryCount = max(*count, 0)
+		}
+		if intervalSeconds != nil {
+			channel.retryInterval = time.Duration(max(*intervalSeconds, 0)) * time.Second
+		}
+	}
+}
+
+// WithOrdering keeps order of notifications per recipient, while different recipients are processed in parallel
+func WithOrdering(ordered bool) NotificationsChannelOption {
+	return func(channel *NotificationsChannel) {
+		channel.ordered = ordered
+	}
+}
+
+// WithScheduler postpones notifications with send_at in the future until they are due
+func WithScheduler(scheduler NotificationsScheduler) NotificationsChannelOption {
+	return func(channel *NotificationsChannel) {
+		channel.scheduler = scheduler
+	}
+}
+
+// WithPreferences skips notifications user opted out of in this channel
+func WithPreferences(preferences *PreferencesService, preferencesKey string) NotificationsChannelOption {
+	return func(channel *NotificationsChannel) {
+		channel.preferences = preferences
+		channel.preferencesKey = preferencesKey
+	}
 }
 
-func NewNotificationChannel(cfg *config.Config, name string, observer NotificationsObserver, processor NotificationsProcessor, deadProcessor DeadNotificationsProcessor) *NotificationsChannel {
-	return &NotificationsChannel{cfg: cfg, Name: name, notificationsObserver: observer, notificationsProcessor: processor, deadNotificationsProcessor: deadProcessor}
+// WithQuietHours defers non-urgent notifications until the end of user's quiet hours, requires preferences and scheduler
+func WithQuietHours(urgentCategories []string) NotificationsChannelOption {
+	return func(channel *NotificationsChannel) {
+		channel.quietHours = true
+		channel.urgentCategories = make(map[string]struct{}, len(urgentCategories))
+		for _, category := range urgentCategories {
+			channel.urgentCategories[category] = struct{}{}
+		}
+	}
+}
+
+// WithDigest collects notifications of the categories into digests instead of sending them one by one
+func WithDigest(digest NotificationsDigest, windows map[string]string) NotificationsChannelOption {
+	return func(channel *NotificationsChannel) {
+		if len(windows) > 0 {
+			channel.digest = digest
+			channel.digestWindows = windows
+		}
+	}
+}
+
+// WithStatusTracker tracks statuses of notifications with correlation ID
+func WithStatusTracker(tracker NotificationsStatusTracker, statusKey string) NotificationsChannelOption {
+	return func(channel *NotificationsChannel) {
+		channel.statusTracker = tracker
+		channel.statusKey = statusKey
+	}
+}
+
+func NewNotificationChannel(cfg *config.Config, name string, observer NotificationsObserver, processor NotificationsProcessor, deadProcessor DeadNotificationsProcessor, options ...NotificationsChannelOption) *NotificationsChannel {
+	channel := &NotificationsChannel{
+		cfg:                        cfg,
+		Name:                       name,
+		notificationsObserver:      observer,
+		notificationsProcessor:     processor,
+		deadNotificationsProcessor: deadProcessor,
+		workers:                    max(cfg.NotificationsWorkers, 1),
+		retryCount:                 cfg.NotificationsRetryCount,
+		retryInterval:              time.Duration(cfg.NotificationsRetryIntervalSeconds) * time.Second,
+		queue:                      make(chan *entity.Notification, max(cfg.NotificationsQueueSize, 0)),
+		delayed:                    make(chan struct{}, max(cfg.NotificationsMaxDelayed, 1)),
+	}
+
+	for _, option := range options {
+		option(channel)
+	}
+
+	if channel.ordered {
+		channel.orderedQueue = newKeyedQueue(cap(channel.queue))
+	}
+
+	return channel
 }
 
 func (n *NotificationsChannel) Run(ctx context.Context, wg *sync.WaitGroup) {
 	n.wg = wg
-	n.notificationsObserver.Subscribe(n.getSubscriber(ctx), n.terminator)
+	n.mu.Lock()
+	n.processingCtx, n.cancelProcessing = context.WithCancel(context.WithoutCancel(ctx))
+	n.mu.Unlock()
+	for range n.workers {
+		go n.worker(ctx)
+	}
+
+	n.notificationsObserver.Subscribe(n.getSubscriber(), n.terminator)
 	n.notificationsObserver.StartListening(ctx)
 }
 
-func (n *NotificationsChannel) getSubscriber(ctx context.Context) NotificationsSubscriber {
+func (n *NotificationsChannel) getSubscriber() NotificationsSubscriber {
 	return func(notification *entity.Notification) {
-		go n.process(ctx, notification)
+		metrics.NotificationsReceived.WithLabelValues(n.Name).Inc()
+		if n.scheduler != nil && notification.SendAt != nil && notification.SendAt.After(time.Now()) {
+			n.schedule(notification)
+			return
+		}
+		if !n.startProcessing() {
+			n.processDead(notification, ErrRetryAbortedByShutdown)
+			return
+		}
+		n.enqueue(notification)
+	}
+}
+
+// Release passes due scheduled notification to processing, false means the channel isn't running
+func (n *NotificationsChannel) Release(notification *entity.Notification) bool {
+	if !n.startProcessing() {
+		return false
+	}
+
+	slog.Info("Scheduled notification released", slog.String("process channel", n.Name), slog.String("id", notification.ID))
+	n.enqueue(notification)
+	return true
+}
+
+func (n *NotificationsChannel) schedule(notification *entity.Notification) {
+	err := n.scheduler.Schedule(n.processingCtx, n.Name, notification)
+	if err != nil {
+		// Сообщение будет закоммичено в Kafka, поэтому не теряем его, а отправляем в dead notifications
+		n.processDead(notification, err)
+		return
+	}
+
+	metrics.NotificationsScheduled.WithLabelValues(n.Name).Inc()
+	n.trackStatus(notification, entity.StatusScheduled, "")
+	slog.Info("Notification scheduled", slog.String("process channel", n.Name), slog.String("id", notification.ID), slog.Time("send_at", *notification.SendAt))
+}
+
+// startProcessing registers new in-flight notification, it's refused before Run and once the channel started draining
+func (n *NotificationsChannel) startProcessing() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.draining || n.processingCtx == nil {
+		return false
+	}
+	n.inFlight.Add(1)
+	return true
+}
+
+// enqueue blocks while all workers are busy and the queue is full
+func (n *NotificationsChannel) enqueue(notification *entity.Notification) {
+	if n.orderedQueue != nil {
+		if !n.orderedQueue.push(n.processingCtx, notification.UserEmail, notification) {
+			n.processDead(notification, ErrRetryAbortedByShutdown)
+			n.inFlight.Done()
+		}
+		return
+	}
+
+	select {
+	case n.queue <- notification:
+	case <-n.processingCtx.Done():
+		n.processDead(notification, ErrRetryAbortedByShutdown)
+		n.inFlight.Done()
+	}
+}
+
+func (n *NotificationsChannel) worker(ctx context.Context) {
+	var readyKeys chan string
+	if n.orderedQueue != nil {
+		readyKeys = n.orderedQueue.ready
+	}
+
+	for {
+		select {
+		case notification := <-n.queue:
+			n.process(ctx, notification)
+		case key := <-readyKeys:
+			n.processOrdered(ctx, key)
+		case <-n.processingCtx.Done():
+			return
+		}
 	}
 }
 
 func (n *NotificationsChannel) process(ctx context.Context, notification *entity.Notification) {
-	slog.Info("Start process notification", slog.String("process channel", n.Name), slog.Int("Retry number", notification.CurrentRetry))
-	err := n.notificationsProcessor.Process(ctx, notification)
+	n.attempt(ctx, notification, func() { n.enqueue(notification) }, func() {})
+}
+
+// processOrdered processes the oldest notification of the key, the key is kept busy until it's finished
+func (n *NotificationsChannel) processOrdered(ctx context.Context, key string) {
+	notification := n.orderedQueue.head(key)
+	n.attempt(ctx, notification, func() { n.orderedQueue.retry(key) }, func() { n.orderedQueue.done(key) })
+}
+
+// attempt processes notification once. Failed notification is resubmitted after retry interval,
+// finish is called once notification is processed or passed to dead notifications processor.
+func (n *NotificationsChannel) attempt(ctx context.Context, notification *entity.Notification, resubmit func(), finish func()) {
+	if notification.Expired(time.Now()) {
+		n.processDead(notification, fmt.Errorf("%w at %s", ErrNotificationExpired, notification.ExpiresAt.Format(time.RFC3339)))
+		finish()
+		n.inFlight.Done()
+		return
+	}
+
+	deferUntil, err := n.checkPreferences(notification)
+	if errors.Is(err, ErrNotificationSuppressed) {
+		metrics.NotificationsSuppressed.WithLabelValues(n.Name).Inc()
+		slog.Info("Notification suppressed by user preferences", slog.String("process channel", n.Name), slog.String("reason", err.Error()))
+		n.recordSuppressed(notification, err)
+		finish()
+		n.inFlight.Done()
+		return
+	}
+
+	if err == nil {
+		var collected bool
+		collected, err = n.collectToDigest(notification)
+		if collected {
+			n.trackStatus(notification, entity.StatusDigested, "")
+			finish()
+			n.inFlight.Done()
+			return
+		}
+	}
+
+	if err == nil && !deferUntil.IsZero() {
+		notification.SendAt = &deferUntil
+		err = n.scheduler.Schedule(n.processingCtx, n.Name, notification)
+		if err == nil {
+			metrics.NotificationsScheduled.WithLabelValues(n.Name).Inc()
+			n.trackStatus(notification, entity.StatusScheduled, "")
+			slog.Info("Notification deferred by quiet hours", slog.String("process channel", n.Name), slog.String("id", notification.ID), slog.Time("send_at", deferUntil))
+			finish()
+			n.inFlight.Done()
+			return
+		}
+	}
+
+	if err == nil {
+		if n.applyRateLimits(ctx, notification, resubmit, finish) {
+			return
+		}
+
+		slog.Info("Start process notification", slog.String("process channel", n.Name), slog.Int("Retry number", notification.CurrentRetry))
+		err = n.runProcessor(n.processingCtx, notification)
+	}
+	if err == nil {
+		n.trackStatus(notification, entity.StatusDelivered, "")
+		finish()
+		n.inFlight.Done()
+		return
+	}
+
+	slog.Error("Can`t process notification", slog.String("error", err.Error()), slog.String("process channel", n.Name))
+	if notification.CurrentRetry < n.retryCount && !errors.Is(err, ErrPermanent) {
+		n.delay(ctx, notification, retryInterval(err, n.retryInterval), err, resubmit, finish)
+		return
+	}
+
+	n.processDead(notification, err)
+	finish()
+	n.inFlight.Done()
+}
+
+// retryInterval is interval of the channel, unless provider asked to wait longer
+func retryInterval(err error, interval time.Duration) time.Duration {
+	var retryAfter *RetryAfterError
+	if errors.As(err, &retryAfter) {
+		return max(retryAfter.After, interval)
+	}
+
+	return interval
+}
+
+// checkPreferences returns ErrNotificationSuppressed when user opted out of notification,
+// or the end of quiet hours when notification has to be deferred.
+// Failed check is retried as failed processing, notifications aren't sent without consent.
+func (n *NotificationsChannel) checkPreferences(notification *entity.Notification) (time.Time, error) {
+	if n.preferences == nil {
+		return time.Time{}, nil
+	}
+
+	preferences, err := n.preferences.Get(n.processingCtx, notification.UserEmail)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !resolvePreference(preferences.Rules, notification.Category, n.preferencesKey) {
+		return time.Time{}, fmt.Errorf("%w: category %q, channel %s", ErrNotificationSuppressed, notification.Category, n.preferencesKey)
+	}
+
+	if !n.quietHours || n.scheduler == nil || preferences.QuietHours == nil || n.urgent(notification) {
+		return time.Time{}, nil
+	}
+	deferUntil, ok := preferences.QuietHours.Until(time.Now())
+	if !ok {
+		return time.Time{}, nil
+	}
+
+	return deferUntil, nil
+}
+
+// collectToDigest adds notification to the digest window of its category
+func (n *NotificationsChannel) collectToDigest(notification *entity.Notification) (bool, error) {
+	if n.digest == nil || notification.Digest || notification.Priority == entity.PriorityHigh {
+		return false, nil
+	}
+
+	windowEnd, ok := digestWindowEnd(n.digestWindows[notification.Category], time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	window := entity.DigestWindow{UserEmail: notification.UserEmail, Category: notification.Category, End: windowEnd}
+	err := n.digest.Add(n.processingCtx, window, notification)
 	if err != nil {
-		slog.Error("Can`t process notification", slog.String("error", err.Error()), slog.String("process channel", n.Name))
-		if notification.CurrentRetry < n.cfg.NotificationsRetryCount {
-
-			timer := time.NewTimer(time.Duration(n.cfg.NotificationsRetryIntervalSeconds) * time.Second)
-			select {
-			case <-timer.C:
-				notification.CurrentRetry += 1
-				slog.Info("Run retry", slog.String("error", err.Error()), slog.String("process channel", n.Name), slog.Int("current retry", notification.CurrentRetry))
-				go n.process(ctx, notification)
-				timer.Stop()
-			case <-ctx.Done():
-				slog.Info("Stop retry by ctx.Done()", slog.String("error", err.Error()), slog.String("process channel", n.Name), slog.Int("current retry", notification.CurrentRetry))
-			}
-		} else {
-			notification.Channel = n.Name
-			slog.Info("Run dead notification process", slog.String("error", err.Error()), slog.String("process channel", n.Name), slog.Int("current retry", notification.CurrentRetry))
-			err = n.deadNotificationsProcessor.Process(notification, err)
-			if err != nil {
-				slog.Error("Can`t process dead notification", slog.String("error", err.Error()), slog.String("process channel", n.Name))
-			}
+		return false, err
+	}
+
+	metrics.NotificationsDigested.WithLabelValues(n.Name).Inc()
+	slog.Info("Notification collected to digest", slog.String("process channel", n.Name), slog.String("category", notification.Category), slog.Time("window_end", windowEnd))
+	return true, nil
+}
+
+func (n *NotificationsChannel) urgent(notification *entity.Notification) bool {
+	if notification.Priority == entity.PriorityHigh {
+		return true
+	}
+
+	_, ok := n.urgentCategories[notification.Category]
+	return ok
+}
+
+// delay resubmits notification after delay. Waiting doesn't hold the worker, but amount of waiting notifications
+// is limited: when the limit is reached the worker blocks, so the observer stops consuming.
+func (n *NotificationsChannel) delay(ctx context.Context, notification *entity.Notification, delay time.Duration, err error, resubmit func(), finish func()) {
+	select {
+	case n.delayed <- struct{}{}:
+	case <-n.processingCtx.Done():
+		n.persistDelayed(notification, time.Now().Add(delay), err, finish)
+		return
+	}
+
+	go n.resubmitAfter(ctx, notification, delay, err, resubmit, finish)
+}
+
+// resubmitAfter returns notification to the queue after delay. Delay caused by processing error counts as a retry.
+// Slot of delayed notification is released before resubmit: full queue waits for workers, which may wait for the slot.
+func (n *NotificationsChannel) resubmitAfter(ctx context.Context, notification *entity.Notification, delay time.Duration, err error, resubmit func(), finish func()) {
+	resubmitAt := time.Now().Add(delay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		<-n.delayed
+		if err != nil {
+			notification.CurrentRetry += 1
+			metrics.NotificationsRetried.WithLabelValues(n.Name).Inc()
+			slog.Info("Run retry", slog.String("error", err.Error()), slog.String("process channel", n.Name), slog.Int("current retry", notification.CurrentRetry))
 		}
+		resubmit()
+	case <-ctx.Done():
+		<-n.delayed
+		n.persistDelayed(notification, resubmitAt, err, finish)
 	}
 }
 
+// persistDelayed hands notification waiting for retry over to the scheduler on shutdown, so any instance resubmits it in time.
+// Without scheduler it's passed to dead notifications, the message is already committed in Kafka.
+func (n *NotificationsChannel) persistDelayed(notification *entity.Notification, resubmitAt time.Time, err error, finish func()) {
+	if n.scheduler != nil {
+		retry := *notification
+		retry.SendAt = &resubmitAt
+		if err != nil {
+			retry.CurrentRetry += 1
+		}
+
+		scheduleErr := n.scheduler.Schedule(context.WithoutCancel(n.processingCtx), n.Name, &retry)
+		if scheduleErr == nil {
+			slog.Info("Delayed notification moved to scheduler by shutdown", slog.String("process channel", n.Name), slog.String("id", retry.ID), slog.Time("send_at", resubmitAt))
+			metrics.NotificationsScheduled.WithLabelValues(n.Name).Inc()
+			n.trackStatus(&retry, entity.StatusScheduled, "")
+			finish()
+			n.inFlight.Done()
+			return
+		}
+		slog.Error("Can`t schedule delayed notification", slog.String("process channel", n.Name), slog.String("error", scheduleErr.Error()))
+	}
+
+	if err == nil {
+		err = errors.New("notification was delayed")
+	}
+
+	slog.Info("Stop retry by ctx.Done()", slog.String("error", err.Error()), slog.String("process channel", n.Name), slog.Int("current retry", notification.CurrentRetry))
+	n.processDead(notification, fmt.Errorf("%w: %w", ErrRetryAbortedByShutdown, err))
+	finish()
+	n.inFlight.Done()
+}
+
+func (n *NotificationsChannel) processDead(notification *entity.Notification, err error) {
+	notification.Channel = n.Name
+	slog.Info("Run dead notification process", slog.String("error", err.Error()), slog.String("process channel", n.Name), slog.Int("current retry", notification.CurrentRetry))
+	metrics.NotificationsDead.WithLabelValues(n.Name).Inc()
+	n.trackStatus(notification, entity.StatusFailed, DeadNotificationReason(err))
+	err = n.deadNotificationsProcessor.Process(notification, err)
+	if err != nil {
+		slog.Error("Can`t process dead notification", slog.String("error", err.Error()), slog.String("process channel", n.Name))
+	}
+}
+
+// recordSuppressed keeps suppression in status of the notification. Notification without status
+// is passed to dead notifications processor, otherwise suppression isn't recorded anywhere.
+func (n *NotificationsChannel) recordSuppressed(notification *entity.Notification, err error) {
+	// Отписка пользователя не ошибка доставки, поэтому в NotificationsDead не считается
+	if n.statusTracker != nil && notification.CorrelationID != "" {
+		n.trackStatus(notification, entity.StatusSuppressed, DeadReasonSuppressed)
+		return
+	}
+
+	notification.Channel = n.Name
+	err = n.deadNotificationsProcessor.Process(notification, err)
+	if err != nil {
+		slog.Error("Can`t process suppressed notification", slog.String("error", err.Error()), slog.String("process channel", n.Name))
+	}
+}
+
+func (n *NotificationsChannel) trackStatus(notification *entity.Notification, status string, reason string) {
+	if n.statusTracker == nil || notification.CorrelationID == "" {
+		return
+	}
+
+	channelStatus := entity.ChannelStatus{Status: status, Reason: reason, UpdatedAt: time.Now()}
+	// Сгенерированный планировщиком ID продюсер узнает из статуса, чтобы отменить уведомление
+	if status == entity.StatusScheduled {
+		channelStatus.ScheduledID = notification.ID
+	}
+	err := n.statusTracker.Track(context.WithoutCancel(n.processingCtx), notification.CorrelationID, n.statusKey, channelStatus)
+	if err != nil {
+		slog.Warn("Can`t track notification status", slog.String("process channel", n.Name), slog.String("correlation_id", notification.CorrelationID), slog.String("error", err.Error()))
+	}
+}
+
+// drain waits for in-flight notifications up to shutdown timeout, then cancels the ones left
+func (n *NotificationsChannel) drain() {
+	n.mu.Lock()
+	n.draining = true
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.inFlight.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(time.Duration(n.cfg.ShutdownTimeoutSeconds) * time.Second)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		slog.Info("All in-flight notifications processed", slog.String("process channel", n.Name))
+	case <-timer.C:
+		slog.Warn("Shutdown timeout exceeded, cancelling in-flight notifications", slog.String("process channel", n.Name))
+	}
+	n.cancelProcessing()
+}
+
+// runProcessor makes one attempt to process notification
+func (n *NotificationsChannel) runProcessor(ctx context.Context, notification *entity.Notification) error {
+	ctx, span := tracing.Tracer().Start(tracing.ContextFromNotification(ctx, notification), "notifications.process",
+		trace.WithAttributes(
+			attribute.String("notifications.channel", n.Name),
+			attribute.Int("notifications.retry", notification.CurrentRetry),
+		))
+	defer span.End()
+
+	startedAt := time.Now()
+	err := n.notificationsProcessor.Process(ctx, notification)
+	metrics.ProcessingDuration.WithLabelValues(n.Name, metrics.Result(err)).Observe(time.Since(startedAt).Seconds())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	metrics.NotificationsDelivered.WithLabelValues(n.Name).Inc()
+	return nil
+}
+
 func (n *NotificationsChannel) terminator() {
+	n.drain()
 	n.wg.Done()
 }
+
+// ListeningActive reports whether observer is currently attached to its source.
+// Observers which can't tell it are considered active.
+func (n *NotificationsChannel) ListeningActive() bool {
+	if observer, ok := n.notificationsObserver.(interface{ SessionActive() bool }); ok {
+		return observer.SessionActive()
+	}
+
+	return true
+}
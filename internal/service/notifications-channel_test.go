@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// testObserver hands subscriber and terminator to the test, which plays the role of the source
+type testObserver struct {
+	subscriber NotificationsSubscriber
+	terminator Terminator
+}
+
+func (o *testObserver) Subscribe(subscriber NotificationsSubscriber, terminator Terminator) {
+	o.subscriber = subscriber
+	o.terminator = terminator
+}
+
+func (o *testObserver) StartListening(context.Context) {}
+
+type failingProcessor struct {
+	attempts atomic.Int64
+}
+
+func (p *failingProcessor) Process(context.Context, *entity.Notification) error {
+	p.attempts.Add(1)
+	return errors.New("SMTP relay is down")
+}
+
+type collectingDeadProcessor struct {
+	mu     sync.Mutex
+	errors []error
+}
+
+func (p *collectingDeadProcessor) Process(_ *entity.Notification, err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.errors = append(p.errors, err)
+	return nil
+}
+
+func (p *collectingDeadProcessor) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.errors)
+}
+
+// optedOutPreferences user opted out of all notifications
+type optedOutPreferences struct {
+	PreferencesStore
+}
+
+func (optedOutPreferences) Get(_ context.Context, userEmail string) (*entity.Preferences, error) {
+	return &entity.Preferences{UserEmail: userEmail, Rules: []entity.PreferenceRule{
+		{Category: entity.PreferenceWildcard, Channel: entity.PreferenceWildcard, Enabled: false},
+	}}, nil
+}
+
+func newTestChannel(t *testing.T, cfg *config.Config, processor NotificationsProcessor, deadProcessor DeadNotificationsProcessor, options ...NotificationsChannelOption) (*NotificationsChannel, *testObserver) {
+	observer := &testObserver{}
+	channel := NewNotificationChannel(cfg, "test", observer, processor, deadProcessor, options...)
+	channel.Run(context.Background(), &sync.WaitGroup{})
+	t.Cleanup(channel.cancelProcessing)
+
+	return channel, observer
+}
+
+// TestChannelRetriesDoNotDeadlockWithFullQueue keeps the queue full while every attempt fails:
+// delayed notifications wait for the queue and workers wait for slots of delayed notifications
+func TestChannelRetriesDoNotDeadlockWithFullQueue(t *testing.T) {
+	cfg := &config.Config{NotificationsWorkers: 1, NotificationsQueueSize: 1, NotificationsMaxDelayed: 1, NotificationsRetryCount: 2}
+	processor := &failingProcessor{}
+	deadProcessor := &collectingDeadProcessor{}
+	_, observer := newTestChannel(t, cfg, processor, deadProcessor)
+
+	const notifications = 20
+	go func() {
+		for i := range notifications {
+			observer.subscriber(&entity.Notification{ID: fmt.Sprint(i), UserEmail: "user@example.com"})
+		}
+	}()
+
+	require.Eventually(t, func() bool { return deadProcessor.count() == notifications }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(notifications*(cfg.NotificationsRetryCount+1)), processor.attempts.Load())
+}
+
+func TestChannelLimitsWorkersAndBlocksSourceOnFullQueue(t *testing.T) {
+	cfg := &config.Config{NotificationsWorkers: 2, NotificationsQueueSize: 1}
+	processor := newBlockingProcessor()
+	deadProcessor := &collectingDeadProcessor{}
+	_, observer := newTestChannel(t, cfg, processor, deadProcessor)
+
+	// Двое в обработке, один в очереди
+	for range 3 {
+		observer.subscriber(&entity.Notification{UserEmail: "user@example.com"})
+	}
+	<-processor.started
+	<-processor.started
+
+	accepted := make(chan struct{})
+	go func() {
+		observer.subscriber(&entity.Notification{UserEmail: "user@example.com"})
+		close(accepted)
+	}()
+
+	select {
+	case <-processor.started:
+		t.Fatal("more notifications are processed than there are workers")
+	case <-accepted:
+		t.Fatal("notification accepted while queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(processor.release)
+	select {
+	case <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("source is still blocked after workers got free")
+	}
+	for range 2 {
+		<-processor.started
+	}
+	assert.Zero(t, deadProcessor.count())
+}
+
+// flakyProcessor fails the first attempt of the listed notifications and records attempts per user
+type flakyProcessor struct {
+	mu       sync.Mutex
+	failOnce map[string]bool
+	attempts map[string][]string
+}
+
+func (p *flakyProcessor) Process(_ context.Context, notification *entity.Notification) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.attempts[notification.UserEmail] = append(p.attempts[notification.UserEmail], notification.ID)
+	if p.failOnce[notification.ID] {
+		delete(p.failOnce, notification.ID)
+		return errors.New("provider is unavailable")
+	}
+	return nil
+}
+
+func (p *flakyProcessor) attemptsOf(userEmail string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.attempts[userEmail]...)
+}
+
+func TestOrderedChannelKeepsOrderOfRecipientOnRetry(t *testing.T) {
+	cfg := &config.Config{NotificationsWorkers: 4, NotificationsQueueSize: 10, NotificationsRetryCount: 3}
+	processor := &flakyProcessor{failOnce: map[string]bool{"first-1": true}, attempts: make(map[string][]string)}
+	deadProcessor := &collectingDeadProcessor{}
+	_, observer := newTestChannel(t, cfg, processor, deadProcessor, WithOrdering(true))
+
+	for _, id := range []string{"first-1", "first-2", "first-3"} {
+		observer.subscriber(&entity.Notification{ID: id, UserEmail: "first@example.com"})
+	}
+	observer.subscriber(&entity.Notification{ID: "second-1", UserEmail: "second@example.com"})
+
+	require.Eventually(t, func() bool { return len(processor.attemptsOf("first@example.com")) == 4 }, time.Second, 10*time.Millisecond)
+	// Повтор первого уведомления задерживает следующие уведомления того же получателя
+	assert.Equal(t, []string{"first-1", "first-1", "first-2", "first-3"}, processor.attemptsOf("first@example.com"))
+	assert.Equal(t, []string{"second-1"}, processor.attemptsOf("second@example.com"))
+	assert.Zero(t, deadProcessor.count())
+}
+
+func TestChannelPassesExpiredNotificationsToDead(t *testing.T) {
+	processor := &recordingProcessor{}
+	deadProcessor := &collectingDeadProcessor{}
+	_, observer := newTestChannel(t, &config.Config{}, processor, deadProcessor)
+	expiresAt := time.Now().Add(-time.Second)
+
+	observer.subscriber(&entity.Notification{UserEmail: "user@example.com", ExpiresAt: &expiresAt})
+
+	require.Eventually(t, func() bool { return deadProcessor.count() == 1 }, time.Second, 10*time.Millisecond)
+	deadProcessor.mu.Lock()
+	assert.Equal(t, DeadReasonExpired, DeadNotificationReason(deadProcessor.errors[0]))
+	deadProcessor.mu.Unlock()
+	assert.Empty(t, processor.recorded())
+}
+
+func TestRetryInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected time.Duration
+	}{
+		{name: "plain error", err: errors.New("failed"), expected: 10 * time.Second},
+		{name: "retry after is longer", err: fmt.Errorf("send: %w", &RetryAfterError{After: time.Minute, Err: errors.New("flood")}), expected: time.Minute},
+		{name: "retry after is shorter", err: &RetryAfterError{After: time.Second, Err: errors.New("flood")}, expected: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, retryInterval(tt.err, 10*time.Second))
+		})
+	}
+}
+
+func TestChannelRecordsSuppressedNotifications(t *testing.T) {
+	t.Run("without correlation ID", func(t *testing.T) {
+		processor := &failingProcessor{}
+		deadProcessor := &collectingDeadProcessor{}
+		tracker := &MockNotificationsStatusTracker{}
+		_, observer := newTestChannel(t, &config.Config{}, processor, deadProcessor,
+			WithPreferences(NewPreferencesService(optedOutPreferences{}), entity.ChannelEmail), WithStatusTracker(tracker, entity.ChannelEmail))
+
+		observer.subscriber(&entity.Notification{UserEmail: "user@example.com"})
+
+		require.Eventually(t, func() bool { return deadProcessor.count() == 1 }, time.Second, 10*time.Millisecond)
+		deadProcessor.mu.Lock()
+		assert.Equal(t, DeadReasonSuppressed, DeadNotificationReason(deadProcessor.errors[0]))
+		deadProcessor.mu.Unlock()
+		assert.Zero(t, processor.attempts.Load())
+		tracker.AssertNotCalled(t, "Track", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("with correlation ID", func(t *testing.T) {
+		processor := &failingProcessor{}
+		deadProcessor := &collectingDeadProcessor{}
+		tracker := &MockNotificationsStatusTracker{}
+		tracked := make(chan struct{})
+		tracker.On("Track", mock.Anything, "corr", entity.ChannelEmail, mock.MatchedBy(func(status entity.ChannelStatus) bool {
+			return status.Status == entity.StatusSuppressed && status.Reason == DeadReasonSuppressed
+		})).Return(nil).Run(func(mock.Arguments) { close(tracked) }).Once()
+		_, observer := newTestChannel(t, &config.Config{}, processor, deadProcessor,
+			WithPreferences(NewPreferencesService(optedOutPreferences{}), entity.ChannelEmail), WithStatusTracker(tracker, entity.ChannelEmail))
+
+		observer.subscriber(&entity.Notification{CorrelationID: "corr", UserEmail: "user@example.com"})
+
+		select {
+		case <-tracked:
+		case <-time.After(time.Second):
+			t.Fatal("suppression isn't tracked")
+		}
+		assert.Zero(t, deadProcessor.count())
+		assert.Zero(t, processor.attempts.Load())
+	})
+}
+
+// blockingProcessor holds every notification until the test releases it
+type blockingProcessor struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingProcessor() *blockingProcessor {
+	return &blockingProcessor{started: make(chan struct{}, 10), release: make(chan struct{})}
+}
+
+func (p *blockingProcessor) Process(context.Context, *entity.Notification) error {
+	p.started <- struct{}{}
+	<-p.release
+	return nil
+}
+
+// recordingScheduler records notifications handed over to it
+type recordingScheduler struct {
+	NotificationsScheduler
+	mu        sync.Mutex
+	scheduled []entity.Notification
+}
+
+func (s *recordingScheduler) Schedule(_ context.Context, _ string, notification *entity.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scheduled = append(s.scheduled, *notification)
+	return nil
+}
+
+func (s *recordingScheduler) recorded() []entity.Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]entity.Notification(nil), s.scheduled...)
+}
+
+func TestDrainWaitsForInFlightNotifications(t *testing.T) {
+	cfg := &config.Config{ShutdownTimeoutSeconds: 10}
+	processor := newBlockingProcessor()
+	deadProcessor := &collectingDeadProcessor{}
+	channel, observer := newTestChannel(t, cfg, processor, deadProcessor)
+
+	observer.subscriber(&entity.Notification{UserEmail: "user@example.com"})
+	<-processor.started
+
+	channel.wg.Add(1)
+	terminated := make(chan struct{})
+	go func() {
+		observer.terminator()
+		close(terminated)
+	}()
+
+	// Новые уведомления во время остановки не принимаются
+	require.Eventually(t, func() bool {
+		channel.mu.Lock()
+		defer channel.mu.Unlock()
+		return channel.draining
+	}, time.Second, 10*time.Millisecond)
+	observer.subscriber(&entity.Notification{UserEmail: "user@example.com"})
+	require.Equal(t, 1, deadProcessor.count())
+	deadProcessor.mu.Lock()
+	assert.ErrorIs(t, deadProcessor.errors[0], ErrRetryAbortedByShutdown)
+	deadProcessor.mu.Unlock()
+
+	select {
+	case <-terminated:
+		t.Fatal("channel stopped before in-flight notification was processed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(processor.release)
+	select {
+	case <-terminated:
+	case <-time.After(time.Second):
+		t.Fatal("channel didn't stop after in-flight notification was processed")
+	}
+}
+
+func TestShutdownPersistsDelayedNotifications(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheduler *recordingScheduler
+	}{
+		{name: "to scheduler", scheduler: &recordingScheduler{}},
+		{name: "to dead notifications without scheduler"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{NotificationsRetryCount: 3, NotificationsRetryIntervalSeconds: 60}
+			processor := &failingProcessor{}
+			deadProcessor := &collectingDeadProcessor{}
+			var options []NotificationsChannelOption
+			if tt.scheduler != nil {
+				options = append(options, WithScheduler(tt.scheduler))
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			observer := &testObserver{}
+			channel := NewNotificationChannel(cfg, "test", observer, processor, deadProcessor, options...)
+			channel.Run(ctx, &sync.WaitGroup{})
+			t.Cleanup(channel.cancelProcessing)
+
+			startedAt := time.Now()
+			observer.subscriber(&entity.Notification{ID: "42", UserEmail: "user@example.com"})
+			require.Eventually(t, func() bool { return processor.attempts.Load() == 1 }, time.Second, 10*time.Millisecond)
+			cancel()
+
+			if tt.scheduler == nil {
+				require.Eventually(t, func() bool { return deadProcessor.count() == 1 }, time.Second, 10*time.Millisecond)
+				deadProcessor.mu.Lock()
+				assert.Equal(t, DeadReasonShutdown, DeadNotificationReason(deadProcessor.errors[0]))
+				deadProcessor.mu.Unlock()
+				return
+			}
+
+			require.Eventually(t, func() bool { return len(tt.scheduler.recorded()) == 1 }, time.Second, 10*time.Millisecond)
+			retry := tt.scheduler.recorded()[0]
+			assert.Equal(t, "42", retry.ID)
+			assert.Equal(t, 1, retry.CurrentRetry)
+			require.NotNil(t, retry.SendAt)
+			assert.WithinDuration(t, startedAt.Add(time.Minute), *retry.SendAt, 5*time.Second)
+			assert.Zero(t, deadProcessor.count())
+		})
+	}
+}
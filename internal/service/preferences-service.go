@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+)
+
+var (
+	ErrInvalidPreference  = errors.New("invalid preference")
+	ErrPreferenceNotFound = errors.New("preference not found")
+)
+
+type PreferencesService struct {
+	store PreferencesStore
+}
+
+func NewPreferencesService(store PreferencesStore) *PreferencesService {
+	return &PreferencesService{store: store}
+}
+
+func (p *PreferencesService) Get(ctx context.Context, userEmail string) (*entity.Preferences, error) {
+	preferences, err := p.store.Get(ctx, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("can`t get preferences: %w", err)
+	}
+
+	return preferences, nil
+}
+
+func (p *PreferencesService) Set(ctx context.Context, userEmail string, rule entity.PreferenceRule) error {
+	if err := validatePreferenceRule(rule.Category, rule.Channel); err != nil {
+		return err
+	}
+
+	err := p.store.Set(ctx, userEmail, rule)
+	if err != nil {
+		return fmt.Errorf("can`t set preference: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PreferencesService) Replace(ctx context.Context, userEmail string, rules []entity.PreferenceRule) error {
+	for _, rule := range rules {
+		if err := validatePreferenceRule(rule.Category, rule.Channel); err != nil {
+			return err
+		}
+	}
+
+	err := p.store.Replace(ctx, userEmail, rules)
+	if err != nil {
+		return fmt.Errorf("can`t replace preferences: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PreferencesService) Delete(ctx context.Context, userEmail string, category string, channel string) error {
+	if err := validatePreferenceRule(category, channel); err != nil {
+		return err
+	}
+
+	deleted, err := p.store.Delete(ctx, userEmail, category, channel)
+	if err != nil {
+		return fmt.Errorf("can`t delete preference: %w", err)
+	}
+	if !deleted {
+		return ErrPreferenceNotFound
+	}
+
+	return nil
+}
+
+func (p *PreferencesService) SetQuietHours(ctx context.Context, userEmail string, quietHours entity.QuietHours) error {
+	if err := quietHours.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPreference, err)
+	}
+
+	err := p.store.SetQuietHours(ctx, userEmail, quietHours)
+	if err != nil {
+		return fmt.Errorf("can`t set quiet hours: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PreferencesService) DeleteQuietHours(ctx context.Context, userEmail string) error {
+	deleted, err := p.store.DeleteQuietHours(ctx, userEmail)
+	if err != nil {
+		return fmt.Errorf("can`t delete quiet hours: %w", err)
+	}
+	if !deleted {
+		return ErrPreferenceNotFound
+	}
+
+	return nil
+}
+
+// AllowedChannels filters channels, which user accepts notifications of the category in
+func (p *PreferencesService) AllowedChannels(ctx context.Context, userEmail string, category string, channels []string) ([]string, error) {
+	preferences, err := p.store.Get(ctx, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("can`t get preferences: %w", err)
+	}
+
+	allowed := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		if resolvePreference(preferences.Rules, category, channel) {
+			allowed = append(allowed, channel)
+		}
+	}
+
+	return allowed, nil
+}
+
+// resolvePreference applies the most specific matching rule, notifications are allowed by default
+func resolvePreference(rules []entity.PreferenceRule, category string, channel string) bool {
+	if category == "" {
+		category = entity.PreferenceWildcard
+	}
+
+	candidates := [][2]string{
+		{category, channel},
+		{category, entity.PreferenceWildcard},
+		{entity.PreferenceWildcard, channel},
+		{entity.PreferenceWildcard, entity.PreferenceWildcard},
+	}
+	for _, candidate := range candidates {
+		for _, rule := range rules {
+			if rule.Category == candidate[0] && rule.Channel == candidate[1] {
+				return rule.Enabled
+			}
+		}
+	}
+
+	return true
+}
+
+func validatePreferenceRule(category string, channel string) error {
+	if category == "" || channel == "" {
+		return fmt.Errorf("%w: category and channel must be present", ErrInvalidPreference)
+	}
+	if strings.Contains(category, ":") || strings.Contains(channel, ":") {
+		return fmt.Errorf("%w: category and channel can`t contain ':'", ErrInvalidPreference)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+)
+
+var (
+	ErrInvalidPushSubscription  = errors.New("invalid push subscription")
+	ErrPushSubscriptionNotFound = errors.New("push subscription not found")
+	ErrWebPushNotConfigured     = errors.New("web push isn't configured")
+)
+
+const pushAuthSecretLength = 16
+
+type PushSubscriptionsService struct {
+	cfg   *config.Config
+	store PushSubscriptionsStore
+	// vapidPublicKey is application server key browsers subscribe with, empty when Web Push isn't configured
+	vapidPublicKey string
+}
+
+func NewPushSubscriptionsService(cfg *config.Config, store PushSubscriptionsStore, vapidPublicKey string) *PushSubscriptionsService {
+	return &PushSubscriptionsService{cfg: cfg, store: store, vapidPublicKey: vapidPublicKey}
+}
+
+// VapidPublicKey returns application server key in base64url
+func (p *PushSubscriptionsService) VapidPublicKey() (string, error) {
+	if p.vapidPublicKey == "" {
+		return "", ErrWebPushNotConfigured
+	}
+
+	return p.vapidPublicKey, nil
+}
+
+// Register saves subscription of the browser, subscription with the same endpoint is replaced
+func (p *PushSubscriptionsService) Register(ctx context.Context, userEmail string, subscription entity.PushSubscription) (*entity.PushSubscription, error) {
+	if err := p.validate(subscription); err != nil {
+		return nil, err
+	}
+
+	endpointHash := sha256.Sum256([]byte(subscription.Endpoint))
+	subscription.ID = hex.EncodeToString(endpointHash[:16])
+	subscription.UserEmail = userEmail
+	subscription.CreatedAt = time.Now().UTC()
+
+	err := p.store.Save(ctx, subscription)
+	if err != nil {
+		return nil, fmt.Errorf("can`t register push subscription: %w", err)
+	}
+
+	return &subscription, nil
+}
+
+func (p *PushSubscriptionsService) List(ctx context.Context, userEmail string) ([]entity.PushSubscription, error) {
+	subscriptions, err := p.store.List(ctx, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("can`t list push subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (p *PushSubscriptionsService) Delete(ctx context.Context, userEmail string, id string) error {
+	deleted, err := p.store.Delete(ctx, userEmail, id)
+	if err != nil {
+		return fmt.Errorf("can`t delete push subscription: %w", err)
+	}
+	if !deleted {
+		return ErrPushSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func (p *PushSubscriptionsService) validate(subscription entity.PushSubscription) error {
+	endpoint, err := url.Parse(subscription.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return fmt.Errorf("%w: endpoint must be https URL", ErrInvalidPushSubscription)
+	}
+	if endpoint.User != nil {
+		return fmt.Errorf("%w: endpoint can`t contain credentials", ErrInvalidPushSubscription)
+	}
+	// Адреса, в которые резолвится имя хоста, проверяются при отправке
+	if addr, err := netip.ParseAddr(endpoint.Hostname()); err == nil && !p.cfg.WebPush.AllowPrivateNetworks && !tools.IsPublicAddr(addr) {
+		return fmt.Errorf("%w: endpoint must point to public address", ErrInvalidPushSubscription)
+	}
+
+	p256dh, err := decodeBase64URL(subscription.Keys.P256dh)
+	if err != nil {
+		return fmt.Errorf("%w: keys.p256dh must be base64url", ErrInvalidPushSubscription)
+	}
+	if _, err := ecdh.P256().NewPublicKey(p256dh); err != nil {
+		return fmt.Errorf("%w: keys.p256dh must be uncompressed P-256 point", ErrInvalidPushSubscription)
+	}
+
+	auth, err := decodeBase64URL(subscription.Keys.Auth)
+	if err != nil || len(auth) != pushAuthSecretLength {
+		return fmt.Errorf("%w: keys.auth must be base64url of %d octets", ErrInvalidPushSubscription, pushAuthSecretLength)
+	}
+
+	return nil
+}
+
+// decodeBase64URL accepts base64url with and without padding, browsers produce both
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
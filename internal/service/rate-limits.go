@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/metrics"
+)
+
+const (
+	RateLimitPolicyDelay = "delay"
+	RateLimitPolicyDrop  = "drop"
+)
+
+// ErrRateLimited notification was dropped, because rate limit was exceeded
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimits limits of notifications channel, zero limit disables the check
+type RateLimits struct {
+	ChannelLimit    int
+	ChannelPeriod   time.Duration
+	RecipientLimit  int
+	RecipientPeriod time.Duration
+	// Policy defines what happens with over-limit notification: RateLimitPolicyDelay or RateLimitPolicyDrop
+	Policy string
+}
+
+// RateLimitsFromConfig builds channel rate limits from its config section
+func RateLimitsFromConfig(cfg config.ChannelConfig) RateLimits {
+	return RateLimits{
+		ChannelLimit:    cfg.RateLimit,
+		ChannelPeriod:   time.Duration(cfg.RateLimitPeriodSeconds) * time.Second,
+		RecipientLimit:  cfg.RecipientRateLimit,
+		RecipientPeriod: time.Duration(cfg.RecipientRateLimitPeriodSeconds) * time.Second,
+		Policy:          cfg.RateLimitPolicy,
+	}
+}
+
+// WithRateLimits limits rate of processed notifications per channel and per recipient
+func WithRateLimits(limiter RateLimiter, limits RateLimits) NotificationsChannelOption {
+	return func(channel *NotificationsChannel) {
+		if limits.ChannelLimit > 0 || limits.RecipientLimit > 0 {
+			channel.rateLimiter = limiter
+			channel.rateLimits = limits
+		}
+	}
+}
+
+// checkRateLimits returns time to wait when one of the limits is exceeded, both limits are checked atomically,
+// so hits of over-limit notification aren't counted. Every attempt is a request to provider and takes hit of channel limit,
+// recipient limit counts notification once. Limiter failures don't stop notifications, they are only logged.
+func (n *NotificationsChannel) checkRateLimits(ctx context.Context, notification *entity.Notification) (time.Duration, string) {
+	if n.rateLimiter == nil {
+		return 0, ""
+	}
+
+	// Ключи одного канала делят hash tag, чтобы скрипт проверял их атомарно в Redis Cluster
+	limits := make([]entity.RateLimit, 0, 2)
+	scopes := make([]string, 0, 2)
+	if n.rateLimits.RecipientLimit > 0 && !notification.RecipientRateLimitCounted {
+		limits = append(limits, entity.RateLimit{
+			Key:    fmt.Sprintf("{%s}:recipient:%s", n.Name, notification.UserEmail),
+			Limit:  n.rateLimits.RecipientLimit,
+			Period: n.rateLimits.RecipientPeriod,
+		})
+		scopes = append(scopes, "recipient")
+	}
+	if n.rateLimits.ChannelLimit > 0 {
+		limits = append(limits, entity.RateLimit{
+			Key:    fmt.Sprintf("{%s}:channel", n.Name),
+			Limit:  n.rateLimits.ChannelLimit,
+			Period: n.rateLimits.ChannelPeriod,
+		})
+		scopes = append(scopes, "channel")
+	}
+	if len(limits) == 0 {
+		return 0, ""
+	}
+
+	allowed, exceeded, wait, err := n.rateLimiter.Allow(ctx, limits)
+	if err != nil {
+		slog.Warn("Can`t check rate limit", slog.String("process channel", n.Name), slog.String("error", err.Error()))
+		return 0, ""
+	}
+	if allowed {
+		notification.RecipientRateLimitCounted = true
+		return 0, ""
+	}
+
+	return max(wait, time.Millisecond), scopes[exceeded]
+}
+
+// applyRateLimits returns true when notification was postponed or dropped because of rate limits
+func (n *NotificationsChannel) applyRateLimits(ctx context.Context, notification *entity.Notification, resubmit func(), finish func()) bool {
+	wait, scope := n.checkRateLimits(n.processingCtx, notification)
+	if wait == 0 {
+		return false
+	}
+
+	metrics.NotificationsRateLimited.WithLabelValues(n.Name, scope).Inc()
+	if n.rateLimits.Policy == RateLimitPolicyDrop {
+		slog.Info("Notification dropped by rate limit", slog.String("process channel", n.Name), slog.String("scope", scope))
+		n.processDead(notification, fmt.Errorf("%w: %s limit", ErrRateLimited, scope))
+		finish()
+		n.inFlight.Done()
+		return true
+	}
+
+	// Отложенная отправка не расходует попытки ретраев
+	slog.Info("Notification delayed by rate limit", slog.String("process channel", n.Name), slog.String("scope", scope), slog.Duration("wait", wait))
+	n.delay(ctx, notification, wait, nil, resubmit, finish)
+	return true
+}
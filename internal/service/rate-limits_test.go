@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingRateLimiter allows every notification and records keys of the limits of each check
+type recordingRateLimiter struct {
+	mu     sync.Mutex
+	checks [][]string
+}
+
+func (l *recordingRateLimiter) Allow(_ context.Context, limits []entity.RateLimit) (bool, int, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keys := make([]string, 0, len(limits))
+	for _, limit := range limits {
+		keys = append(keys, limit.Key)
+	}
+	l.checks = append(l.checks, keys)
+	return true, 0, 0, nil
+}
+
+func (l *recordingRateLimiter) recorded() [][]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([][]string(nil), l.checks...)
+}
+
+func TestRetriesTakeHitsOfChannelLimit(t *testing.T) {
+	cfg := &config.Config{NotificationsRetryCount: 2}
+	processor := &failingProcessor{}
+	deadProcessor := &collectingDeadProcessor{}
+	limiter := &recordingRateLimiter{}
+	_, observer := newTestChannel(t, cfg, processor, deadProcessor, WithRateLimits(limiter, RateLimits{
+		ChannelLimit:    10,
+		ChannelPeriod:   time.Second,
+		RecipientLimit:  10,
+		RecipientPeriod: time.Second,
+	}))
+
+	observer.subscriber(&entity.Notification{UserEmail: "user@example.com"})
+
+	require.Eventually(t, func() bool { return deadProcessor.count() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, [][]string{
+		{"{test}:recipient:user@example.com", "{test}:channel"},
+		{"{test}:channel"},
+		{"{test}:channel"},
+	}, limiter.recorded())
+	assert.Equal(t, int64(3), processor.attempts.Load())
+}
+
+// denyingRateLimiter denies the first checks with exceeded recipient limit
+type denyingRateLimiter struct {
+	mu     sync.Mutex
+	denied int
+}
+
+func (l *denyingRateLimiter) Allow(context.Context, []entity.RateLimit) (bool, int, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.denied > 0 {
+		l.denied--
+		return false, 0, 10 * time.Millisecond, nil
+	}
+	return true, 0, 0, nil
+}
+
+// recordingProcessor records processed notifications
+type recordingProcessor struct {
+	mu        sync.Mutex
+	processed []entity.Notification
+}
+
+func (p *recordingProcessor) Process(_ context.Context, notification *entity.Notification) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.processed = append(p.processed, *notification)
+	return nil
+}
+
+func (p *recordingProcessor) recorded() []entity.Notification {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]entity.Notification(nil), p.processed...)
+}
+
+func TestRateLimitPolicies(t *testing.T) {
+	t.Run("delay", func(t *testing.T) {
+		processor := &recordingProcessor{}
+		deadProcessor := &collectingDeadProcessor{}
+		_, observer := newTestChannel(t, &config.Config{NotificationsRetryCount: 1}, processor, deadProcessor,
+			WithRateLimits(&denyingRateLimiter{denied: 2}, RateLimits{RecipientLimit: 1, RecipientPeriod: time.Second, Policy: RateLimitPolicyDelay}))
+
+		observer.subscriber(&entity.Notification{UserEmail: "user@example.com"})
+
+		require.Eventually(t, func() bool { return len(processor.recorded()) == 1 }, time.Second, 10*time.Millisecond)
+		// Отложенная отправка не расходует попытки ретраев
+		assert.Zero(t, processor.recorded()[0].CurrentRetry)
+		assert.Zero(t, deadProcessor.count())
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		processor := &recordingProcessor{}
+		deadProcessor := &collectingDeadProcessor{}
+		_, observer := newTestChannel(t, &config.Config{}, processor, deadProcessor,
+			WithRateLimits(&denyingRateLimiter{denied: 1}, RateLimits{RecipientLimit: 1, RecipientPeriod: time.Second, Policy: RateLimitPolicyDrop}))
+
+		observer.subscriber(&entity.Notification{UserEmail: "user@example.com"})
+
+		require.Eventually(t, func() bool { return deadProcessor.count() == 1 }, time.Second, 10*time.Millisecond)
+		deadProcessor.mu.Lock()
+		assert.Equal(t, DeadReasonRateLimited, DeadNotificationReason(deadProcessor.errors[0]))
+		deadProcessor.mu.Unlock()
+		assert.Empty(t, processor.recorded())
+	})
+}
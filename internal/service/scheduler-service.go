@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+)
+
+var ErrScheduledNotificationNotFound = errors.New("scheduled notification not found")
+
+// SchedulerService releases due scheduled notifications into their channels
+type SchedulerService struct {
+	cfg       *config.Config
+	scheduler NotificationsScheduler
+	channels  []*NotificationsChannel
+}
+
+func NewSchedulerService(cfg *config.Config, scheduler NotificationsScheduler, channels []*NotificationsChannel) *SchedulerService {
+	return &SchedulerService{cfg: cfg, scheduler: scheduler, channels: channels}
+}
+
+func (s *SchedulerService) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(max(s.cfg.Scheduler.PollIntervalMilliseconds, 10)) * time.Millisecond)
+	defer ticker.Stop()
+
+	slog.Info("Start notifications scheduler")
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Terminated notifications scheduler")
+			return
+		case <-ticker.C:
+			for _, channel := range s.channels {
+				s.releaseDue(ctx, channel)
+			}
+		}
+	}
+}
+
+// Cancel removes notification from the schedule
+func (s *SchedulerService) Cancel(ctx context.Context, id string) error {
+	cancelled, err := s.scheduler.Cancel(ctx, id)
+	if err != nil {
+		return fmt.Errorf("can`t cancel scheduled notification: %w", err)
+	}
+	if !cancelled {
+		return ErrScheduledNotificationNotFound
+	}
+
+	slog.Info("Scheduled notification cancelled", slog.String("id", id))
+	return nil
+}
+
+func (s *SchedulerService) releaseDue(ctx context.Context, channel *NotificationsChannel) {
+	batchSize := max(s.cfg.Scheduler.BatchSize, 1)
+	lease := time.Duration(max(s.cfg.Scheduler.LeaseSeconds, 1)) * time.Second
+
+	for ctx.Err() == nil {
+		notifications, err := s.scheduler.Claim(ctx, channel.Name, batchSize, lease)
+		if err != nil {
+			slog.Error("Can`t claim scheduled notifications", slog.String("process channel", channel.Name), slog.String("error", err.Error()))
+			return
+		}
+
+		for _, notification := range notifications {
+			// Остановленный канал не подтверждает уведомление, после окончания аренды его заберёт другой экземпляр
+			if !channel.Release(notification) {
+				continue
+			}
+
+			err := s.scheduler.Ack(context.WithoutCancel(ctx), channel.Name, notification.ID)
+			if err != nil {
+				slog.Error("Can`t ack scheduled notification", slog.String("id", notification.ID), slog.String("error", err.Error()))
+			}
+		}
+
+		if len(notifications) < batchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dueScheduler hands out the due notifications once and records acks
+type dueScheduler struct {
+	mu    sync.Mutex
+	due   []*entity.Notification
+	acked []string
+}
+
+func (s *dueScheduler) Schedule(context.Context, string, *entity.Notification) error {
+	return nil
+}
+
+func (s *dueScheduler) Claim(context.Context, string, int, time.Duration) ([]*entity.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := s.due
+	s.due = nil
+	return due, nil
+}
+
+func (s *dueScheduler) Ack(_ context.Context, _ string, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.acked = append(s.acked, id)
+	return nil
+}
+
+func (s *dueScheduler) Cancel(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+func (s *dueScheduler) ackedIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.acked...)
+}
+
+func TestSchedulerServiceReleasesDueNotifications(t *testing.T) {
+	scheduler := &dueScheduler{due: []*entity.Notification{{ID: "42", UserEmail: "user@example.com"}}}
+	processor := &recordingProcessor{}
+	channel, _ := newTestChannel(t, &config.Config{}, processor, &collectingDeadProcessor{}, WithScheduler(scheduler))
+	schedulerService := NewSchedulerService(&config.Config{}, scheduler, []*NotificationsChannel{channel})
+
+	schedulerService.releaseDue(context.Background(), channel)
+
+	assert.Equal(t, []string{"42"}, scheduler.ackedIDs())
+	require.Eventually(t, func() bool { return len(processor.recorded()) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestSchedulerServiceDoesNotAckNotificationsOfStoppedChannel(t *testing.T) {
+	scheduler := &dueScheduler{due: []*entity.Notification{{ID: "42", UserEmail: "user@example.com"}}}
+	// Канал не запущен, уведомление вернется в расписание после окончания аренды
+	channel := NewNotificationChannel(&config.Config{}, "test", &testObserver{}, &recordingProcessor{}, &collectingDeadProcessor{}, WithScheduler(scheduler))
+	schedulerService := NewSchedulerService(&config.Config{}, scheduler, []*NotificationsChannel{channel})
+
+	schedulerService.releaseDue(context.Background(), channel)
+
+	assert.Empty(t, scheduler.ackedIDs())
+}
+
+func TestChannelSchedulesNotificationsForFuture(t *testing.T) {
+	scheduler := &recordingScheduler{}
+	processor := &recordingProcessor{}
+	_, observer := newTestChannel(t, &config.Config{}, processor, &collectingDeadProcessor{}, WithScheduler(scheduler))
+	sendAt := time.Now().Add(time.Hour)
+
+	observer.subscriber(&entity.Notification{ID: "later", UserEmail: "user@example.com", SendAt: &sendAt})
+	observer.subscriber(&entity.Notification{ID: "now", UserEmail: "user@example.com"})
+
+	require.Eventually(t, func() bool { return len(processor.recorded()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "now", processor.recorded()[0].ID)
+	require.Len(t, scheduler.recorded(), 1)
+	assert.Equal(t, "later", scheduler.recorded()[0].ID)
+}
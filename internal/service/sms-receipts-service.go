@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+)
+
+var ErrSmsMessageNotFound = errors.New("SMS message not found")
+
+// SmsReceiptsService applies delivery receipts of SMS to statuses of notifications
+type SmsReceiptsService struct {
+	messages      SmsMessagesStore
+	statusTracker NotificationsStatusTracker
+}
+
+func NewSmsReceiptsService(messages SmsMessagesStore, statusTracker NotificationsStatusTracker) *SmsReceiptsService {
+	return &SmsReceiptsService{messages: messages, statusTracker: statusTracker}
+}
+
+// Handle records final state of the part, intermediate states are skipped. Status of notification aggregates
+// all its parts: it's delivered once every part is delivered, failed on the first failed part.
+func (s *SmsReceiptsService) Handle(ctx context.Context, receipt entity.SmsReceipt) error {
+	receiptStatus, final := receipt.ChannelStatus()
+	if !final {
+		return nil
+	}
+
+	correlationID, ok, err := s.messages.Get(ctx, receipt.MessageID)
+	if err != nil {
+		return fmt.Errorf("can`t handle SMS receipt: %w", err)
+	}
+	if !ok {
+		return ErrSmsMessageNotFound
+	}
+
+	parts, err := s.messages.SaveState(ctx, correlationID, receipt.MessageID, receipt.State)
+	if err != nil {
+		return fmt.Errorf("can`t handle SMS receipt: %w", err)
+	}
+
+	status, final := parts.ChannelStatus()
+	// Статус уже записан квитанцией упавшей части, доставка остальных частей его не меняет
+	if !final || (status == entity.StatusFailed && receiptStatus != entity.StatusFailed) {
+		slog.Info("SMS receipt recorded", slog.String("correlation_id", correlationID), slog.String("message_id", receipt.MessageID), slog.String("state", receipt.State))
+		return nil
+	}
+
+	channelStatus := entity.ChannelStatus{Status: status, UpdatedAt: time.Now().UTC()}
+	if status == entity.StatusFailed {
+		channelStatus.Reason = receipt.State
+		if receipt.ErrorCode != "" {
+			channelStatus.Reason = fmt.Sprintf("%s (error %s)", receipt.State, receipt.ErrorCode)
+		}
+	}
+
+	err = s.statusTracker.Track(ctx, correlationID, entity.ChannelSms, channelStatus)
+	if err != nil {
+		return fmt.Errorf("can`t handle SMS receipt: %w", err)
+	}
+
+	slog.Info("SMS receipt applied", slog.String("correlation_id", correlationID), slog.String("message_id", receipt.MessageID), slog.String("state", receipt.State))
+	return nil
+}
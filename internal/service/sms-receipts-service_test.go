@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmsReceiptsServiceAggregatesParts(t *testing.T) {
+	tests := []struct {
+		name    string
+		receipt entity.SmsReceipt
+		parts   entity.SmsParts
+		// tracked is status tracked by the receipt, empty means status isn't changed
+		tracked string
+		reason  string
+	}{
+		{
+			name:    "first delivered part of two",
+			receipt: entity.SmsReceipt{MessageID: "m1", State: entity.SmsStateDelivered},
+			parts:   entity.SmsParts{Parts: 2, States: map[string]string{"m1": entity.SmsStateDelivered, "m2": ""}},
+		},
+		{
+			name:    "last delivered part",
+			receipt: entity.SmsReceipt{MessageID: "m2", State: entity.SmsStateDelivered},
+			parts:   entity.SmsParts{Parts: 2, States: map[string]string{"m1": entity.SmsStateDelivered, "m2": entity.SmsStateDelivered}},
+			tracked: entity.StatusDelivered,
+		},
+		{
+			name:    "first failed part",
+			receipt: entity.SmsReceipt{MessageID: "m1", State: entity.SmsStateUndelivered, ErrorCode: "012"},
+			parts:   entity.SmsParts{Parts: 2, States: map[string]string{"m1": entity.SmsStateUndelivered, "m2": ""}},
+			tracked: entity.StatusFailed,
+			reason:  "UNDELIV (error 012)",
+		},
+		{
+			name:    "delivered part doesn't mask failed one",
+			receipt: entity.SmsReceipt{MessageID: "m2", State: entity.SmsStateDelivered},
+			parts:   entity.SmsParts{Parts: 2, States: map[string]string{"m1": entity.SmsStateUndelivered, "m2": entity.SmsStateDelivered}},
+		},
+		{
+			name:    "partially submitted SMS isn't delivered",
+			receipt: entity.SmsReceipt{MessageID: "m1", State: entity.SmsStateDelivered},
+			parts:   entity.SmsParts{Parts: 3, States: map[string]string{"m1": entity.SmsStateDelivered}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := &MockSmsMessagesStore{}
+			messages.On("Get", mock.Anything, tt.receipt.MessageID).Return("corr", true, nil)
+			messages.On("SaveState", mock.Anything, "corr", tt.receipt.MessageID, tt.receipt.State).Return(tt.parts, nil)
+			tracker := &MockNotificationsStatusTracker{}
+			if tt.tracked != "" {
+				tracker.On("Track", mock.Anything, "corr", entity.ChannelSms, mock.MatchedBy(func(status entity.ChannelStatus) bool {
+					return status.Status == tt.tracked && status.Reason == tt.reason
+				})).Return(nil).Once()
+			}
+
+			err := NewSmsReceiptsService(messages, tracker).Handle(context.Background(), tt.receipt)
+
+			require.NoError(t, err)
+			tracker.AssertExpectations(t)
+			if tt.tracked == "" {
+				tracker.AssertNotCalled(t, "Track", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestSmsReceiptsServiceSkipsIntermediateStates(t *testing.T) {
+	messages := &MockSmsMessagesStore{}
+
+	err := NewSmsReceiptsService(messages, &MockNotificationsStatusTracker{}).Handle(context.Background(), entity.SmsReceipt{MessageID: "m1", State: "ENROUTE"})
+
+	assert.NoError(t, err)
+	messages.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
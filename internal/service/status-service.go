@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+)
+
+var ErrStatusNotFound = errors.New("notification status not found")
+
+type StatusService struct {
+	tracker NotificationsStatusTracker
+}
+
+func NewStatusService(tracker NotificationsStatusTracker) *StatusService {
+	return &StatusService{tracker: tracker}
+}
+
+func (s *StatusService) Get(ctx context.Context, correlationID string) (*entity.NotificationStatus, error) {
+	channels, err := s.tracker.Get(ctx, correlationID)
+	if err != nil {
+		return nil, fmt.Errorf("can`t get notification status: %w", err)
+	}
+	if len(channels) == 0 {
+		return nil, ErrStatusNotFound
+	}
+
+	return &entity.NotificationStatus{CorrelationID: correlationID, Channels: channels}, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+)
+
+var (
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// minWebhookSecretLength secrets shorter than this are too weak for HMAC-SHA256
+const minWebhookSecretLength = 16
+
+type WebhooksService struct {
+	cfg   *config.Config
+	store WebhooksStore
+}
+
+func NewWebhooksService(cfg *config.Config, store WebhooksStore) *WebhooksService {
+	return &WebhooksService{cfg: cfg, store: store}
+}
+
+// Create registers webhook of the user, returned subscription is the only one carrying the secret
+func (w *WebhooksService) Create(ctx context.Context, userEmail string, rawURL string, secret string, categories []string) (*entity.WebhookSubscription, error) {
+	if err := w.validateURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	if secret == "" {
+		generated, err := randomHex(32)
+		if err != nil {
+			return nil, fmt.Errorf("can`t generate webhook secret: %w", err)
+		}
+		secret = generated
+	}
+	if len(secret) < minWebhookSecretLength {
+		return nil, fmt.Errorf("%w: secret must be at least %d characters long", ErrInvalidWebhook, minWebhookSecretLength)
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, fmt.Errorf("can`t generate webhook ID: %w", err)
+	}
+
+	subscription := entity.WebhookSubscription{
+		ID:         id,
+		UserEmail:  userEmail,
+		URL:        rawURL,
+		Secret:     secret,
+		Categories: categories,
+		CreatedAt:  time.Now().UTC(),
+	}
+	err = w.store.Save(ctx, subscription)
+	if err != nil {
+		return nil, fmt.Errorf("can`t create webhook: %w", err)
+	}
+
+	return &subscription, nil
+}
+
+// List returns webhooks of the user without secrets
+func (w *WebhooksService) List(ctx context.Context, userEmail string) ([]entity.WebhookSubscription, error) {
+	subscriptions, err := w.store.List(ctx, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("can`t list webhooks: %w", err)
+	}
+
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	return subscriptions, nil
+}
+
+func (w *WebhooksService) Delete(ctx context.Context, userEmail string, id string) error {
+	deleted, err := w.store.Delete(ctx, userEmail, id)
+	if err != nil {
+		return fmt.Errorf("can`t delete webhook: %w", err)
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// Matching returns webhooks of the user, which subscribed to the category, secrets included
+func (w *WebhooksService) Matching(ctx context.Context, userEmail string, category string) ([]entity.WebhookSubscription, error) {
+	subscriptions, err := w.store.List(ctx, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("can`t list webhooks: %w", err)
+	}
+
+	matching := subscriptions[:0]
+	for _, subscription := range subscriptions {
+		if subscription.Matches(category) {
+			matching = append(matching, subscription)
+		}
+	}
+
+	return matching, nil
+}
+
+func (w *WebhooksService) validateURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be absolute", ErrInvalidWebhook)
+	}
+
+	switch parsed.Scheme {
+	case "https":
+	case "http":
+		if !w.cfg.Webhook.AllowInsecure {
+			return fmt.Errorf("%w: url must use https", ErrInvalidWebhook)
+		}
+	default:
+		return fmt.Errorf("%w: url must use https", ErrInvalidWebhook)
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("%w: url can`t contain credentials", ErrInvalidWebhook)
+	}
+	// Адреса, в которые резолвится имя хоста, проверяются при отправке
+	if addr, err := netip.ParseAddr(parsed.Hostname()); err == nil && !w.cfg.Webhook.AllowPrivateNetworks && !tools.IsPublicAddr(addr) {
+		return fmt.Errorf("%w: url must point to public address", ErrInvalidWebhook)
+	}
+
+	return nil
+}
+
+func randomHex(size int) (string, error) {
+	randomBytes := make([]byte, size)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(randomBytes), nil
+}
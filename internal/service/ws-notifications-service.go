@@ -4,99 +4,260 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
+	"github.com/mwsbkru/evrone-go-final/config"
 	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/tracing"
+	"github.com/mwsbkru/evrone-go-final/internal/metrics"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type ReceivedNotificationProcessor func(notification entity.Notification)
+// ReceivedNotificationProcessor returns false, when notification wasn't accepted for sending and must be read again
+type ReceivedNotificationProcessor func(notification entity.Notification) bool
 type WsConnectionTerminator func(userEmail string)
 
 type WsNotificationsReceiver interface {
 	Subscribe(receivedNotificationProcessor ReceivedNotificationProcessor, wsConnectionTerminator WsConnectionTerminator)
+	Run(ctx context.Context)
 	ReceiveNotifications(ctx context.Context, userEmail string)
 }
 
+type wsConnection struct {
+	userEmail string
+	conn      *websocket.Conn
+	token     string
+	// ctx is done, when notifications of the connection aren't received anymore
+	ctx    context.Context
+	cancel context.CancelFunc
+	// outbox is bounded, so one slow client doesn't block shared reader of Redis streams
+	outbox       chan []byte
+	writeTimeout time.Duration
+	writeMu      sync.Mutex
+	closeOnce    sync.Once
+}
+
+// write serializes writes, gorilla/websocket supports only one concurrent writer
+func (w *wsConnection) write(messageType int, data []byte) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+
+	if w.writeTimeout > 0 {
+		w.conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+	}
+	return w.conn.WriteMessage(messageType, data)
+}
+
 type WsNotificationsService struct {
-	connections             map[string]*websocket.Conn
+	cfg *config.Config
+	// ctx of Run, once it's done connections are closed with "going away" code
+	ctx                     context.Context
+	mu                      sync.Mutex
+	connections             map[string]*wsConnection
 	wsNotificationsReceiver WsNotificationsReceiver
+	wsConnectionsRegistry   WsConnectionsRegistry
 }
 
-func NewWsNotificationsService(wsNotificationsReceiver WsNotificationsReceiver) *WsNotificationsService {
-	return &WsNotificationsService{connections: make(map[string]*websocket.Conn), wsNotificationsReceiver: wsNotificationsReceiver}
+func NewWsNotificationsService(cfg *config.Config, wsNotificationsReceiver WsNotificationsReceiver, wsConnectionsRegistry WsConnectionsRegistry) *WsNotificationsService {
+	return &WsNotificationsService{
+		cfg:                     cfg,
+		ctx:                     context.Background(),
+		connections:             make(map[string]*wsConnection),
+		wsNotificationsReceiver: wsNotificationsReceiver,
+		wsConnectionsRegistry:   wsConnectionsRegistry,
+	}
 }
 
 func (u *WsNotificationsService) Run(ctx context.Context) {
+	u.ctx = ctx
 	u.wsNotificationsReceiver.Subscribe(u.handleNotification, u.handleConnectionTermination)
+	u.wsNotificationsReceiver.Run(ctx)
+	u.wsConnectionsRegistry.Subscribe(u.handleKick)
+	go u.wsConnectionsRegistry.Run(ctx)
 }
 
 func (u *WsNotificationsService) HandleConnection(ctx context.Context, userEmail string, connection *websocket.Conn) {
-	currentConnection, ok := u.connections[userEmail]
-	if ok {
-		currentConnection.WriteMessage(websocket.TextMessage, prepareMessageForSending("new attempt to connect to WS, terminating current connection"))
-		u.handleConnectionTermination(userEmail)
-
-		connection.WriteMessage(websocket.TextMessage, prepareMessageForSending("terminating current connection, try again"))
-		connection.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "connection closed by server"))
-		connection.Close()
+	// Новое соединение вытесняет старое, само новое соединение остается открытым
+	if currentConnection, ok := u.getConnection(userEmail); ok {
+		u.terminateConnection(currentConnection, "new attempt to connect to WS, terminating current connection")
 	}
 
-	u.connections[userEmail] = connection
-	go u.handleConnection(ctx, userEmail, connection)
+	// Становимся владельцем сессии пользователя во всем кластере, остальные инстансы закроют свои соединения
+	token, err := u.wsConnectionsRegistry.Acquire(ctx, userEmail)
+	if err != nil {
+		slog.Error("Can`t acquire WS connection ownership", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+	}
+
+	connectionCtx, cancel := context.WithCancel(ctx)
+	wsConn := &wsConnection{
+		userEmail:    userEmail,
+		conn:         connection,
+		token:        token,
+		ctx:          connectionCtx,
+		cancel:       cancel,
+		outbox:       make(chan []byte, max(u.cfg.WS.SendBufferSize, 1)),
+		writeTimeout: time.Duration(u.cfg.WS.WriteTimeoutSeconds) * time.Second,
+	}
+
+	u.mu.Lock()
+	u.connections[userEmail] = wsConn
+	metrics.WSActiveConnections.Set(float64(len(u.connections)))
+	u.mu.Unlock()
+
+	go u.handleConnection(wsConn)
 }
 
-func (u *WsNotificationsService) handleConnection(ctx context.Context, userEmail string, connection *websocket.Conn) {
-	slog.Info("New WS connection", slog.String("user_email", userEmail))
-	defer slog.Info("WS connection closed", slog.String("user_email", userEmail))
-	ctx, cancel := context.WithCancel(ctx)
-	go u.wsNotificationsReceiver.ReceiveNotifications(ctx, userEmail)
-	u.handleConnectionClosedByUser(userEmail, cancel)
+func (u *WsNotificationsService) handleConnection(connection *wsConnection) {
+	slog.Info("New WS connection", slog.String("user_email", connection.userEmail))
+	defer slog.Info("WS connection closed", slog.String("user_email", connection.userEmail))
+	go u.wsNotificationsReceiver.ReceiveNotifications(connection.ctx, connection.userEmail)
+	go u.writeNotifications(connection)
+	u.handleConnectionClosedByUser(connection)
 }
 
-func (u *WsNotificationsService) handleConnectionClosedByUser(userEmail string, cancel context.CancelFunc) {
+// writeNotifications writes notifications from outbox of the connection, until it's closed
+func (u *WsNotificationsService) writeNotifications(connection *wsConnection) {
 	for {
-		slog.Info("Waiting for reading message from WS connection", slog.String("user_email", userEmail))
-
-		if conn, ok := u.connections[userEmail]; ok {
-			messageType, _, err := conn.ReadMessage()
-			if err != nil {
-				slog.Error("Error in handleConnectionClosedByUser", slog.String("user_email", userEmail), slog.String("error", err.Error()))
-				cancel()
+		select {
+		case <-connection.ctx.Done():
+			return
+		case message := <-connection.outbox:
+			if err := connection.write(websocket.TextMessage, message); err != nil {
+				slog.Error("Can`t write notification to WS connection", slog.String("user_email", connection.userEmail), slog.String("error", err.Error()))
+				u.terminateConnection(connection, "connection closed by server")
 				return
 			}
+		}
+	}
+}
 
-			if messageType == websocket.CloseMessage {
-				slog.Info("WS connection closed by user", slog.String("user_email", userEmail))
-				cancel()
-				return
-			}
-		} else {
+// handleConnectionClosedByUser reads the connection until it's closed, the read loop belongs to this connection only
+func (u *WsNotificationsService) handleConnectionClosedByUser(connection *wsConnection) {
+	defer connection.cancel()
+
+	for {
+		slog.Info("Waiting for reading message from WS connection", slog.String("user_email", connection.userEmail))
+
+		messageType, _, err := connection.conn.ReadMessage()
+		if err != nil {
+			slog.Error("Error in handleConnectionClosedByUser", slog.String("user_email", connection.userEmail), slog.String("error", err.Error()))
+			return
+		}
+
+		if messageType == websocket.CloseMessage {
+			slog.Info("WS connection closed by user", slog.String("user_email", connection.userEmail))
 			return
 		}
 	}
 }
 
-func (u *WsNotificationsService) handleNotification(notification entity.Notification) {
-	if conn, ok := u.connections[notification.UserEmail]; ok {
-		conn.WriteMessage(websocket.TextMessage, prepareMessageForSending(notification.Body))
+func (u *WsNotificationsService) handleNotification(notification entity.Notification) bool {
+	_, span := tracing.Tracer().Start(tracing.ContextFromNotification(context.Background(), &notification), "ws.write",
+		trace.WithAttributes(attribute.String("user_email", notification.UserEmail)))
+	defer span.End()
+
+	connection, ok := u.getConnection(notification.UserEmail)
+	if !ok {
+		span.SetStatus(codes.Error, "no WS connection")
+		return false
+	}
+
+	select {
+	case connection.outbox <- prepareMessageForSending(notification.Body):
+		return true
+	default:
+		// Клиент не успевает читать: закрываем соединение, уведомления остаются во входящих пользователя
+		slog.Warn("WS connection falls behind, closing it", slog.String("user_email", notification.UserEmail))
+		span.SetStatus(codes.Error, "WS send buffer overflow")
+		metrics.WSSlowConnectionsClosed.Inc()
+		go u.terminateConnection(connection, "connection falls behind, reconnect to receive notifications")
+		return false
 	}
 }
 
+// handleConnectionTermination closes connection of the user, whose notifications aren't received anymore.
+// Newer connection of the same user keeps receiving, so it stays open.
 func (u *WsNotificationsService) handleConnectionTermination(userEmail string) {
 	slog.Info("handleConnectionTermination run", slog.String("user_email", userEmail))
-	u.terminateConnection(userEmail)
+
+	connection, ok := u.getConnection(userEmail)
+	if !ok || connection.ctx.Err() == nil {
+		return
+	}
+	u.terminateConnection(connection, "connection closed by server")
 }
 
-func (u *WsNotificationsService) terminateConnection(userEmail string) {
-	slog.Info("Termination connection", slog.String("user_email", userEmail))
-	if conn, ok := u.connections[userEmail]; ok {
-		delete(u.connections, userEmail)
-		conn.WriteMessage(websocket.TextMessage, prepareMessageForSending("connection closed by server"))
-		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "connection closed by server"))
-		conn.Close()
+// handleKick terminates local connection of the user, if it isn't the owner of user's session anymore
+func (u *WsNotificationsService) handleKick(userEmail string, ownerToken string) {
+	connection, ok := u.getConnection(userEmail)
+	if !ok || connection.token == ownerToken {
+		return
 	}
+
+	slog.Info("WS connection taken over by another connection", slog.String("user_email", userEmail))
+	u.terminateConnection(connection, "new connection opened, terminating current connection")
+}
+
+func (u *WsNotificationsService) terminateConnection(connection *wsConnection, reason string) {
+	slog.Info("Termination connection", slog.String("user_email", connection.userEmail))
+
+	u.mu.Lock()
+	if u.connections[connection.userEmail] == connection {
+		delete(u.connections, connection.userEmail)
+	}
+	metrics.WSActiveConnections.Set(float64(len(u.connections)))
+	u.mu.Unlock()
+
+	connection.closeOnce.Do(func() {
+		connection.cancel()
+
+		closeCode, closeText := websocket.CloseNormalClosure, "connection closed by server"
+		if u.ctx.Err() != nil {
+			closeCode, closeText = websocket.CloseGoingAway, "server is shutting down"
+		}
+
+		connection.write(websocket.TextMessage, prepareMessageForSending(reason))
+		connection.write(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, closeText))
+		connection.conn.Close()
+		u.wsConnectionsRegistry.Release(context.Background(), connection.userEmail, connection.token)
+	})
+}
+
+// Shutdown closes all remaining connections with "going away" code, ctx of Run must be done already
+func (u *WsNotificationsService) Shutdown() {
+	u.mu.Lock()
+	connections := make([]*wsConnection, 0, len(u.connections))
+	for _, connection := range u.connections {
+		connections = append(connections, connection)
+	}
+	u.mu.Unlock()
+
+	slog.Info("Closing WS connections", slog.Int("connections", len(connections)))
+	for _, connection := range connections {
+		u.terminateConnection(connection, "server is shutting down")
+	}
+}
+
+// Online tells whether user has live WS connection, local connections are checked before the registry of all instances
+func (u *WsNotificationsService) Online(ctx context.Context, userEmail string) (bool, error) {
+	if _, ok := u.getConnection(userEmail); ok {
+		return true, nil
+	}
+
+	return u.wsConnectionsRegistry.Online(ctx, userEmail)
+}
+
+func (u *WsNotificationsService) getConnection(userEmail string) (*wsConnection, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	connection, ok := u.connections[userEmail]
+	return connection, ok
 }
 
 func prepareMessageForSending(message string) []byte {
@@ -0,0 +1,94 @@
+package sms_messages_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Fields of hash with parts of SMS: number of parts and state of every submitted part by its message ID
+const (
+	partsCountField = "parts"
+	partStatePrefix = "state:"
+)
+
+// RedisSmsMessagesStore keeps correlation ID of notification under every message ID, as long as its status lives
+type RedisSmsMessagesStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+func NewRedisSmsMessagesStore(client redis.UniversalClient, cfg *config.Config) *RedisSmsMessagesStore {
+	return &RedisSmsMessagesStore{client: client, ttl: time.Duration(cfg.NotificationStatusTTLHours) * time.Hour}
+}
+
+func (r *RedisSmsMessagesStore) Save(ctx context.Context, messageIDs []string, parts int, correlationID string) error {
+	fields := map[string]any{partsCountField: parts}
+	for _, messageID := range messageIDs {
+		fields[partStatePrefix+messageID] = ""
+	}
+
+	// Ключи сообщений в разных слотах кластера, поэтому пайплайн без транзакции
+	partsKey := tools.GetSmsPartsKey(correlationID)
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, partsKey, fields)
+		pipe.Expire(ctx, partsKey, r.ttl)
+		for _, messageID := range messageIDs {
+			pipe.Set(ctx, tools.GetSmsMessageKey(messageID), correlationID, r.ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can`t save SMS messages of %s: %w", correlationID, err)
+	}
+
+	return nil
+}
+
+func (r *RedisSmsMessagesStore) Get(ctx context.Context, messageID string) (string, bool, error) {
+	correlationID, err := r.client.Get(ctx, tools.GetSmsMessageKey(messageID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("can`t read SMS message %s: %w", messageID, err)
+	}
+
+	return correlationID, true, nil
+}
+
+func (r *RedisSmsMessagesStore) SaveState(ctx context.Context, correlationID string, messageID string, state string) (entity.SmsParts, error) {
+	partsKey := tools.GetSmsPartsKey(correlationID)
+	var fieldsCmd *redis.MapStringStringCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, partsKey, partStatePrefix+messageID, state)
+		pipe.Expire(ctx, partsKey, r.ttl)
+		fieldsCmd = pipe.HGetAll(ctx, partsKey)
+		return nil
+	})
+	if err != nil {
+		return entity.SmsParts{}, fmt.Errorf("can`t save state of SMS message %s: %w", messageID, err)
+	}
+
+	parts := entity.SmsParts{States: make(map[string]string)}
+	for field, value := range fieldsCmd.Val() {
+		if partMessageID, ok := strings.CutPrefix(field, partStatePrefix); ok {
+			parts.States[partMessageID] = value
+			continue
+		}
+		if field == partsCountField {
+			parts.Parts, _ = strconv.Atoi(value)
+		}
+	}
+
+	return parts, nil
+}
@@ -0,0 +1,92 @@
+package sms_senders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+)
+
+const httpGatewayMaxResponseBytes = 64 << 10
+
+type httpGatewayRequest struct {
+	To   string `json:"to"`
+	From string `json:"from,omitempty"`
+	Text string `json:"text"`
+}
+
+// httpGatewayResponse gateway returns ID of every part or one ID of the whole message
+type httpGatewayResponse struct {
+	MessageIDs []string `json:"message_ids"`
+	MessageID  string   `json:"message_id"`
+}
+
+// HttpSmsSender posts SMS as JSON {"to", "from", "text"} to generic HTTP gateway, which splits text itself.
+// Gateway reports delivery to POST /sms/receipts with message IDs from its response.
+type HttpSmsSender struct {
+	cfg    *config.Config
+	client *http.Client
+}
+
+func NewHttpSmsSender(cfg *config.Config) *HttpSmsSender {
+	return &HttpSmsSender{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.Sms.GatewayTimeoutSeconds) * time.Second},
+	}
+}
+
+func (h *HttpSmsSender) Send(ctx context.Context, phone string, text string) ([]string, error) {
+	body, err := json.Marshal(httpGatewayRequest{To: phone, From: h.cfg.Sms.SourceAddr, Text: text})
+	if err != nil {
+		return nil, fmt.Errorf("%w: can`t marshal SMS gateway request: %w", service.ErrPermanent, err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, h.cfg.Sms.GatewayURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: can`t prepare SMS gateway request: %w", service.ErrPermanent, err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if h.cfg.Sms.GatewayToken != "" {
+		request.Header.Set("Authorization", "Bearer "+h.cfg.Sms.GatewayToken)
+	}
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("can`t post to SMS gateway: %w", err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, httpGatewayMaxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("can`t read SMS gateway response: %w", err)
+	}
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+	case response.StatusCode == http.StatusRequestTimeout || response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		return nil, fmt.Errorf("SMS gateway responded with status %d", response.StatusCode)
+	default:
+		return nil, fmt.Errorf("%w: SMS gateway responded with status %d: %s", service.ErrPermanent, response.StatusCode, responseBody)
+	}
+
+	var gatewayResponse httpGatewayResponse
+	if len(responseBody) > 0 {
+		if err := json.Unmarshal(responseBody, &gatewayResponse); err != nil {
+			// SMS уже принят шлюзом, повторная отправка продублирует его
+			slog.Warn("Can`t read message IDs from SMS gateway response, receipts won't be matched", slog.String("error", err.Error()))
+			return nil, nil
+		}
+	}
+	if gatewayResponse.MessageID != "" {
+		gatewayResponse.MessageIDs = append(gatewayResponse.MessageIDs, gatewayResponse.MessageID)
+	}
+
+	return gatewayResponse.MessageIDs, nil
+}
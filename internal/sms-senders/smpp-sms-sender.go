@@ -0,0 +1,97 @@
+package sms_senders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/smpp"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+)
+
+// Providers of SMS channel
+const (
+	ProviderNone = "none"
+	ProviderSmpp = "smpp"
+	ProviderHttp = "http"
+)
+
+// permanentSmppStatuses statuses of submit_sm_resp, which won't change on retry:
+// invalid message length, source or destination address, their TON or NPI, and data coding
+var permanentSmppStatuses = map[uint32]bool{
+	0x00000001: true,
+	0x0000000A: true,
+	0x0000000B: true,
+	0x00000048: true,
+	0x00000049: true,
+	0x00000050: true,
+	0x00000051: true,
+	0x00000104: true,
+}
+
+// SmppSmsSender submits SMS to SMPP server, long text is sent as concatenated SMS with 8-bit reference UDH
+type SmppSmsSender struct {
+	cfg       *config.Config
+	client    *smpp.Client
+	reference atomic.Uint32
+}
+
+func NewSmppSmsSender(cfg *config.Config, client *smpp.Client) *SmppSmsSender {
+	return &SmppSmsSender{cfg: cfg, client: client}
+}
+
+func (s *SmppSmsSender) Send(ctx context.Context, phone string, text string) ([]string, error) {
+	encoded := tools.SplitSms(text)
+	sourceTon, sourceNpi := sourceAddrType(s.cfg.Sms.SourceAddr)
+	reference := byte(s.reference.Add(1))
+
+	messageIDs := make([]string, 0, len(encoded.Parts))
+	for i, part := range encoded.Parts {
+		submit := &smpp.SubmitSm{
+			SourceAddr:         s.cfg.Sms.SourceAddr,
+			SourceTon:          sourceTon,
+			SourceNpi:          sourceNpi,
+			DestAddr:           strings.TrimPrefix(phone, "+"),
+			RegisteredDelivery: smpp.RegisteredDeliveryFinal,
+			DataCoding:         encoded.DataCoding,
+			ShortMessage:       part,
+		}
+		if len(encoded.Parts) > 1 {
+			submit.EsmClass = smpp.EsmClassUdh
+			// UDH: IE конкатенации с 8-битной ссылкой, числом частей и номером части
+			udh := []byte{0x05, 0x00, 0x03, reference, byte(len(encoded.Parts)), byte(i + 1)}
+			submit.ShortMessage = append(udh, part...)
+		}
+
+		messageID, err := s.client.Submit(ctx, submit)
+		if err != nil {
+			var statusErr *smpp.StatusError
+			if errors.As(err, &statusErr) && permanentSmppStatuses[statusErr.Status] {
+				return messageIDs, fmt.Errorf("%w: can`t submit SMS part %d: %w", service.ErrPermanent, i+1, err)
+			}
+			// Повтор отправил бы уже доставленные части с новой ссылкой UDH, поэтому частичная отправка окончательна
+			if len(messageIDs) > 0 {
+				return messageIDs, fmt.Errorf("%w: SMS is partially submitted, can`t submit part %d of %d: %w", service.ErrPermanent, i+1, len(encoded.Parts), err)
+			}
+			return nil, fmt.Errorf("can`t submit SMS part %d: %w", i+1, err)
+		}
+		messageIDs = append(messageIDs, messageID)
+	}
+
+	return messageIDs, nil
+}
+
+// sourceAddrType numeric sender is international number, any other is alphanumeric sender ID
+func sourceAddrType(sourceAddr string) (byte, byte) {
+	digits := strings.TrimPrefix(sourceAddr, "+")
+	if digits != "" && strings.IndexFunc(digits, func(char rune) bool { return !unicode.IsDigit(char) }) < 0 {
+		return smpp.TonInternational, smpp.NpiIsdn
+	}
+
+	return smpp.TonAlphanumeric, smpp.NpiUnknown
+}
@@ -0,0 +1,138 @@
+package sms_senders
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/smpp"
+	"github.com/mwsbkru/evrone-go-final/internal/infrastructure/smpp/smpptest"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSmppSender(t *testing.T, sourceAddr string) (*SmppSmsSender, *smpptest.Server) {
+	server, err := smpptest.NewServer()
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	cfg.Sms.SourceAddr = sourceAddr
+	cfg.Sms.SmppAddr = server.Addr()
+	cfg.Sms.SmppTimeoutSeconds = 2
+
+	client, err := smpp.NewClient(cfg, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return NewSmppSmsSender(cfg, client), server
+}
+
+func TestSmppSmsSenderSendsSingleSms(t *testing.T) {
+	sender, server := newTestSmppSender(t, "+79000000000")
+
+	messageIDs, err := sender.Send(context.Background(), "+79001234567", "Hello")
+	require.NoError(t, err)
+
+	submits := server.Submits()
+	require.Len(t, submits, 1)
+	assert.Equal(t, []string{submits[0].MessageID}, messageIDs)
+	assert.Equal(t, "79001234567", submits[0].DestAddr)
+	assert.Equal(t, "+79000000000", submits[0].SourceAddr)
+	assert.Zero(t, submits[0].EsmClass)
+	assert.Equal(t, tools.SmsDataCodingGsm7, submits[0].DataCoding)
+	assert.Equal(t, []byte("Hello"), submits[0].ShortMessage)
+}
+
+func TestSmppSmsSenderSendsConcatenatedSms(t *testing.T) {
+	sender, server := newTestSmppSender(t, "Notificator")
+	text := strings.Repeat("Привет ", 30)
+
+	messageIDs, err := sender.Send(context.Background(), "+79001234567", text)
+	require.NoError(t, err)
+
+	submits := server.Submits()
+	require.Len(t, submits, 4)
+	require.Len(t, messageIDs, 4)
+
+	var reference byte
+	var decoded []byte
+	for i, submit := range submits {
+		assert.Equal(t, submit.MessageID, messageIDs[i])
+		assert.Equal(t, smpp.EsmClassUdh, submit.EsmClass)
+		assert.Equal(t, tools.SmsDataCodingUcs2, submit.DataCoding)
+
+		udh := submit.ShortMessage[:6]
+		if i == 0 {
+			reference = udh[3]
+		}
+		// IE конкатенации: длина UDH, IEI 0x00, длина IE, ссылка, число частей, номер части
+		assert.Equal(t, []byte{0x05, 0x00, 0x03, reference, 4, byte(i + 1)}, udh)
+		assert.LessOrEqual(t, len(submit.ShortMessage), 140)
+		decoded = append(decoded, submit.ShortMessage[6:]...)
+	}
+
+	assert.Equal(t, tools.SplitSms(text).Parts, splitAt(decoded, 134))
+}
+
+func TestSmppSmsSenderUsesNewReferenceForEveryMessage(t *testing.T) {
+	sender, server := newTestSmppSender(t, "Notificator")
+	text := strings.Repeat("a", 200)
+
+	_, err := sender.Send(context.Background(), "+79001234567", text)
+	require.NoError(t, err)
+	_, err = sender.Send(context.Background(), "+79001234567", text)
+	require.NoError(t, err)
+
+	submits := server.Submits()
+	require.Len(t, submits, 4)
+	assert.Equal(t, submits[0].ShortMessage[3], submits[1].ShortMessage[3])
+	assert.NotEqual(t, submits[0].ShortMessage[3], submits[2].ShortMessage[3])
+}
+
+func TestSmppSmsSenderFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		failedPart int
+		status     uint32
+		submitted  int
+		permanent  bool
+	}{
+		{name: "throttled first part is retried", failedPart: 1, status: 0x00000058, submitted: 0, permanent: false},
+		{name: "invalid destination is permanent", failedPart: 1, status: 0x0000000B, submitted: 0, permanent: true},
+		{name: "partial submit is permanent", failedPart: 2, status: 0x00000058, submitted: 1, permanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, server := newTestSmppSender(t, "Notificator")
+			server.SubmitStatus = func(n int) uint32 {
+				if n == tt.failedPart {
+					return tt.status
+				}
+				return 0
+			}
+
+			messageIDs, err := sender.Send(context.Background(), "+79001234567", strings.Repeat("a", 200))
+
+			require.Error(t, err)
+			assert.Equal(t, tt.permanent, errors.Is(err, service.ErrPermanent))
+			assert.Len(t, messageIDs, tt.submitted)
+			assert.Len(t, server.Submits(), tt.submitted)
+		})
+	}
+}
+
+func splitAt(data []byte, size int) [][]byte {
+	var parts [][]byte
+	for len(data) > size {
+		parts = append(parts, data[:size])
+		data = data[size:]
+	}
+	return append(parts, data)
+}
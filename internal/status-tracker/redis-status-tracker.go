@@ -0,0 +1,109 @@
+package status_tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// untrackScript deletes status of the channel only if nobody updated it meanwhile
+var untrackScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) == ARGV[2] then
+	return redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// RedisStatusTracker keeps statuses of notification in a hash, field is channel
+type RedisStatusTracker struct {
+	client redis.UniversalClient
+	cfg    *config.Config
+}
+
+func NewRedisStatusTracker(client redis.UniversalClient, cfg *config.Config) *RedisStatusTracker {
+	return &RedisStatusTracker{client: client, cfg: cfg}
+}
+
+func (r *RedisStatusTracker) Track(ctx context.Context, correlationID string, channel string, status entity.ChannelStatus) error {
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("can`t marshal notification status: %w", err)
+	}
+
+	key := tools.GetNotificationStatusKey(correlationID)
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, channel, payload)
+		pipe.Expire(ctx, key, r.ttl())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can`t track status of notification %s: %w", correlationID, err)
+	}
+
+	return nil
+}
+
+func (r *RedisStatusTracker) TrackInitial(ctx context.Context, correlationID string, channel string, status entity.ChannelStatus) (bool, error) {
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return false, fmt.Errorf("can`t marshal notification status: %w", err)
+	}
+
+	key := tools.GetNotificationStatusKey(correlationID)
+	var setCmd *redis.BoolCmd
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		setCmd = pipe.HSetNX(ctx, key, channel, payload)
+		pipe.Expire(ctx, key, r.ttl())
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("can`t track status of notification %s: %w", correlationID, err)
+	}
+
+	return setCmd.Val(), nil
+}
+
+func (r *RedisStatusTracker) Untrack(ctx context.Context, correlationID string, channel string, status entity.ChannelStatus) error {
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("can`t marshal notification status: %w", err)
+	}
+
+	err = untrackScript.Run(ctx, r.client, []string{tools.GetNotificationStatusKey(correlationID)}, channel, payload).Err()
+	if err != nil {
+		return fmt.Errorf("can`t untrack status of notification %s: %w", correlationID, err)
+	}
+
+	return nil
+}
+
+func (r *RedisStatusTracker) Get(ctx context.Context, correlationID string) (map[string]entity.ChannelStatus, error) {
+	fields, err := r.client.HGetAll(ctx, tools.GetNotificationStatusKey(correlationID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("can`t read status of notification %s: %w", correlationID, err)
+	}
+
+	statuses := make(map[string]entity.ChannelStatus, len(fields))
+	for channel, payload := range fields {
+		var status entity.ChannelStatus
+		if err := json.Unmarshal([]byte(payload), &status); err != nil {
+			slog.Warn("Skip malformed notification status", slog.String("correlation_id", correlationID), slog.String("channel", channel))
+			continue
+		}
+		statuses[channel] = status
+	}
+
+	return statuses, nil
+}
+
+func (r *RedisStatusTracker) ttl() time.Duration {
+	return time.Duration(max(r.cfg.NotificationStatusTTLHours, 1)) * time.Hour
+}
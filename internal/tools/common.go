@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
 
 	"github.com/IBM/sarama"
 )
@@ -71,3 +72,12 @@ func EnsureTopicExists(topic string, client sarama.Client) error {
 
 	return nil
 }
+
+// GetPriorityTopicName returns topic lane of the priority, normal priority uses the topic itself
+func GetPriorityTopicName(topic string, priority string) string {
+	if priority == "" || priority == entity.PriorityNormal {
+		return topic
+	}
+
+	return fmt.Sprintf("%s.%s", topic, priority)
+}
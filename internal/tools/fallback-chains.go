@@ -0,0 +1,7 @@
+package tools
+
+// Fallback chains keys share hash tag, so claim script works in Redis Cluster
+const (
+	REDIS_FALLBACK_CHAINS_QUEUE  = "fallback-chains--{fallback}"
+	REDIS_FALLBACK_CHAINS_STATES = "fallback-chains-states--{fallback}"
+)
@@ -0,0 +1,8 @@
+package tools
+
+import "fmt"
+
+// GetNotificationStatusKey returns key of hash with statuses of notification per channel
+func GetNotificationStatusKey(correlationID string) string {
+	return fmt.Sprintf("notification-status--%s", correlationID)
+}
@@ -0,0 +1,13 @@
+package tools
+
+import "fmt"
+
+// Digest keys share hash tag, so adding and acking of digest are atomic in Redis Cluster
+
+// REDIS_DIGEST_WINDOWS sorted set of digest windows by their end
+const REDIS_DIGEST_WINDOWS = "digest-windows--{digest}"
+
+// GetUserDigestKey returns key of the list with notifications collected in the digest window
+func GetUserDigestKey(userName string, category string, windowEnd int64) string {
+	return fmt.Sprintf("digest--{digest}--%s--%s--%d", userName, category, windowEnd)
+}
@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{addr: "93.184.216.34", public: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", public: true},
+		{addr: "127.0.0.1", public: false},
+		{addr: "::1", public: false},
+		{addr: "0.0.0.0", public: false},
+		{addr: "10.1.2.3", public: false},
+		{addr: "172.16.0.1", public: false},
+		{addr: "192.168.1.1", public: false},
+		{addr: "169.254.169.254", public: false},
+		{addr: "100.64.0.1", public: false},
+		{addr: "fd00::1", public: false},
+		{addr: "fe80::1", public: false},
+		{addr: "::ffff:127.0.0.1", public: false},
+		{addr: "::ffff:10.0.0.1", public: false},
+		{addr: "224.0.0.1", public: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.public, IsPublicAddr(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
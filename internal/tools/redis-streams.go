@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+)
+
+// ParseStreamID splits Redis stream ID "<ms>-<seq>" into its parts
+func ParseStreamID(id string) (uint64, uint64, error) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid stream ID %q", id)
+	}
+
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid stream ID %q: %w", id, err)
+	}
+
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid stream ID %q: %w", id, err)
+	}
+
+	return ms, seq, nil
+}
+
+// CompareStreamIDs returns -1, 0 or 1 like strings.Compare, but in terms of stream ID ordering.
+// Invalid IDs are treated as "0-0".
+func CompareStreamIDs(a, b string) int {
+	aMs, aSeq, _ := ParseStreamID(a)
+	bMs, bSeq, _ := ParseStreamID(b)
+
+	switch {
+	case aMs < bMs:
+		return -1
+	case aMs > bMs:
+		return 1
+	case aSeq < bSeq:
+		return -1
+	case aSeq > bSeq:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ParseStreamNotification extracts notification from the values of Redis stream entry
+func ParseStreamNotification(values map[string]interface{}) (*entity.Notification, error) {
+	rawNotification, ok := values[REDIS_STREAM_NOTIFICATION_FIELD_NAME]
+	if !ok {
+		return nil, errors.New("stream entry has no notification field")
+	}
+
+	notificationString, ok := rawNotification.(string)
+	if !ok {
+		return nil, fmt.Errorf("stream entry notification field has unexpected type %T", rawNotification)
+	}
+
+	var notification entity.Notification
+	err := json.Unmarshal([]byte(notificationString), &notification)
+	if err != nil {
+		return nil, fmt.Errorf("can`t unmarshal notification from stream entry: %w", err)
+	}
+
+	// Контекст трассировки необязателен, битое значение просто игнорируем
+	if rawTraceContext, ok := values[REDIS_STREAM_TRACE_CONTEXT_FIELD_NAME].(string); ok {
+		var traceContext map[string]string
+		if json.Unmarshal([]byte(rawTraceContext), &traceContext) == nil {
+			notification.TraceContext = traceContext
+		}
+	}
+
+	return &notification, nil
+}
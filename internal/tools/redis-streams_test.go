@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareStreamIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected int
+	}{
+		{name: "equal", a: "5-1", b: "5-1", expected: 0},
+		{name: "ms compared as numbers", a: "9-0", b: "10-0", expected: -1},
+		{name: "seq compared as numbers", a: "5-10", b: "5-9", expected: 1},
+		{name: "ms wins over seq", a: "6-0", b: "5-100", expected: 1},
+		{name: "invalid ID is 0-0", a: "garbage", b: "0-0", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CompareStreamIDs(tt.a, tt.b))
+		})
+	}
+}
+
+func TestParseStreamIDRejectsInvalidIDs(t *testing.T) {
+	for _, id := range []string{"", "5", "5-", "-1", "a-1", "1-b"} {
+		_, _, err := ParseStreamID(id)
+		assert.Error(t, err, id)
+	}
+}
@@ -0,0 +1,24 @@
+package tools
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TouchUserLastSeen marks user as active for the inactivity threshold, so janitor keeps user's keys
+func TouchUserLastSeen(ctx context.Context, client redis.UniversalClient, cfg *config.Config, userEmail string) {
+	if cfg.Redis.InactiveUserTTLHours <= 0 {
+		return
+	}
+
+	ttl := time.Duration(cfg.Redis.InactiveUserTTLHours) * time.Hour
+	err := client.Set(ctx, GetUserLastSeenKey(userEmail), time.Now().Unix(), ttl).Err()
+	if err != nil {
+		slog.Warn("Can`t update last seen mark of user", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,25 @@
+package tools
+
+import "fmt"
+
+// REDIS_SCHEDULED_NOTIFICATIONS_INDEX legacy hash of scheduled notification ID to its channel, it's only read on cancel
+const REDIS_SCHEDULED_NOTIFICATIONS_INDEX = "scheduled-notifications-index"
+
+// GetScheduledNotificationChannelsKey returns key of the set of channels, which scheduled notification with the ID
+func GetScheduledNotificationChannelsKey(id string) string {
+	return fmt.Sprintf("scheduled-notification-channels--%s", id)
+}
+
+// Keys of one channel schedule share hash tag, so scheduler scripts work in Redis Cluster
+
+func GetScheduledNotificationsKey(channel string) string {
+	return fmt.Sprintf("scheduled-notifications--{%s}", channel)
+}
+
+func GetScheduledNotificationsLeasesKey(channel string) string {
+	return fmt.Sprintf("scheduled-notifications-leases--{%s}", channel)
+}
+
+func GetScheduledNotificationsPayloadsKey(channel string) string {
+	return fmt.Sprintf("scheduled-notifications-payloads--{%s}", channel)
+}
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// Data coding schemes of SMS
+const (
+	SmsDataCodingGsm7 byte = 0x00
+	SmsDataCodingUcs2 byte = 0x08
+)
+
+// Limits of one SMS in septets for GSM-7 and in octets for UCS-2, concatenated parts lose 6 octets to UDH
+const (
+	smsGsm7SingleLimit = 160
+	smsGsm7PartLimit   = 153
+	smsUcs2SingleLimit = 140
+	smsUcs2PartLimit   = 134
+)
+
+const gsm7Escape = 0x1B
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+var e164Phone = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// gsm7Basic is GSM 03.38 default alphabet, index is the septet, escape 0x1B is never matched
+var gsm7Basic = []rune("@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞ\x00ÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà")
+
+var gsm7Extension = map[rune]byte{
+	'\f': 0x0A, '^': 0x14, '{': 0x28, '}': 0x29, '\\': 0x2F, '[': 0x3C, '~': 0x3D, ']': 0x3E, '|': 0x40, '€': 0x65,
+}
+
+var gsm7BasicIndex = func() map[rune]byte {
+	index := make(map[rune]byte, len(gsm7Basic))
+	for septet, char := range gsm7Basic {
+		if septet != gsm7Escape {
+			index[char] = byte(septet)
+		}
+	}
+	return index
+}()
+
+// SmsEncoded text of SMS split into parts, GSM-7 parts hold one septet per octet as SMPP expects
+type SmsEncoded struct {
+	DataCoding byte
+	Parts      [][]byte
+}
+
+// NormalizePhone removes formatting of phone number and checks it's in E.164 format, e.g. +79001234567
+func NormalizePhone(phone string) (string, error) {
+	normalized := strings.Map(func(char rune) rune {
+		switch char {
+		case ' ', '-', '(', ')', '.':
+			return -1
+		}
+		return char
+	}, phone)
+	if strings.HasPrefix(normalized, "00") {
+		normalized = "+" + normalized[2:]
+	}
+
+	if !e164Phone.MatchString(normalized) {
+		return "", fmt.Errorf("%w: %q must be in international format", ErrInvalidPhone, phone)
+	}
+
+	return normalized, nil
+}
+
+// SplitSms encodes text to GSM-7 when alphabet allows, to UCS-2 otherwise, and splits it into parts.
+// Escaped GSM-7 characters and UTF-16 surrogate pairs aren't split between parts.
+func SplitSms(text string) SmsEncoded {
+	if units, ok := encodeGsm7(text); ok {
+		return SmsEncoded{DataCoding: SmsDataCodingGsm7, Parts: splitSmsUnits(units, smsGsm7SingleLimit, smsGsm7PartLimit)}
+	}
+
+	return SmsEncoded{DataCoding: SmsDataCodingUcs2, Parts: splitSmsUnits(encodeUcs2(text), smsUcs2SingleLimit, smsUcs2PartLimit)}
+}
+
+func encodeGsm7(text string) ([][]byte, bool) {
+	units := make([][]byte, 0, len(text))
+	for _, char := range text {
+		if septet, ok := gsm7BasicIndex[char]; ok {
+			units = append(units, []byte{septet})
+			continue
+		}
+		if septet, ok := gsm7Extension[char]; ok {
+			units = append(units, []byte{gsm7Escape, septet})
+			continue
+		}
+		return nil, false
+	}
+
+	return units, true
+}
+
+func encodeUcs2(text string) [][]byte {
+	units := make([][]byte, 0, len(text))
+	for _, char := range text {
+		var unit []byte
+		for _, codeUnit := range utf16.Encode([]rune{char}) {
+			unit = append(unit, byte(codeUnit>>8), byte(codeUnit))
+		}
+		units = append(units, unit)
+	}
+
+	return units
+}
+
+func splitSmsUnits(units [][]byte, singleLimit int, partLimit int) [][]byte {
+	total := 0
+	for _, unit := range units {
+		total += len(unit)
+	}
+	if total <= singleLimit {
+		return [][]byte{joinSmsUnits(units)}
+	}
+
+	var parts [][]byte
+	var part []byte
+	for _, unit := range units {
+		if len(part)+len(unit) > partLimit {
+			parts = append(parts, part)
+			part = nil
+		}
+		part = append(part, unit...)
+	}
+
+	return append(parts, part)
+}
+
+func joinSmsUnits(units [][]byte) []byte {
+	joined := make([]byte, 0, len(units))
+	for _, unit := range units {
+		joined = append(joined, unit...)
+	}
+	return joined
+}
+
+// GetSmsPartsKey returns key of hash with final states of SMS parts sent for notification
+func GetSmsPartsKey(correlationID string) string {
+	return fmt.Sprintf("sms-parts--%s", correlationID)
+}
+
+// GetSmsMessageKey returns key binding message ID of SMS to the notification, delivery receipts are matched by it
+func GetSmsMessageKey(messageID string) string {
+	return fmt.Sprintf("sms-message--%s", messageID)
+}
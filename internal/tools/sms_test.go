@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSms(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		dataCoding byte
+		partSizes  []int
+	}{
+		{name: "short GSM-7", text: "Hello", dataCoding: SmsDataCodingGsm7, partSizes: []int{5}},
+		{name: "GSM-7 fits single SMS", text: strings.Repeat("a", 160), dataCoding: SmsDataCodingGsm7, partSizes: []int{160}},
+		{name: "GSM-7 one over single SMS", text: strings.Repeat("a", 161), dataCoding: SmsDataCodingGsm7, partSizes: []int{153, 8}},
+		{name: "GSM-7 two full parts", text: strings.Repeat("a", 306), dataCoding: SmsDataCodingGsm7, partSizes: []int{153, 153}},
+		{name: "extension characters take two septets", text: strings.Repeat("€", 80), dataCoding: SmsDataCodingGsm7, partSizes: []int{160}},
+		{name: "escape isn't split between parts", text: strings.Repeat("a", 152) + "€" + strings.Repeat("a", 10), dataCoding: SmsDataCodingGsm7, partSizes: []int{152, 12}},
+		{name: "GSM-7 accented letters", text: "Ça va? Ñandù é à", dataCoding: SmsDataCodingGsm7, partSizes: []int{16}},
+		{name: "UCS-2 fits single SMS", text: strings.Repeat("ж", 70), dataCoding: SmsDataCodingUcs2, partSizes: []int{140}},
+		{name: "UCS-2 one over single SMS", text: strings.Repeat("ж", 71), dataCoding: SmsDataCodingUcs2, partSizes: []int{134, 8}},
+		{name: "surrogate pair isn't split between parts", text: strings.Repeat("ж", 66) + "😀жжж", dataCoding: SmsDataCodingUcs2, partSizes: []int{132, 10}},
+		{name: "one non GSM character switches to UCS-2", text: "Hello, мир", dataCoding: SmsDataCodingUcs2, partSizes: []int{20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := SplitSms(tt.text)
+
+			assert.Equal(t, tt.dataCoding, encoded.DataCoding)
+			sizes := make([]int, 0, len(encoded.Parts))
+			for _, part := range encoded.Parts {
+				sizes = append(sizes, len(part))
+			}
+			assert.Equal(t, tt.partSizes, sizes)
+		})
+	}
+}
+
+func TestSplitSmsEncoding(t *testing.T) {
+	gsm7 := SplitSms("@A{")
+	require.Len(t, gsm7.Parts, 1)
+	assert.Equal(t, []byte{0x00, 0x41, 0x1B, 0x28}, gsm7.Parts[0])
+
+	ucs2 := SplitSms("Я😀")
+	require.Len(t, ucs2.Parts, 1)
+	assert.Equal(t, []byte{0x04, 0x2F, 0xD8, 0x3D, 0xDE, 0x00}, ucs2.Parts[0])
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone      string
+		normalized string
+		valid      bool
+	}{
+		{phone: "+79001234567", normalized: "+79001234567", valid: true},
+		{phone: "+7 (900) 123-45-67", normalized: "+79001234567", valid: true},
+		{phone: "0079001234567", normalized: "+79001234567", valid: true},
+		{phone: "+1.415.555.2671", normalized: "+14155552671", valid: true},
+		{phone: "+123456789012345", normalized: "+123456789012345", valid: true},
+		{phone: "+1234567890123456", valid: false},
+		{phone: "+1234567", valid: false},
+		{phone: "89001234567", valid: false},
+		{phone: "+09001234567", valid: false},
+		{phone: "+7900123456a", valid: false},
+		{phone: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phone, func(t *testing.T) {
+			normalized, err := NormalizePhone(tt.phone)
+			if !tt.valid {
+				assert.ErrorIs(t, err, ErrInvalidPhone)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.normalized, normalized)
+		})
+	}
+}
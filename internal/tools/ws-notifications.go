@@ -1,13 +1,131 @@
 package tools
 
-import "fmt"
+import (
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
 
 const REDIS_STREAM_NOTIFICATION_FIELD_NAME = "notification"
 
+// REDIS_STREAM_TRACE_CONTEXT_FIELD_NAME field of stream entry with JSON encoded W3C trace context
+const REDIS_STREAM_TRACE_CONTEXT_FIELD_NAME = "trace_context"
+
+// REDIS_USER_STREAMS_PATTERN matches streams of all users
+const REDIS_USER_STREAMS_PATTERN = "notifications:*"
+
+// USER_KEYS_BUCKETS amount of Redis Cluster hash tags user keys are spread over.
+// Streams of one bucket share a slot, so they can be read with one XREAD. Changing it orphans existing keys.
+const USER_KEYS_BUCKETS = 64
+
+// GetUserKeysBucket returns bucket of user's keys
+func GetUserKeysBucket(userName string) int {
+	return int(crc32.ChecksumIEEE([]byte(userName)) % USER_KEYS_BUCKETS)
+}
+
+// getUserKeysHashTag all keys of the user share one hash tag, so multi-key commands work in Redis Cluster
+func getUserKeysHashTag(userName string) string {
+	return fmt.Sprintf("{u%d}", GetUserKeysBucket(userName))
+}
+
 func GetUserStreamName(userName string) string {
-	return fmt.Sprintf("notifications:%s", userName)
+	return fmt.Sprintf("notifications:%s:%s", getUserKeysHashTag(userName), userName)
 }
 
 func GetUserLastReadedNotificationID(userName string) string {
-	return fmt.Sprintf("last-readed-notification--%s", userName)
+	return fmt.Sprintf("last-readed-notification--%s--%s", getUserKeysHashTag(userName), userName)
+}
+
+// GetUserInboxLastReadNotificationID returns key of the ID up to which user has read the inbox
+func GetUserInboxLastReadNotificationID(userName string) string {
+	return fmt.Sprintf("inbox-last-read-notification--%s--%s", getUserKeysHashTag(userName), userName)
+}
+
+// GetUserLastSeenKey returns key which marks user as active, it expires after user inactivity threshold
+func GetUserLastSeenKey(userName string) string {
+	return fmt.Sprintf("last-seen--%s--%s", getUserKeysHashTag(userName), userName)
+}
+
+// GetUserPreferencesKey returns key of hash with user's notification preferences
+func GetUserPreferencesKey(userName string) string {
+	return fmt.Sprintf("preferences--%s--%s", getUserKeysHashTag(userName), userName)
+}
+
+// GetUserQuietHoursKey returns key of user's quiet hours, it shares hash tag with preferences
+func GetUserQuietHoursKey(userName string) string {
+	return fmt.Sprintf("quiet-hours--%s--%s", getUserKeysHashTag(userName), userName)
+}
+
+// GetUserWebhooksKey returns key of hash with webhook subscriptions of the user
+func GetUserWebhooksKey(userName string) string {
+	return fmt.Sprintf("webhooks--%s--%s", getUserKeysHashTag(userName), userName)
+}
+
+// GetUserPushSubscriptionsKey returns key of hash with Web Push subscriptions of the user
+func GetUserPushSubscriptionsKey(userName string) string {
+	return fmt.Sprintf("push-subscriptions--%s--%s", getUserKeysHashTag(userName), userName)
+}
+
+// REDIS_USER_CURSORS_PATTERNS match cursor keys of all users, they can outlive user's stream
+var REDIS_USER_CURSORS_PATTERNS = []string{"last-readed-notification--*", "inbox-last-read-notification--*"}
+
+// GetUserFromCursorKey is reverse of GetUserLastReadedNotificationID and GetUserInboxLastReadNotificationID
+func GetUserFromCursorKey(key string) (string, bool) {
+	_, withHashTag, ok := strings.Cut(key, "--{")
+	if !ok {
+		return "", false
+	}
+
+	_, userName, ok := strings.Cut(withHashTag, "}--")
+	return userName, ok && userName != ""
+}
+
+// GetUserFromLegacyStreamName returns user of the stream written before keys got cluster hash tags
+func GetUserFromLegacyStreamName(streamName string) (string, bool) {
+	userName, ok := strings.CutPrefix(streamName, "notifications:")
+	if !ok || userName == "" || strings.HasPrefix(userName, "{") {
+		return "", false
+	}
+	return userName, true
+}
+
+// LegacyUserKey key of the user written before keys got cluster hash tags, with the current key of the same data
+type LegacyUserKey struct {
+	Prefix     string
+	CurrentKey func(userName string) string
+}
+
+// LEGACY_USER_KEYS keys of users besides streams, which were renamed when keys got cluster hash tags
+var LEGACY_USER_KEYS = []LegacyUserKey{
+	{Prefix: "last-readed-notification--", CurrentKey: GetUserLastReadedNotificationID},
+	{Prefix: "inbox-last-read-notification--", CurrentKey: GetUserInboxLastReadNotificationID},
+	{Prefix: "last-seen--", CurrentKey: GetUserLastSeenKey},
+}
+
+// GetUserFromLegacyKey returns user of the legacy key, current keys with hash tag aren't legacy
+func GetUserFromLegacyKey(key string, prefix string) (string, bool) {
+	userName, ok := strings.CutPrefix(key, prefix)
+	if !ok || userName == "" || strings.HasPrefix(userName, "{") {
+		return "", false
+	}
+	return userName, true
+}
+
+// GetUserFromStreamName is reverse of GetUserStreamName
+func GetUserFromStreamName(streamName string) (string, bool) {
+	withoutPrefix, ok := strings.CutPrefix(streamName, "notifications:")
+	if !ok {
+		return "", false
+	}
+
+	_, userName, ok := strings.Cut(withoutPrefix, "}:")
+	return userName, ok
+}
+
+// REDIS_WS_KICK_CHANNEL_NAME pub/sub channel used to terminate user's connections held by other instances
+const REDIS_WS_KICK_CHANNEL_NAME = "ws-connections-kick"
+
+// GetUserPresenceKey returns key holding token of the connection which currently owns user's WS session
+func GetUserPresenceKey(userName string) string {
+	return fmt.Sprintf("ws-presence--%s--%s", getUserKeysHashTag(userName), userName)
 }
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var hashTagPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+func TestUserKeysShareHashTag(t *testing.T) {
+	const userName = "user@example.com"
+	keys := []string{
+		GetUserStreamName(userName),
+		GetUserLastReadedNotificationID(userName),
+		GetUserInboxLastReadNotificationID(userName),
+		GetUserLastSeenKey(userName),
+		GetUserPresenceKey(userName),
+	}
+
+	tag := hashTagPattern.FindString(keys[0])
+	assert.NotEmpty(t, tag)
+	for _, key := range keys {
+		assert.Equal(t, tag, hashTagPattern.FindString(key), key)
+	}
+}
+
+func TestUserKeysReverse(t *testing.T) {
+	const userName = "user@example.com"
+
+	userFromStream, ok := GetUserFromStreamName(GetUserStreamName(userName))
+	assert.True(t, ok)
+	assert.Equal(t, userName, userFromStream)
+
+	for _, cursorKey := range []string{GetUserLastReadedNotificationID(userName), GetUserInboxLastReadNotificationID(userName)} {
+		userFromCursor, ok := GetUserFromCursorKey(cursorKey)
+		assert.True(t, ok, cursorKey)
+		assert.Equal(t, userName, userFromCursor)
+	}
+}
+
+func TestLegacyUserKeys(t *testing.T) {
+	const userName = "user@example.com"
+
+	userFromStream, ok := GetUserFromLegacyStreamName("notifications:" + userName)
+	assert.True(t, ok)
+	assert.Equal(t, userName, userFromStream)
+	_, ok = GetUserFromLegacyStreamName(GetUserStreamName(userName))
+	assert.False(t, ok, "current stream isn't legacy")
+
+	for _, legacyKey := range LEGACY_USER_KEYS {
+		userFromKey, ok := GetUserFromLegacyKey(legacyKey.Prefix+userName, legacyKey.Prefix)
+		assert.True(t, ok, legacyKey.Prefix)
+		assert.Equal(t, userName, userFromKey)
+
+		_, ok = GetUserFromLegacyKey(legacyKey.CurrentKey(userName), legacyKey.Prefix)
+		assert.False(t, ok, "current key %s isn't legacy", legacyKey.CurrentKey(userName))
+	}
+}
@@ -0,0 +1,69 @@
+package webhooks_store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisWebhooksStore keeps webhook subscriptions of the user in one hash, field is subscription ID
+type RedisWebhooksStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisWebhooksStore(client redis.UniversalClient) *RedisWebhooksStore {
+	return &RedisWebhooksStore{client: client}
+}
+
+func (r *RedisWebhooksStore) List(ctx context.Context, userEmail string) ([]entity.WebhookSubscription, error) {
+	fields, err := r.client.HGetAll(ctx, tools.GetUserWebhooksKey(userEmail)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("can`t read webhooks of %s: %w", userEmail, err)
+	}
+
+	subscriptions := make([]entity.WebhookSubscription, 0, len(fields))
+	for id, payload := range fields {
+		var subscription entity.WebhookSubscription
+		if err := json.Unmarshal([]byte(payload), &subscription); err != nil {
+			slog.Warn("Skip malformed webhook subscription", slog.String("user_email", userEmail), slog.String("id", id), slog.String("error", err.Error()))
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+
+	return subscriptions, nil
+}
+
+func (r *RedisWebhooksStore) Save(ctx context.Context, subscription entity.WebhookSubscription) error {
+	payload, err := json.Marshal(subscription)
+	if err != nil {
+		return fmt.Errorf("can`t marshal webhook subscription: %w", err)
+	}
+
+	err = r.client.HSet(ctx, tools.GetUserWebhooksKey(subscription.UserEmail), subscription.ID, payload).Err()
+	if err != nil {
+		return fmt.Errorf("can`t save webhook of %s: %w", subscription.UserEmail, err)
+	}
+
+	return nil
+}
+
+func (r *RedisWebhooksStore) Delete(ctx context.Context, userEmail string, id string) (bool, error) {
+	deleted, err := r.client.HDel(ctx, tools.GetUserWebhooksKey(userEmail), id).Result()
+	if err != nil {
+		return false, fmt.Errorf("can`t delete webhook of %s: %w", userEmail, err)
+	}
+
+	return deleted > 0, nil
+}
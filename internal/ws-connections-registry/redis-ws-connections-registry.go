@@ -0,0 +1,207 @@
+package ws_connections_registry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/service"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultPresenceTTL = 30 * time.Second
+
+// refreshScript prolongs presence only while it is still owned by the connection
+var refreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript removes presence only while it is still owned by the connection
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+type kickMessage struct {
+	UserEmail  string `json:"user_email"`
+	OwnerToken string `json:"owner_token"`
+}
+
+// RedisWsConnectionsRegistry tracks which connection owns user's WS session across all instances.
+// Owner is stored in presence key with TTL, other instances are told to drop their connections through pub/sub.
+type RedisWsConnectionsRegistry struct {
+	client     redis.UniversalClient
+	cfg        *config.Config
+	instanceID string
+	kicker     service.WsConnectionKicker
+	mu         sync.Mutex
+	tokens     map[string]string
+}
+
+func NewRedisWsConnectionsRegistry(client redis.UniversalClient, cfg *config.Config) *RedisWsConnectionsRegistry {
+	instanceID := cfg.WS.InstanceID
+	if instanceID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "ws-notifications"
+		}
+		instanceID = hostname
+	}
+
+	return &RedisWsConnectionsRegistry{client: client, cfg: cfg, instanceID: instanceID, tokens: make(map[string]string)}
+}
+
+func (r *RedisWsConnectionsRegistry) Subscribe(kicker service.WsConnectionKicker) {
+	r.kicker = kicker
+}
+
+func (r *RedisWsConnectionsRegistry) Run(ctx context.Context) {
+	pubsub := r.client.Subscribe(ctx, tools.REDIS_WS_KICK_CHANNEL_NAME)
+	defer pubsub.Close()
+
+	ticker := time.NewTicker(r.presenceTTL() / 3)
+	defer ticker.Stop()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Terminating WS connections registry")
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			r.handleKickMessage(message.Payload)
+		case <-ticker.C:
+			r.refresh(ctx)
+		}
+	}
+}
+
+// Acquire makes new connection the owner of user's session and asks other instances to drop previous one
+func (r *RedisWsConnectionsRegistry) Acquire(ctx context.Context, userEmail string) (string, error) {
+	token, err := r.newToken()
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.tokens[userEmail] = token
+	r.mu.Unlock()
+
+	err = r.client.Set(ctx, tools.GetUserPresenceKey(userEmail), token, r.presenceTTL()).Err()
+	if err != nil {
+		return token, fmt.Errorf("can`t write WS presence of user %s: %w", userEmail, err)
+	}
+
+	payload, err := json.Marshal(kickMessage{UserEmail: userEmail, OwnerToken: token})
+	if err != nil {
+		return token, fmt.Errorf("can`t prepare WS kick message: %w", err)
+	}
+
+	err = r.client.Publish(ctx, tools.REDIS_WS_KICK_CHANNEL_NAME, payload).Err()
+	if err != nil {
+		return token, fmt.Errorf("can`t publish WS kick message for user %s: %w", userEmail, err)
+	}
+
+	return token, nil
+}
+
+func (r *RedisWsConnectionsRegistry) Release(ctx context.Context, userEmail string, token string) {
+	r.mu.Lock()
+	if r.tokens[userEmail] == token {
+		delete(r.tokens, userEmail)
+	}
+	r.mu.Unlock()
+
+	err := releaseScript.Run(ctx, r.client, []string{tools.GetUserPresenceKey(userEmail)}, token).Err()
+	if err != nil {
+		slog.Warn("Can`t release WS presence of user", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+	}
+}
+
+func (r *RedisWsConnectionsRegistry) handleKickMessage(payload string) {
+	var message kickMessage
+	err := json.Unmarshal([]byte(payload), &message)
+	if err != nil {
+		slog.Error("Can`t unmarshal WS kick message", slog.String("error", err.Error()))
+		return
+	}
+
+	r.kicker(message.UserEmail, message.OwnerToken)
+}
+
+// refresh prolongs presence of local connections. Pub/sub gives no delivery guarantees,
+// so connection which lost ownership without receiving kick message is dropped here.
+func (r *RedisWsConnectionsRegistry) refresh(ctx context.Context) {
+	r.mu.Lock()
+	tokens := make(map[string]string, len(r.tokens))
+	for userEmail, token := range r.tokens {
+		tokens[userEmail] = token
+	}
+	r.mu.Unlock()
+
+	for userEmail, token := range tokens {
+		refreshed, err := refreshScript.Run(ctx, r.client, []string{tools.GetUserPresenceKey(userEmail)}, token, r.presenceTTL().Milliseconds()).Int()
+		if err != nil {
+			slog.Warn("Can`t refresh WS presence of user", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+			continue
+		}
+
+		if refreshed == 0 {
+			owner, err := r.client.Get(ctx, tools.GetUserPresenceKey(userEmail)).Result()
+			if err == redis.Nil {
+				// Ключ протух, например из-за недоступности Redis - восстанавливаем владение
+				r.client.SetNX(ctx, tools.GetUserPresenceKey(userEmail), token, r.presenceTTL())
+				continue
+			}
+			if err != nil {
+				slog.Warn("Can`t read WS presence of user", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+				continue
+			}
+			r.kicker(userEmail, owner)
+		}
+	}
+}
+
+// Online tells whether any instance holds WS connection of the user
+func (r *RedisWsConnectionsRegistry) Online(ctx context.Context, userEmail string) (bool, error) {
+	exists, err := r.client.Exists(ctx, tools.GetUserPresenceKey(userEmail)).Result()
+	if err != nil {
+		return false, fmt.Errorf("can`t read WS presence of %s: %w", userEmail, err)
+	}
+
+	return exists > 0, nil
+}
+
+func (r *RedisWsConnectionsRegistry) newToken() (string, error) {
+	randomBytes := make([]byte, 8)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", fmt.Errorf("can`t generate WS connection token: %w", err)
+	}
+
+	return fmt.Sprintf("%s:%s", r.instanceID, hex.EncodeToString(randomBytes)), nil
+}
+
+func (r *RedisWsConnectionsRegistry) presenceTTL() time.Duration {
+	if r.cfg.WS.PresenceTTLSeconds <= 0 {
+		return defaultPresenceTTL
+	}
+	return time.Duration(r.cfg.WS.PresenceTTLSeconds) * time.Second
+}
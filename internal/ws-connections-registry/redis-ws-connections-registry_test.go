@@ -0,0 +1,136 @@
+package ws_connections_registry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserEmail = "user@example.com"
+
+// kicks records kick calls of one instance
+type kicks struct {
+	mu     sync.Mutex
+	owners []string
+}
+
+func (k *kicks) kick(userEmail string, ownerToken string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.owners = append(k.owners, userEmail+" "+ownerToken)
+}
+
+func (k *kicks) recorded() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return append([]string(nil), k.owners...)
+}
+
+func newTestRegistry(client redis.UniversalClient, instanceID string) (*RedisWsConnectionsRegistry, *kicks) {
+	cfg := &config.Config{}
+	cfg.WS.InstanceID = instanceID
+
+	registry := NewRedisWsConnectionsRegistry(client, cfg)
+	kicks := &kicks{}
+	registry.Subscribe(kicks.kick)
+
+	return registry, kicks
+}
+
+func newTestClient(t *testing.T) *redis.Client {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestAcquireKicksConnectionsOfOtherInstances(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := newTestClient(t)
+	first, firstKicks := newTestRegistry(client, "first")
+	second, _ := newTestRegistry(client, "second")
+	go first.Run(ctx)
+	go second.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		return client.PubSubNumSub(ctx, tools.REDIS_WS_KICK_CHANNEL_NAME).Val()[tools.REDIS_WS_KICK_CHANNEL_NAME] == 2
+	}, time.Second, 10*time.Millisecond)
+
+	firstToken, err := first.Acquire(ctx, testUserEmail)
+	require.NoError(t, err)
+	secondToken, err := second.Acquire(ctx, testUserEmail)
+	require.NoError(t, err)
+	assert.NotEqual(t, firstToken, secondToken)
+
+	// Первый инстанс узнает о новом владельце и закрывает свое соединение
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{testUserEmail + " " + firstToken, testUserEmail + " " + secondToken}, firstKicks.recorded())
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, secondToken, client.Get(ctx, tools.GetUserPresenceKey(testUserEmail)).Val())
+}
+
+func TestReleaseKeepsPresenceOfNewOwner(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	first, _ := newTestRegistry(client, "first")
+	second, _ := newTestRegistry(client, "second")
+
+	firstToken, err := first.Acquire(ctx, testUserEmail)
+	require.NoError(t, err)
+	secondToken, err := second.Acquire(ctx, testUserEmail)
+	require.NoError(t, err)
+
+	// Вытесненное соединение закрывается позже и не снимает присутствие нового владельца
+	first.Release(ctx, testUserEmail, firstToken)
+	online, err := first.Online(ctx, testUserEmail)
+	require.NoError(t, err)
+	assert.True(t, online)
+
+	second.Release(ctx, testUserEmail, secondToken)
+	online, err = first.Online(ctx, testUserEmail)
+	require.NoError(t, err)
+	assert.False(t, online)
+}
+
+func TestRefreshDropsConnectionWhichLostOwnership(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	registry, kicks := newTestRegistry(client, "first")
+
+	_, err := registry.Acquire(ctx, testUserEmail)
+	require.NoError(t, err)
+	// Сообщение о новом владельце потерялось, остается только ключ присутствия
+	client.Set(ctx, tools.GetUserPresenceKey(testUserEmail), "second:token", time.Minute)
+
+	registry.refresh(ctx)
+
+	assert.Equal(t, []string{testUserEmail + " second:token"}, kicks.recorded())
+}
+
+func TestRefreshRestoresExpiredPresence(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	registry, kicks := newTestRegistry(client, "first")
+
+	token, err := registry.Acquire(ctx, testUserEmail)
+	require.NoError(t, err)
+	client.Del(ctx, tools.GetUserPresenceKey(testUserEmail))
+
+	registry.refresh(ctx)
+
+	assert.Empty(t, kicks.recorded())
+	assert.Equal(t, token, client.Get(ctx, tools.GetUserPresenceKey(testUserEmail)).Val())
+	assert.Positive(t, client.PTTL(ctx, tools.GetUserPresenceKey(testUserEmail)).Val())
+}
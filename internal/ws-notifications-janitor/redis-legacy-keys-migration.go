@@ -0,0 +1,157 @@
+package ws_notifications_janitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const migrationBatchSize = 500
+
+// MigrateLegacyKeys moves streams, cursors and last seen marks of users to keys with cluster hash tags.
+// It's idempotent, so instances of the previous version writing legacy keys during deploy are caught up by the next run.
+func (r *RedisWsNotificationsJanitor) MigrateLegacyKeys(ctx context.Context) error {
+	if !r.cfg.Redis.MigrateLegacyKeys {
+		return nil
+	}
+
+	migrated, err := r.forEachNode(ctx, r.migrateNode)
+	if err != nil {
+		return fmt.Errorf("can`t migrate legacy Redis keys: %w", err)
+	}
+	if migrated > 0 {
+		slog.Info("Legacy Redis keys migrated", slog.Int("keys", migrated))
+	}
+
+	return nil
+}
+
+func (r *RedisWsNotificationsJanitor) migrateNode(ctx context.Context, node redis.Cmdable) (int, error) {
+	migrated := 0
+
+	iter := node.Scan(ctx, 0, tools.REDIS_USER_STREAMS_PATTERN, scanBatchSize).Iterator()
+	for iter.Next(ctx) {
+		userEmail, ok := tools.GetUserFromLegacyStreamName(iter.Val())
+		if !ok {
+			continue
+		}
+
+		if err := r.migrateStream(ctx, iter.Val(), tools.GetUserStreamName(userEmail)); err != nil {
+			slog.Warn("Can`t migrate legacy stream", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+			continue
+		}
+		migrated++
+	}
+	if err := iter.Err(); err != nil {
+		return migrated, fmt.Errorf("can`t scan legacy streams: %w", err)
+	}
+
+	// Курсоры переносим после стримов, иначе новый курсор мог бы указывать на еще не перенесенные записи
+	for _, legacyKey := range tools.LEGACY_USER_KEYS {
+		iter := node.Scan(ctx, 0, legacyKey.Prefix+"*", scanBatchSize).Iterator()
+		for iter.Next(ctx) {
+			userEmail, ok := tools.GetUserFromLegacyKey(iter.Val(), legacyKey.Prefix)
+			if !ok {
+				continue
+			}
+
+			if err := r.migrateValue(ctx, iter.Val(), legacyKey.CurrentKey(userEmail)); err != nil {
+				slog.Warn("Can`t migrate legacy key", slog.String("key", iter.Val()), slog.String("error", err.Error()))
+				continue
+			}
+			migrated++
+		}
+		if err := iter.Err(); err != nil {
+			return migrated, fmt.Errorf("can`t scan legacy keys %s: %w", legacyKey.Prefix, err)
+		}
+	}
+
+	return migrated, nil
+}
+
+// migrateStream copies entries of legacy stream to the current one and removes legacy stream.
+// Entries keep their IDs, so cursors stay valid. Entries older than the tail of the current stream,
+// which the new version had already written, are appended with new IDs.
+func (r *RedisWsNotificationsJanitor) migrateStream(ctx context.Context, legacyStream string, stream string) error {
+	lastID, err := r.lastStreamID(ctx, stream)
+	if err != nil {
+		return err
+	}
+
+	start := "-"
+	for {
+		messages, err := r.client.XRangeN(ctx, legacyStream, start, "+", migrationBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("can`t read legacy stream: %w", err)
+		}
+
+		for _, message := range messages {
+			id := message.ID
+			if tools.CompareStreamIDs(id, lastID) <= 0 {
+				// Запись уже могла быть перенесена прерванной миграцией
+				existing, err := r.client.XRangeN(ctx, stream, id, id, 1).Result()
+				if err != nil {
+					return fmt.Errorf("can`t check migrated entry: %w", err)
+				}
+				if len(existing) > 0 {
+					continue
+				}
+				id = "*"
+			}
+
+			newID, err := r.client.XAdd(ctx, &redis.XAddArgs{Stream: stream, ID: id, Values: message.Values}).Result()
+			if err != nil {
+				return fmt.Errorf("can`t copy legacy stream entry %s: %w", message.ID, err)
+			}
+			lastID = newID
+		}
+
+		if len(messages) < migrationBatchSize {
+			break
+		}
+		start = "(" + messages[len(messages)-1].ID
+	}
+
+	return r.client.Del(ctx, legacyStream).Err()
+}
+
+// migrateValue copies string value with its TTL, current value written by the new version wins
+func (r *RedisWsNotificationsJanitor) migrateValue(ctx context.Context, legacyKey string, key string) error {
+	value, err := r.client.Get(ctx, legacyKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can`t read legacy key: %w", err)
+	}
+
+	ttl, err := r.client.PTTL(ctx, legacyKey).Result()
+	if err != nil {
+		return fmt.Errorf("can`t read TTL of legacy key: %w", err)
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	if err := r.client.SetArgs(ctx, key, value, redis.SetArgs{Mode: "NX", TTL: ttl}).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("can`t write current key: %w", err)
+	}
+
+	return r.client.Del(ctx, legacyKey).Err()
+}
+
+func (r *RedisWsNotificationsJanitor) lastStreamID(ctx context.Context, stream string) (string, error) {
+	lastMessages, err := r.client.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("can`t fetch last stream entry: %w", err)
+	}
+	if len(lastMessages) == 0 {
+		return "0-0", nil
+	}
+	return lastMessages[0].ID, nil
+}
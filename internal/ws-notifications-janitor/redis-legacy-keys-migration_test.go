@@ -0,0 +1,86 @@
+package ws_notifications_janitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamIDs(t *testing.T, client *redis.Client, stream string) []string {
+	messages, err := client.XRange(context.Background(), stream, "-", "+").Result()
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
+
+func TestMigrateLegacyKeys(t *testing.T) {
+	ctx := context.Background()
+	janitor, client := newTestJanitor(t)
+	const userEmail = "user@example.com"
+
+	addEntry(t, client, "notifications:"+userEmail, "1-0")
+	addEntry(t, client, "notifications:"+userEmail, "2-0")
+	client.Set(ctx, "last-readed-notification--"+userEmail, "1-0", 0)
+	client.Set(ctx, "last-seen--"+userEmail, "1", time.Hour)
+	// Новая версия уже записала курсор входящих, он важнее устаревшего
+	client.Set(ctx, "inbox-last-read-notification--"+userEmail, "1-0", 0)
+	client.Set(ctx, tools.GetUserInboxLastReadNotificationID(userEmail), "2-0", 0)
+
+	require.NoError(t, janitor.MigrateLegacyKeys(ctx))
+	// Повторный запуск ничего не ломает
+	require.NoError(t, janitor.MigrateLegacyKeys(ctx))
+
+	assert.Equal(t, []string{"1-0", "2-0"}, streamIDs(t, client, tools.GetUserStreamName(userEmail)))
+	assert.Equal(t, "1-0", client.Get(ctx, tools.GetUserLastReadedNotificationID(userEmail)).Val())
+	assert.Equal(t, "2-0", client.Get(ctx, tools.GetUserInboxLastReadNotificationID(userEmail)).Val())
+	assert.Positive(t, client.PTTL(ctx, tools.GetUserLastSeenKey(userEmail)).Val())
+
+	legacy, err := client.Exists(ctx,
+		"notifications:"+userEmail,
+		"last-readed-notification--"+userEmail,
+		"inbox-last-read-notification--"+userEmail,
+		"last-seen--"+userEmail,
+	).Result()
+	require.NoError(t, err)
+	assert.Zero(t, legacy)
+}
+
+func TestMigrateLegacyStreamBehindCurrentOne(t *testing.T) {
+	ctx := context.Background()
+	janitor, client := newTestJanitor(t)
+	const userEmail = "user@example.com"
+
+	addEntry(t, client, "notifications:"+userEmail, "1-0")
+	addEntry(t, client, "notifications:"+userEmail, "5-0")
+	addEntry(t, client, tools.GetUserStreamName(userEmail), "3-0")
+
+	require.NoError(t, janitor.MigrateLegacyKeys(ctx))
+
+	// Записи старше хвоста текущего стрима дописываются с новыми ID
+	ids := streamIDs(t, client, tools.GetUserStreamName(userEmail))
+	require.Len(t, ids, 3)
+	assert.Equal(t, "3-0", ids[0])
+	assert.Equal(t, 1, tools.CompareStreamIDs(ids[1], "3-0"))
+	assert.Equal(t, 1, tools.CompareStreamIDs(ids[2], ids[1]))
+	assert.Zero(t, client.Exists(ctx, "notifications:"+userEmail).Val())
+}
+
+func TestMigrateLegacyKeysDisabled(t *testing.T) {
+	ctx := context.Background()
+	janitor, client := newTestJanitor(t)
+	janitor.cfg.Redis.MigrateLegacyKeys = false
+	addEntry(t, client, "notifications:user@example.com", "1-0")
+
+	require.NoError(t, janitor.MigrateLegacyKeys(ctx))
+
+	assert.Equal(t, int64(1), client.Exists(ctx, "notifications:user@example.com").Val())
+}
@@ -0,0 +1,185 @@
+package ws_notifications_janitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const scanBatchSize = 100
+
+// RedisWsNotificationsJanitor periodically removes notification streams and cursors of inactive users
+type RedisWsNotificationsJanitor struct {
+	client redis.UniversalClient
+	cfg    *config.Config
+}
+
+func NewRedisWsNotificationsJanitor(client redis.UniversalClient, cfg *config.Config) *RedisWsNotificationsJanitor {
+	return &RedisWsNotificationsJanitor{client: client, cfg: cfg}
+}
+
+func (r *RedisWsNotificationsJanitor) Run(ctx context.Context) {
+	if r.cfg.Redis.InactiveUserTTLHours <= 0 || r.cfg.Redis.JanitorIntervalMinutes <= 0 {
+		slog.Info("Redis janitor disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(r.cfg.Redis.JanitorIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		// Ключи без hash tag могли записать инстансы предыдущей версии во время выкладки
+		if err := r.MigrateLegacyKeys(ctx); err != nil {
+			slog.Error("Redis janitor migration failed", slog.String("error", err.Error()))
+		}
+
+		removed, err := r.forEachNode(ctx, r.cleanupNode)
+		if err != nil {
+			slog.Error("Redis janitor cleanup failed", slog.String("error", err.Error()))
+		} else {
+			slog.Info("Redis janitor cleanup finished", slog.Int("removed", removed))
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Terminating Redis janitor")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// forEachNode runs pass over keys of every node and sums amounts of processed keys
+func (r *RedisWsNotificationsJanitor) forEachNode(ctx context.Context, pass func(ctx context.Context, node redis.Cmdable) (int, error)) (int, error) {
+	// В кластере SCAN видит только ключи одного узла, поэтому обходим все мастера
+	if clusterClient, ok := r.client.(*redis.ClusterClient); ok {
+		var mu sync.Mutex
+		processed := 0
+		err := clusterClient.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			nodeProcessed, err := pass(ctx, node)
+			mu.Lock()
+			processed += nodeProcessed
+			mu.Unlock()
+			return err
+		})
+		return processed, err
+	}
+
+	return pass(ctx, r.client)
+}
+
+func (r *RedisWsNotificationsJanitor) cleanupNode(ctx context.Context, node redis.Cmdable) (int, error) {
+	inactiveSince := time.Now().Add(-time.Duration(r.cfg.Redis.InactiveUserTTLHours) * time.Hour)
+	removed := 0
+
+	iter := node.Scan(ctx, 0, tools.REDIS_USER_STREAMS_PATTERN, scanBatchSize).Iterator()
+	for iter.Next(ctx) {
+		streamName := iter.Val()
+		userEmail, ok := tools.GetUserFromStreamName(streamName)
+		if !ok {
+			continue
+		}
+
+		inactive, err := r.isInactive(ctx, userEmail, streamName, inactiveSince)
+		if err != nil {
+			slog.Warn("Redis janitor can`t check user activity", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+			continue
+		}
+		if !inactive {
+			continue
+		}
+
+		err = r.client.Del(ctx,
+			streamName,
+			tools.GetUserLastReadedNotificationID(userEmail),
+			tools.GetUserInboxLastReadNotificationID(userEmail),
+		).Err()
+		if err != nil {
+			slog.Warn("Redis janitor can`t remove user keys", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+			continue
+		}
+
+		slog.Info("Redis janitor removed keys of inactive user", slog.String("user_email", userEmail))
+		removed++
+	}
+
+	if err := iter.Err(); err != nil {
+		return removed, fmt.Errorf("can`t scan notification streams: %w", err)
+	}
+
+	removedCursors, err := r.cleanupOrphanedCursors(ctx, node)
+	return removed + removedCursors, err
+}
+
+// cleanupOrphanedCursors removes cursors of inactive users whose stream is already gone
+func (r *RedisWsNotificationsJanitor) cleanupOrphanedCursors(ctx context.Context, node redis.Cmdable) (int, error) {
+	removed := 0
+
+	for _, pattern := range tools.REDIS_USER_CURSORS_PATTERNS {
+		iter := node.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+		for iter.Next(ctx) {
+			cursorKey := iter.Val()
+			userEmail, ok := tools.GetUserFromCursorKey(cursorKey)
+			if !ok {
+				continue
+			}
+
+			// Курсоры пользователя со стримом удаляются вместе со стримом
+			exists, err := r.client.Exists(ctx, tools.GetUserStreamName(userEmail), tools.GetUserLastSeenKey(userEmail)).Result()
+			if err != nil {
+				slog.Warn("Redis janitor can`t check user activity", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+				continue
+			}
+			if exists > 0 {
+				continue
+			}
+
+			if err := r.client.Del(ctx, cursorKey).Err(); err != nil {
+				slog.Warn("Redis janitor can`t remove cursor", slog.String("key", cursorKey), slog.String("error", err.Error()))
+				continue
+			}
+
+			slog.Info("Redis janitor removed orphaned cursor", slog.String("user_email", userEmail), slog.String("key", cursorKey))
+			removed++
+		}
+
+		if err := iter.Err(); err != nil {
+			return removed, fmt.Errorf("can`t scan cursors: %w", err)
+		}
+	}
+
+	return removed, nil
+}
+
+// isInactive user is inactive when they weren't seen and got no notifications since inactiveSince
+func (r *RedisWsNotificationsJanitor) isInactive(ctx context.Context, userEmail string, streamName string, inactiveSince time.Time) (bool, error) {
+	seen, err := r.client.Exists(ctx, tools.GetUserLastSeenKey(userEmail)).Result()
+	if err != nil {
+		return false, fmt.Errorf("can`t check last seen key: %w", err)
+	}
+	if seen > 0 {
+		return false, nil
+	}
+
+	lastMessages, err := r.client.XRevRangeN(ctx, streamName, "+", "-", 1).Result()
+	if err != nil {
+		return false, fmt.Errorf("can`t fetch last stream entry: %w", err)
+	}
+	if len(lastMessages) == 0 {
+		return true, nil
+	}
+
+	lastMs, _, err := tools.ParseStreamID(lastMessages[0].ID)
+	if err != nil {
+		return false, err
+	}
+
+	return time.UnixMilli(int64(lastMs)).Before(inactiveSince), nil
+}
@@ -0,0 +1,67 @@
+package ws_notifications_janitor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestJanitor(t *testing.T) (*RedisWsNotificationsJanitor, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := &config.Config{}
+	cfg.Redis.InactiveUserTTLHours = 1
+	cfg.Redis.MigrateLegacyKeys = true
+
+	return NewRedisWsNotificationsJanitor(client, cfg), client
+}
+
+func addEntry(t *testing.T, client *redis.Client, stream string, id string) {
+	err := client.XAdd(context.Background(), &redis.XAddArgs{Stream: stream, ID: id, Values: []string{"notification", id}}).Err()
+	require.NoError(t, err)
+}
+
+func TestCleanupRemovesKeysOfInactiveUsers(t *testing.T) {
+	ctx := context.Background()
+	janitor, client := newTestJanitor(t)
+	recentID := fmt.Sprintf("%d-0", time.Now().UnixMilli())
+
+	// Давно не заходил и давно не получал уведомлений
+	addEntry(t, client, tools.GetUserStreamName("inactive@example.com"), "1000-0")
+	client.Set(ctx, tools.GetUserLastReadedNotificationID("inactive@example.com"), "1000-0", 0)
+	client.Set(ctx, tools.GetUserInboxLastReadNotificationID("inactive@example.com"), "1000-0", 0)
+	// Недавно заходил
+	addEntry(t, client, tools.GetUserStreamName("seen@example.com"), "1000-0")
+	client.Set(ctx, tools.GetUserLastSeenKey("seen@example.com"), time.Now().Unix(), time.Hour)
+	// Недавно получил уведомление
+	addEntry(t, client, tools.GetUserStreamName("notified@example.com"), recentID)
+	// Курсор пользователя, чей стрим уже удален
+	client.Set(ctx, tools.GetUserLastReadedNotificationID("orphaned@example.com"), "1000-0", 0)
+
+	removed, err := janitor.forEachNode(ctx, janitor.cleanupNode)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	remaining, err := client.Exists(ctx,
+		tools.GetUserStreamName("inactive@example.com"),
+		tools.GetUserLastReadedNotificationID("inactive@example.com"),
+		tools.GetUserInboxLastReadNotificationID("inactive@example.com"),
+		tools.GetUserLastReadedNotificationID("orphaned@example.com"),
+	).Result()
+	require.NoError(t, err)
+	assert.Zero(t, remaining)
+
+	kept, err := client.Exists(ctx, tools.GetUserStreamName("seen@example.com"), tools.GetUserStreamName("notified@example.com")).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), kept)
+}
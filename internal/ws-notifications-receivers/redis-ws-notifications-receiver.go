@@ -2,28 +2,61 @@ package ws_notifications_receivers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/mwsbkru/evrone-go-final/config"
 	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/metrics"
 	"github.com/mwsbkru/evrone-go-final/internal/service"
 	"github.com/mwsbkru/evrone-go-final/internal/tools"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// receiverChannelName channel of notifications dropped by receiver
+const receiverChannelName = "WS receiver"
+
+// subscription connected user, whose stream is read by one of the shared readers
+type subscription struct {
+	userEmail string
+	lastID    string
+	reader    *streamsReader
+}
+
+// streamsReader reads streams of up to ReaderBatchSize users with one blocking XREAD
+type streamsReader struct {
+	subscriptions map[string]*subscription
+	// bucket of users keys, in Redis Cluster one XREAD can read only streams of the same slot
+	bucket int
+}
+
+// RedisWsNotificationsReceiver multiplexes streams of all connected users into a few shared readers,
+// so amount of Redis connections doesn't depend on amount of WS connections
 type RedisWsNotificationsReceiver struct {
-	redisClient                   *redis.Client
+	redisClient                   redis.UniversalClient
 	receivedNotificationProcessor service.ReceivedNotificationProcessor
 	wsConnectionTerminator        service.WsConnectionTerminator
+	deadNotificationsProcessor    service.DeadNotificationsProcessor
 	cfg                           *config.Config
+	ctx                           context.Context
+	mu                            sync.Mutex
+	readers                       map[*streamsReader]struct{}
+	subscriptions                 map[string]*subscription
 }
 
-func NewRedisWsNotificationsReceiver(redisClient *redis.Client, cfg *config.Config) *RedisWsNotificationsReceiver {
-	return &RedisWsNotificationsReceiver{redisClient: redisClient, cfg: cfg}
+func NewRedisWsNotificationsReceiver(redisClient redis.UniversalClient, cfg *config.Config, deadProcessor service.DeadNotificationsProcessor) *RedisWsNotificationsReceiver {
+	return &RedisWsNotificationsReceiver{
+		redisClient:                redisClient,
+		deadNotificationsProcessor: deadProcessor,
+		cfg:                        cfg,
+		ctx:                        context.Background(),
+		readers:                    make(map[*streamsReader]struct{}),
+		subscriptions:              make(map[string]*subscription),
+	}
 }
 
 func (r *RedisWsNotificationsReceiver) Subscribe(receivedNotificationProcessor service.ReceivedNotificationProcessor, wsConnectionTerminator service.WsConnectionTerminator) {
@@ -31,57 +64,151 @@ func (r *RedisWsNotificationsReceiver) Subscribe(receivedNotificationProcessor s
 	r.wsConnectionTerminator = wsConnectionTerminator
 }
 
+// Run sets context which limits lifetime of shared readers
+func (r *RedisWsNotificationsReceiver) Run(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ctx = ctx
+}
+
 func (r *RedisWsNotificationsReceiver) ReceiveNotifications(ctx context.Context, userEmail string) {
 	slog.Info("Start receive notifications from Redis for user", slog.String("user_email", userEmail))
+	tools.TouchUserLastSeen(ctx, r.redisClient, r.cfg, userEmail)
+
+	lastID, err := r.readLastProcessedID(ctx, userEmail)
+	if err != nil {
+		slog.Warn("Warning processing notifications from Redis for user", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+	}
+
+	sub := r.addSubscription(userEmail, lastID)
+	<-ctx.Done()
+	r.removeSubscription(sub)
+
+	slog.Info("Terminating redis listening, by context done", slog.String("user_email", userEmail))
+	r.wsConnectionTerminator(userEmail)
+}
+
+func (r *RedisWsNotificationsReceiver) addSubscription(userEmail string, lastID string) *subscription {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if previous, ok := r.subscriptions[userEmail]; ok {
+		delete(previous.reader.subscriptions, userEmail)
+	}
+
+	bucket := -1
+	if _, isCluster := r.redisClient.(*redis.ClusterClient); isCluster {
+		bucket = tools.GetUserKeysBucket(userEmail)
+	}
+
+	var reader *streamsReader
+	for candidate := range r.readers {
+		if candidate.bucket == bucket && len(candidate.subscriptions) < r.batchSize() {
+			reader = candidate
+			break
+		}
+	}
+
+	if reader == nil {
+		reader = &streamsReader{subscriptions: make(map[string]*subscription), bucket: bucket}
+		r.readers[reader] = struct{}{}
+		metrics.RedisReaders.Set(float64(len(r.readers)))
+		go r.runReader(r.ctx, reader)
+	}
+
+	sub := &subscription{userEmail: userEmail, lastID: lastID, reader: reader}
+	reader.subscriptions[userEmail] = sub
+	r.subscriptions[userEmail] = sub
+
+	return sub
+}
+
+func (r *RedisWsNotificationsReceiver) removeSubscription(sub *subscription) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Пользователь мог переподключиться, тогда подписка уже принадлежит новому соединению
+	if r.subscriptions[sub.userEmail] != sub {
+		return
+	}
+
+	delete(r.subscriptions, sub.userEmail)
+	delete(sub.reader.subscriptions, sub.userEmail)
+}
+
+func (r *RedisWsNotificationsReceiver) runReader(ctx context.Context, reader *streamsReader) {
+	slog.Info("Start shared Redis streams reader")
+	defer slog.Info("Shared Redis streams reader stopped")
+	defer r.removeReader(reader)
+
 	for {
-		slog.Info("Waiting for reading message from redis or close connection", slog.String("user_email", userEmail))
+		streams, subscriptions, ok := r.prepareRead(reader)
+		if !ok {
+			return
+		}
+
 		select {
 		case <-ctx.Done():
-			slog.Info("Terminating redis listening, by context done", slog.String("user_email", userEmail))
-			r.wsConnectionTerminator(userEmail)
 			return
 		default:
-			err := r.readNotifications(ctx, userEmail)
-			if err != nil {
-				slog.Warn("Warning processing notifications from Redis for user", slog.String("user_email", userEmail), slog.String("error", err.Error()))
+		}
+
+		entries, err := r.redisClient.XRead(ctx, &redis.XReadArgs{
+			Streams: streams,
+			Block:   time.Duration(r.cfg.Redis.ReaderBlockMilliseconds) * time.Millisecond,
+		}).Result()
+		if err != nil {
+			// redis.Nil означает, что за время блокировки новых сообщений не было
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
 			}
+			slog.Warn("Warning processing notifications from Redis", slog.Int("streams", len(subscriptions)), slog.String("error", err.Error()))
+			r.sleep(ctx, time.Second)
+			continue
 		}
+
+		r.processEntries(ctx, subscriptions, entries)
 	}
 }
 
-func (r *RedisWsNotificationsReceiver) readNotifications(ctx context.Context, userEmail string) error {
-	lastID, err := r.readLastProcessedID(ctx, userEmail)
-	if err != nil && err != redis.Nil {
-		return fmt.Errorf("can`t readnotifications from Redis WS notification for user: %w", err)
-	}
+func (r *RedisWsNotificationsReceiver) removeReader(reader *streamsReader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	entries, err := r.redisClient.XRead(ctx,
-		&redis.XReadArgs{
-			Streams: []string{tools.GetUserStreamName(userEmail), lastID},
-			Block:   time.Duration(r.cfg.Redis.TimeoutSeconds) * time.Second,
-		}).Result()
-	if err != nil {
-		// Check if error is caused by Block option timeout
-		if err == redis.Nil ||
-			err.Error() == "context deadline exceeded" ||
-			err.Error() == "i/o timeout" ||
-			err.Error() == "redis: nil" {
-			// Return nil when error is caused by blocks option timeout
-			return nil
-		}
-		// Return other errors as before
-		return fmt.Errorf("can`t read WS notifications from Redis stream for user: %s; lastID: %s; error: %w; error type: %T", userEmail, lastID, err, err)
+	delete(r.readers, reader)
+	metrics.RedisReaders.Set(float64(len(r.readers)))
+}
+
+// prepareRead snapshots streams of reader's subscriptions, reader without subscriptions is removed
+func (r *RedisWsNotificationsReceiver) prepareRead(reader *streamsReader) ([]string, map[string]*subscription, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Удаляем читателя под той же блокировкой, иначе ему могут успеть назначить новую подписку
+	if len(reader.subscriptions) == 0 {
+		delete(r.readers, reader)
+		metrics.RedisReaders.Set(float64(len(r.readers)))
+		return nil, nil, false
 	}
 
-	r.processEntries(ctx, userEmail, lastID, entries)
+	keys := make([]string, 0, len(reader.subscriptions))
+	ids := make([]string, 0, len(reader.subscriptions))
+	subscriptions := make(map[string]*subscription, len(reader.subscriptions))
+	for userEmail, sub := range reader.subscriptions {
+		streamName := tools.GetUserStreamName(userEmail)
+		keys = append(keys, streamName)
+		ids = append(ids, sub.lastID)
+		subscriptions[streamName] = sub
+	}
 
-	return nil
+	return append(keys, ids...), subscriptions, true
 }
 
 func (r *RedisWsNotificationsReceiver) readLastProcessedID(ctx context.Context, userEmail string) (string, error) {
 	lastID, err := r.redisClient.Get(ctx, tools.GetUserLastReadedNotificationID(userEmail)).Result()
 	if err != nil && err != redis.Nil {
-		return "", fmt.Errorf("can`t fetch last processed ID Redis WS notification for user: %w", err)
+		return "0-0", fmt.Errorf("can`t fetch last processed ID Redis WS notification for user: %w", err)
 	}
 
 	if lastID == "" {
@@ -91,37 +218,81 @@ func (r *RedisWsNotificationsReceiver) readLastProcessedID(ctx context.Context,
 	return lastID, nil
 }
 
-func (r *RedisWsNotificationsReceiver) processEntries(ctx context.Context, userEmail string, lastID string, entries []redis.XStream) {
+// processEntries advances cursor of the user up to the first notification, which wasn't accepted for sending.
+// Stream of such user isn't read anymore: the connection is closed and the next one resumes from the cursor.
+func (r *RedisWsNotificationsReceiver) processEntries(ctx context.Context, subscriptions map[string]*subscription, entries []redis.XStream) {
 	for _, entry := range entries {
-		for _, message := range entry.Messages {
-			rawNotification, ok := message.Values[tools.REDIS_STREAM_NOTIFICATION_FIELD_NAME]
-			if !ok {
-				slog.Error("Can`t extract readed notification from Redis for user", slog.String("user_email", userEmail), slog.String("redis_message_id", lastID))
-				continue
-			}
+		sub, ok := subscriptions[entry.Stream]
+		if !ok {
+			continue
+		}
 
-			notificationString, ok := rawNotification.(string)
-			if !ok {
-				slog.Error("Can`t process notification from Redis to string for user", slog.String("user_email", userEmail), slog.String("redis_message_id", lastID))
-				continue
+		processedID := ""
+		for _, message := range entry.Messages {
+			if !r.processMessage(sub, message) {
+				r.removeSubscription(sub)
+				break
 			}
 
-			var notification entity.Notification
-			err := json.Unmarshal([]byte(notificationString), &notification)
-			if err != nil {
-				slog.Error("Can`t unmarshal notification from Redis to entity.Notification for user", slog.String("user_email", userEmail), slog.String("redis_message_id", lastID), slog.String("error", err.Error()))
-				continue
-			}
+			r.mu.Lock()
+			sub.lastID = message.ID
+			r.mu.Unlock()
+			processedID = message.ID
+		}
 
-			r.receivedNotificationProcessor(notification)
-			r.writeLastProcessedID(ctx, userEmail, message.ID)
+		if processedID != "" {
+			r.writeLastProcessedID(ctx, sub.userEmail, processedID)
 		}
 	}
 }
 
+// processMessage returns false, when notification wasn't accepted for sending
+func (r *RedisWsNotificationsReceiver) processMessage(sub *subscription, message redis.XMessage) bool {
+	notification, err := tools.ParseStreamNotification(message.Values)
+	switch {
+	case err != nil:
+		slog.Error("Can`t extract readed notification from Redis for user", slog.String("user_email", sub.userEmail), slog.String("redis_message_id", message.ID), slog.String("error", err.Error()))
+		return true
+	case notification.Expired(time.Now()):
+		r.processExpired(notification)
+		return true
+	default:
+		return r.receivedNotificationProcessor(*notification)
+	}
+}
+
+// processExpired skips notification, which expired while waiting in the stream
+func (r *RedisWsNotificationsReceiver) processExpired(notification *entity.Notification) {
+	slog.Info("Skip expired WS notification", slog.String("user_email", notification.UserEmail))
+	notification.Channel = receiverChannelName
+	metrics.NotificationsDead.WithLabelValues(receiverChannelName).Inc()
+
+	err := r.deadNotificationsProcessor.Process(notification, fmt.Errorf("%w at %s", service.ErrNotificationExpired, notification.ExpiresAt.Format(time.RFC3339)))
+	if err != nil {
+		slog.Error("Can`t process expired WS notification", slog.String("user_email", notification.UserEmail), slog.String("error", err.Error()))
+	}
+}
+
 func (r *RedisWsNotificationsReceiver) writeLastProcessedID(ctx context.Context, userEmail string, messageId string) {
 	err := r.redisClient.Set(ctx, tools.GetUserLastReadedNotificationID(userEmail), messageId, 0).Err()
 	if err != nil {
 		slog.Error("Can`t write last processed ID of WS notifications to Redis for user", slog.String("user_email", userEmail), slog.String("redis_message_id", messageId), slog.String("error", err.Error()))
 	}
 }
+
+func (r *RedisWsNotificationsReceiver) batchSize() int {
+	if r.cfg.Redis.ReaderBatchSize <= 0 {
+		return 1
+	}
+	return r.cfg.Redis.ReaderBatchSize
+}
+
+func (r *RedisWsNotificationsReceiver) sleep(ctx context.Context, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
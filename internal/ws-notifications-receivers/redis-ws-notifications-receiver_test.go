@@ -0,0 +1,106 @@
+package ws_notifications_receivers
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mwsbkru/evrone-go-final/config"
+	"github.com/mwsbkru/evrone-go-final/internal/entity"
+	"github.com/mwsbkru/evrone-go-final/internal/tools"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserEmail = "user@example.com"
+
+// recordingProcessor accepts notifications until the limit and records subjects of the offered ones
+type recordingProcessor struct {
+	mu      sync.Mutex
+	limit   int
+	offered []string
+}
+
+func (p *recordingProcessor) process(notification entity.Notification) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.offered = append(p.offered, notification.Subject)
+	return len(p.offered) <= p.limit
+}
+
+func (p *recordingProcessor) subjects() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.offered...)
+}
+
+func newTestReceiver(t *testing.T) (*RedisWsNotificationsReceiver, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := &config.Config{}
+	cfg.Redis.ReaderBlockMilliseconds = 20
+	cfg.Redis.ReaderBatchSize = 10
+
+	return NewRedisWsNotificationsReceiver(client, cfg, nil), client
+}
+
+func addStreamNotification(t *testing.T, client *redis.Client, id string, subject string) {
+	payload, err := json.Marshal(entity.Notification{UserEmail: testUserEmail, Subject: subject})
+	require.NoError(t, err)
+
+	err = client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: tools.GetUserStreamName(testUserEmail),
+		ID:     id,
+		Values: map[string]interface{}{tools.REDIS_STREAM_NOTIFICATION_FIELD_NAME: string(payload)},
+	}).Err()
+	require.NoError(t, err)
+}
+
+func receive(receiver *RedisWsNotificationsReceiver, processor *recordingProcessor) context.CancelFunc {
+	terminated := func(userEmail string) {}
+	receiver.Subscribe(processor.process, terminated)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	receiver.Run(ctx)
+	go receiver.ReceiveNotifications(ctx, testUserEmail)
+
+	return cancel
+}
+
+func TestReceiverKeepsCursorAtRejectedNotification(t *testing.T) {
+	receiver, client := newTestReceiver(t)
+	addStreamNotification(t, client, "1-0", "first")
+	addStreamNotification(t, client, "2-0", "second")
+	addStreamNotification(t, client, "3-0", "third")
+	addStreamNotification(t, client, "4-0", "fourth")
+
+	overflowed := &recordingProcessor{limit: 2}
+	cancel := receive(receiver, overflowed)
+	defer cancel()
+
+	require.Eventually(t, func() bool { return len(overflowed.subjects()) == 3 }, time.Second, 10*time.Millisecond)
+	// Поток пользователя больше не читается, отклоненное уведомление не предлагается повторно
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []string{"first", "second", "third"}, overflowed.subjects())
+
+	lastID, err := client.Get(context.Background(), tools.GetUserLastReadedNotificationID(testUserEmail)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "2-0", lastID)
+
+	// Новое соединение продолжает с отклоненного уведомления
+	cancel()
+	reconnected := &recordingProcessor{limit: 10}
+	cancelReconnected := receive(receiver, reconnected)
+	defer cancelReconnected()
+
+	require.Eventually(t, func() bool { return len(reconnected.subjects()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"third", "fourth"}, reconnected.subjects())
+}